@@ -0,0 +1,157 @@
+package acl
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ynachi/gcache/gerror"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultUser is the user new connections are authenticated as, when it needs no password.
+const DefaultUser = "default"
+
+// Commands tells which commands and categories exist, so that rules referring to unknown ones are rejected.
+type Commands interface {
+	HasCommand(name string) bool
+	HasCategory(category string) bool
+}
+
+// ACL holds the users of a server and the log of the permissions they were denied.
+type ACL struct {
+	mu    sync.RWMutex
+	users map[string]*User
+	known Commands
+	log   *Log
+}
+
+// New creates an ACL with the default user, which needs no password and can do anything.
+func New(known Commands) *ACL {
+	a := &ACL{users: make(map[string]*User), known: known, log: newLog()}
+	a.users[DefaultUser] = newDefaultUser(known)
+	return a
+}
+
+func newDefaultUser(known Commands) *User {
+	u := newUser(DefaultUser)
+	_ = u.SetRules(known, "on", "nopass", "allkeys", "allchannels", "allcommands")
+	return u
+}
+
+// Log returns the log of the permissions denied to the users.
+func (a *ACL) Log() *Log {
+	return a.log
+}
+
+// User returns a user by name.
+func (a *ACL) User(name string) (*User, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	u, ok := a.users[name]
+	return u, ok
+}
+
+// Users returns all the users, sorted by name.
+func (a *ACL) Users() []*User {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	users := make([]*User, 0, len(a.users))
+	for _, u := range a.users {
+		users = append(users, u)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].name < users[j].name })
+	return users
+}
+
+// SetUser applies rules to a user, creating it if needed. A user created by invalid rules is not kept.
+func (a *ACL) SetUser(name string, rules ...string) error {
+	if name == "" || strings.ContainsAny(name, " \x00") {
+		return gerror.ErrInvalidUsername
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.users[name]
+	if !ok {
+		u = newUser(name)
+	}
+	if err := u.SetRules(a.known, rules...); err != nil {
+		return err
+	}
+	a.users[name] = u
+	return nil
+}
+
+// DelUser removes a user and tells if it existed. The default user cannot be removed.
+func (a *ACL) DelUser(name string) (bool, error) {
+	if name == DefaultUser {
+		return false, gerror.ErrDefaultUser
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, ok := a.users[name]
+	delete(a.users, name)
+	return ok, nil
+}
+
+// Authenticate returns the user matching a name and a password. Disabled users cannot authenticate.
+func (a *ACL) Authenticate(name, password string) (*User, error) {
+	u, ok := a.User(name)
+	if !ok || !u.Enabled() || !u.checkPassword(password) {
+		return nil, gerror.ErrWrongPass
+	}
+	return u, nil
+}
+
+// Load replaces the users with the ones described by an ACL file, one per line in the format of ACL LIST:
+//
+//	user <name> <rule>...
+//
+// Empty lines and lines starting with # are ignored. The default user is created with its default permissions
+// if the file does not describe it. Nothing is replaced if one of the lines is invalid.
+func (a *ACL) Load(r io.Reader) error {
+	users := make(map[string]*User)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 || fields[0] != "user" {
+			return fmt.Errorf("line %d: %w", line, gerror.ErrSyntax)
+		}
+		name := fields[1]
+		if _, ok := users[name]; ok {
+			return fmt.Errorf("line %d: duplicate user '%s'", line, name)
+		}
+		u := newUser(name)
+		if err := u.SetRules(a.known, fields[2:]...); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		users[name] = u
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if _, ok := users[DefaultUser]; !ok {
+		users[DefaultUser] = newDefaultUser(a.known)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.users = users
+	return nil
+}
+
+// LoadFile replaces the users with the ones described by an ACL file, see Load.
+func (a *ACL) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	return a.Load(f)
+}
@@ -0,0 +1,70 @@
+package acl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"testing"
+)
+
+func TestACL_Authenticate(t *testing.T) {
+	a := New(fakeCommands{})
+	_, err := a.Authenticate(DefaultUser, "anything")
+	assert.NoError(t, err, "the default user needs no password")
+
+	assert.NoError(t, a.SetUser("alice", "on", ">secret"))
+	u, err := a.Authenticate("alice", "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", u.Name())
+
+	_, err = a.Authenticate("alice", "wrong")
+	assert.Equal(t, gerror.ErrWrongPass, err)
+	_, err = a.Authenticate("bob", "secret")
+	assert.Equal(t, gerror.ErrWrongPass, err)
+
+	assert.NoError(t, a.SetUser("alice", "off"))
+	_, err = a.Authenticate("alice", "secret")
+	assert.Equal(t, gerror.ErrWrongPass, err, "disabled users cannot authenticate")
+}
+
+func TestACL_SetUserAndDelUser(t *testing.T) {
+	a := New(fakeCommands{})
+	assert.Error(t, a.SetUser("alice", "+nonexistent"))
+	_, ok := a.User("alice")
+	assert.False(t, ok, "a user created by invalid rules should not be kept")
+
+	assert.Equal(t, gerror.ErrInvalidUsername, a.SetUser("a b"))
+	assert.NoError(t, a.SetUser("alice"))
+	assert.Len(t, a.Users(), 2)
+
+	deleted, err := a.DelUser("alice")
+	assert.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = a.DelUser("alice")
+	assert.NoError(t, err)
+	assert.False(t, deleted)
+	_, err = a.DelUser(DefaultUser)
+	assert.Equal(t, gerror.ErrDefaultUser, err)
+}
+
+func TestACL_Load(t *testing.T) {
+	a := New(fakeCommands{})
+	file := `
+# users of the cache
+user alice on >secret ~cache:* +@read
+user bob off
+`
+	assert.NoError(t, a.Load(strings.NewReader(file)))
+	assert.Len(t, a.Users(), 3, "the default user should be created")
+	alice, ok := a.User("alice")
+	assert.True(t, ok)
+	assert.True(t, alice.CanRun("get", []string{"read"}))
+	assert.True(t, alice.CanAccessKey("cache:1", false))
+
+	err := a.Load(strings.NewReader("user carol on +nonexistent\n"))
+	assert.ErrorIs(t, err, gerror.ErrUnknownCommand)
+	_, ok = a.User("alice")
+	assert.True(t, ok, "users should not be replaced by an invalid file")
+
+	assert.ErrorIs(t, a.Load(strings.NewReader("alice on\n")), gerror.ErrSyntax)
+}
@@ -0,0 +1,99 @@
+package acl
+
+import (
+	"sync"
+	"time"
+)
+
+// Reasons for which an entry is added to the log.
+const (
+	ReasonCommand = "command"
+	ReasonKey     = "key"
+	ReasonChannel = "channel"
+	ReasonAuth    = "auth"
+)
+
+const (
+	// logMaxLen is the number of entries the log keeps, the oldest ones being dropped.
+	logMaxLen = 128
+	// logGroupingWindow is the time during which identical denials are counted in the same entry.
+	logGroupingWindow = time.Minute
+)
+
+// LogEntry records a permission denied to a user, or a failed authentication.
+type LogEntry struct {
+	// Count is the number of identical denials grouped in the entry.
+	Count int
+	// Reason is one of ReasonCommand, ReasonKey, ReasonChannel or ReasonAuth.
+	Reason string
+	// Context is "toplevel", or "multi" for a command queued in a transaction.
+	Context string
+	// Object is the command, key or channel which was denied.
+	Object   string
+	Username string
+	// ClientInfo describes the client, like CLIENT INFO does.
+	ClientInfo string
+	EntryID    int64
+	Created    time.Time
+	Updated    time.Time
+}
+
+// Log keeps the most recent denials, newest first, like ACL LOG shows them.
+type Log struct {
+	mu      sync.Mutex
+	entries []*LogEntry
+	nextID  int64
+	now     func() time.Time
+}
+
+func newLog() *Log {
+	return &Log{now: time.Now}
+}
+
+// Add records a denial. Identical to a recent entry, it only increments its count.
+func (l *Log) Add(entry LogEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for i, e := range l.entries {
+		if e.Reason == entry.Reason && e.Context == entry.Context && e.Object == entry.Object &&
+			e.Username == entry.Username && now.Sub(e.Updated) < logGroupingWindow {
+			e.Count++
+			e.Updated = now
+			e.ClientInfo = entry.ClientInfo
+			// the entry becomes the most recent one
+			copy(l.entries[1:i+1], l.entries[:i])
+			l.entries[0] = e
+			return
+		}
+	}
+	entry.Count = 1
+	entry.EntryID = l.nextID
+	entry.Created, entry.Updated = now, now
+	l.nextID++
+	l.entries = append([]*LogEntry{&entry}, l.entries...)
+	if len(l.entries) > logMaxLen {
+		l.entries = l.entries[:logMaxLen]
+	}
+}
+
+// Entries returns up to count entries, newest first. A negative count returns all of them.
+func (l *Log) Entries(count int) []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+	entries := make([]LogEntry, 0, count)
+	for _, e := range l.entries[:count] {
+		entries = append(entries, *e)
+	}
+	return entries
+}
+
+// Reset empties the log.
+func (l *Log) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
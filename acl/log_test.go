@@ -0,0 +1,44 @@
+package acl
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLog_Add(t *testing.T) {
+	l := newLog()
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	l.Add(LogEntry{Reason: ReasonCommand, Context: "toplevel", Object: "flushall", Username: "alice"})
+	l.Add(LogEntry{Reason: ReasonKey, Context: "toplevel", Object: "secret", Username: "alice"})
+	now = now.Add(time.Second)
+	l.Add(LogEntry{Reason: ReasonCommand, Context: "toplevel", Object: "flushall", Username: "alice"})
+
+	entries := l.Entries(-1)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "flushall", entries[0].Object, "the updated entry should be the most recent one")
+	assert.Equal(t, 2, entries[0].Count)
+	assert.Equal(t, int64(0), entries[0].EntryID)
+	assert.Equal(t, now, entries[0].Updated)
+	assert.Equal(t, 1, entries[1].Count)
+
+	now = now.Add(logGroupingWindow)
+	l.Add(LogEntry{Reason: ReasonCommand, Context: "toplevel", Object: "flushall", Username: "alice"})
+	assert.Len(t, l.Entries(-1), 3, "identical denials are grouped for a limited time only")
+	assert.Len(t, l.Entries(1), 1)
+
+	l.Reset()
+	assert.Empty(t, l.Entries(-1))
+}
+
+func TestLog_MaxLen(t *testing.T) {
+	l := newLog()
+	for i := 0; i < logMaxLen+10; i++ {
+		l.Add(LogEntry{Reason: ReasonKey, Object: string(rune('a' + i%26)), Username: string(rune('a' + i/26))})
+	}
+	entries := l.Entries(-1)
+	assert.Len(t, entries, logMaxLen)
+	assert.Equal(t, int64(logMaxLen+9), entries[0].EntryID)
+}
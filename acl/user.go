@@ -0,0 +1,373 @@
+// Package acl implements the users of the server and their permissions, with the rules of Redis ACL.
+package acl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/ynachi/gcache/gerror"
+	"github.com/ynachi/gcache/glob"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// CategoryAll is the category every command belongs to.
+const CategoryAll = "all"
+
+// keyPattern is a glob-style pattern of the keys a user can read, write, or both.
+type keyPattern struct {
+	pattern string
+	read    bool
+	write   bool
+}
+
+// commandRule allows or denies a command, or all the commands of a category when category is set.
+type commandRule struct {
+	allow    bool
+	name     string
+	category string
+}
+
+func (r commandRule) String() string {
+	sign := "-"
+	if r.allow {
+		sign = "+"
+	}
+	if r.category != "" {
+		return sign + "@" + r.category
+	}
+	return sign + r.name
+}
+
+// User is a user of the server along with its permissions. Users are modified in place by ACL SETUSER,
+// so that the clients authenticated as a user are immediately subject to its new permissions.
+type User struct {
+	mu      sync.RWMutex
+	name    string
+	enabled bool
+	nopass  bool
+	// passwords holds the SHA-256 of the passwords, in hexadecimal.
+	passwords []string
+	// commands are applied in order, the last rule matching a command deciding if it is allowed.
+	commands []commandRule
+	keys     []keyPattern
+	channels []string
+}
+
+// newUser creates a user which is disabled, has no password and cannot run any command.
+func newUser(name string) *User {
+	return &User{name: name, commands: []commandRule{{category: CategoryAll}}}
+}
+
+// Name returns the name of the user.
+func (u *User) Name() string {
+	return u.name
+}
+
+// Enabled tells if clients can authenticate as the user.
+func (u *User) Enabled() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.enabled
+}
+
+// NoPass tells if the user can authenticate with any password.
+func (u *User) NoPass() bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.nopass
+}
+
+// HashPassword returns the SHA-256 of a password in hexadecimal, the form under which passwords are stored.
+func HashPassword(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkPassword tells if a password is one of the passwords of the user, or if the user does not need any.
+func (u *User) checkPassword(password string) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return u.nopass || slices.Contains(u.passwords, HashPassword(password))
+}
+
+// CanRun tells if the user is allowed to run a command belonging to categories. A subcommand, named like
+// client|kill, is also allowed or denied by the rules on its command.
+func (u *User) CanRun(name string, categories []string) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	command, _, _ := strings.Cut(name, "|")
+	allowed := false
+	for _, rule := range u.commands {
+		if rule.name == name || rule.name == command || rule.category == CategoryAll || (rule.category != "" && slices.Contains(categories, rule.category)) {
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// CanAccessKey tells if the user is allowed to read a key, or to write it if write is set.
+func (u *User) CanAccessKey(key string, write bool) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, p := range u.keys {
+		if ((write && p.write) || (!write && p.read)) && glob.Match(p.pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessChannel tells if the user is allowed to access a channel. When literal is set, the channel is a pattern
+// subscribed to with PSUBSCRIBE, which has to be one of the patterns of the user rather than match it.
+func (u *User) CanAccessChannel(channel string, literal bool) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	for _, p := range u.channels {
+		if p == "*" || p == channel || (!literal && glob.Match(p, channel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRules applies ACL rules to the user, in order. Either all the rules are applied, or none if one is invalid.
+// known validates the commands and categories the rules refer to.
+func (u *User) SetRules(known Commands, rules ...string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	updated := u.clone()
+	for _, rule := range rules {
+		if err := updated.apply(known, rule); err != nil {
+			return fmt.Errorf("Error in ACL SETUSER modifier '%s': %w", rule, err)
+		}
+	}
+	u.enabled, u.nopass = updated.enabled, updated.nopass
+	u.passwords, u.commands, u.keys, u.channels = updated.passwords, updated.commands, updated.keys, updated.channels
+	return nil
+}
+
+// clone copies the permissions of the user. The caller must hold the lock.
+func (u *User) clone() *User {
+	return &User{
+		name:      u.name,
+		enabled:   u.enabled,
+		nopass:    u.nopass,
+		passwords: slices.Clone(u.passwords),
+		commands:  slices.Clone(u.commands),
+		keys:      slices.Clone(u.keys),
+		channels:  slices.Clone(u.channels),
+	}
+}
+
+// apply applies a single rule to a user which is not shared yet.
+func (u *User) apply(known Commands, rule string) error {
+	switch lower := strings.ToLower(rule); {
+	case lower == "on":
+		u.enabled = true
+	case lower == "off":
+		u.enabled = false
+	case lower == "nopass":
+		u.nopass = true
+		u.passwords = nil
+	case lower == "resetpass":
+		u.nopass = false
+		u.passwords = nil
+	case lower == "allkeys":
+		u.keys = []keyPattern{{pattern: "*", read: true, write: true}}
+	case lower == "resetkeys":
+		u.keys = nil
+	case lower == "allchannels":
+		u.channels = []string{"*"}
+	case lower == "resetchannels":
+		u.channels = nil
+	case lower == "allcommands":
+		u.commands = []commandRule{{allow: true, category: CategoryAll}}
+	case lower == "nocommands":
+		u.commands = []commandRule{{category: CategoryAll}}
+	case lower == "reset":
+		u.enabled, u.nopass = false, false
+		u.passwords, u.keys, u.channels = nil, nil, nil
+		u.commands = []commandRule{{category: CategoryAll}}
+	case rule == "":
+		return gerror.ErrSyntax
+	case rule[0] == '>':
+		u.addPassword(HashPassword(rule[1:]))
+	case rule[0] == '<':
+		return u.removePassword(HashPassword(rule[1:]))
+	case rule[0] == '#':
+		if !isPasswordHash(rule[1:]) {
+			return gerror.ErrInvalidPassHash
+		}
+		u.addPassword(rule[1:])
+	case rule[0] == '!':
+		if !isPasswordHash(rule[1:]) {
+			return gerror.ErrInvalidPassHash
+		}
+		return u.removePassword(rule[1:])
+	case rule[0] == '~':
+		u.keys = append(u.keys, keyPattern{pattern: rule[1:], read: true, write: true})
+	case rule[0] == '%':
+		return u.addKeyPattern(rule)
+	case rule[0] == '&':
+		if !slices.Contains(u.channels, rule[1:]) {
+			u.channels = append(u.channels, rule[1:])
+		}
+	case rule[0] == '+' || rule[0] == '-':
+		return u.addCommandRule(known, rule[0] == '+', lower[1:])
+	default:
+		return gerror.ErrSyntax
+	}
+	return nil
+}
+
+func (u *User) addPassword(hash string) {
+	u.nopass = false
+	if !slices.Contains(u.passwords, hash) {
+		u.passwords = append(u.passwords, hash)
+	}
+}
+
+func (u *User) removePassword(hash string) error {
+	i := slices.Index(u.passwords, hash)
+	if i < 0 {
+		return gerror.ErrNoSuchPassword
+	}
+	u.passwords = slices.Delete(u.passwords, i, i+1)
+	return nil
+}
+
+// addKeyPattern adds a pattern of keys with restricted access: %R~ for read, %W~ for write and %RW~ for both.
+func (u *User) addKeyPattern(rule string) error {
+	permissions, pattern, ok := strings.Cut(rule[1:], "~")
+	if !ok || permissions == "" {
+		return gerror.ErrSyntax
+	}
+	p := keyPattern{pattern: pattern}
+	for _, c := range strings.ToUpper(permissions) {
+		switch c {
+		case 'R':
+			p.read = true
+		case 'W':
+			p.write = true
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	u.keys = append(u.keys, p)
+	return nil
+}
+
+// addCommandRule allows or denies a command, or a category prefixed with @. A rule on all the commands
+// overrides all the previous ones, which are dropped.
+func (u *User) addCommandRule(known Commands, allow bool, target string) error {
+	rule := commandRule{allow: allow, name: target}
+	if category, ok := strings.CutPrefix(target, "@"); ok {
+		if category != CategoryAll && !known.HasCategory(category) {
+			return gerror.ErrUnknownCategory
+		}
+		rule = commandRule{allow: allow, category: category}
+	} else if !known.HasCommand(target) {
+		return gerror.ErrUnknownCommand
+	}
+	if rule.category == CategoryAll {
+		u.commands = []commandRule{rule}
+		return nil
+	}
+	u.commands = slices.DeleteFunc(u.commands, func(r commandRule) bool {
+		return r.name == rule.name && r.category == rule.category
+	})
+	u.commands = append(u.commands, rule)
+	return nil
+}
+
+// isPasswordHash tells if a string is a SHA-256 in lowercase hexadecimal.
+func isPasswordHash(s string) bool {
+	if len(s) != 2*sha256.Size {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Flags returns the state of the user: on or off, and nopass if it needs no password.
+func (u *User) Flags() []string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	flags := []string{"off"}
+	if u.enabled {
+		flags[0] = "on"
+	}
+	if u.nopass {
+		flags = append(flags, "nopass")
+	}
+	return flags
+}
+
+// Passwords returns the SHA-256 of the passwords of the user.
+func (u *User) Passwords() []string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	return slices.Clone(u.passwords)
+}
+
+// CommandRules describes the commands the user can run, like "+@all -flushall".
+func (u *User) CommandRules() string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	rules := make([]string, 0, len(u.commands))
+	for _, rule := range u.commands {
+		rules = append(rules, rule.String())
+	}
+	return strings.Join(rules, " ")
+}
+
+// KeyRules describes the keys the user can access, like "~cache:* %R~config:*".
+func (u *User) KeyRules() string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	rules := make([]string, 0, len(u.keys))
+	for _, p := range u.keys {
+		switch {
+		case p.read && p.write:
+			rules = append(rules, "~"+p.pattern)
+		case p.read:
+			rules = append(rules, "%R~"+p.pattern)
+		default:
+			rules = append(rules, "%W~"+p.pattern)
+		}
+	}
+	return strings.Join(rules, " ")
+}
+
+// ChannelRules describes the channels the user can access, like "&news:*".
+func (u *User) ChannelRules() string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	rules := make([]string, 0, len(u.channels))
+	for _, channel := range u.channels {
+		rules = append(rules, "&"+channel)
+	}
+	return strings.Join(rules, " ")
+}
+
+// String describes the user with the rules recreating it, as listed by ACL LIST and stored in ACL files.
+func (u *User) String() string {
+	parts := append([]string{"user", u.name}, u.Flags()...)
+	for _, hash := range u.Passwords() {
+		parts = append(parts, "#"+hash)
+	}
+	if keys := u.KeyRules(); keys != "" {
+		parts = append(parts, keys)
+	}
+	channels := u.ChannelRules()
+	if channels == "" {
+		channels = "resetchannels"
+	}
+	return strings.Join(append(parts, channels, u.CommandRules()), " ")
+}
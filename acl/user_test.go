@@ -0,0 +1,127 @@
+package acl
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"slices"
+	"testing"
+)
+
+// fakeCommands knows a few commands and categories.
+type fakeCommands struct{}
+
+func (fakeCommands) HasCommand(name string) bool {
+	return slices.Contains([]string{"get", "set", "flushall", "publish"}, name)
+}
+
+func (fakeCommands) HasCategory(category string) bool {
+	return slices.Contains([]string{"read", "write", "dangerous", "pubsub"}, category)
+}
+
+func TestUser_CanRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      []string
+		command    string
+		categories []string
+		want       bool
+	}{
+		{name: "NoCommands", rules: nil, command: "get", categories: []string{"read"}},
+		{name: "AllCommands", rules: []string{"allcommands"}, command: "get", categories: []string{"read"}, want: true},
+		{name: "Command", rules: []string{"+get"}, command: "get", categories: []string{"read"}, want: true},
+		{name: "Category", rules: []string{"+@read"}, command: "get", categories: []string{"read"}, want: true},
+		{name: "OtherCategory", rules: []string{"+@write"}, command: "get", categories: []string{"read"}},
+		{name: "CommandRemovedFromCategory", rules: []string{"+@read", "-get"}, command: "get", categories: []string{"read"}},
+		{name: "LastRuleWins", rules: []string{"-get", "+@read"}, command: "get", categories: []string{"read"}, want: true},
+		{name: "Subcommand", rules: []string{"+get"}, command: "get|sub", categories: []string{"dangerous"}, want: true},
+		{name: "SubcommandCategory", rules: []string{"+@read"}, command: "get|sub", categories: []string{"dangerous"}},
+		{name: "AllButDangerous", rules: []string{"+@all", "-@dangerous"}, command: "flushall", categories: []string{"write", "dangerous"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("alice")
+			assert.NoError(t, u.SetRules(fakeCommands{}, tt.rules...))
+			assert.Equal(t, tt.want, u.CanRun(tt.command, tt.categories))
+		})
+	}
+}
+
+func TestUser_CanAccessKey(t *testing.T) {
+	u := newUser("alice")
+	assert.NoError(t, u.SetRules(fakeCommands{}, "~cache:*", "%R~config:*", "%W~log:*"))
+
+	assert.True(t, u.CanAccessKey("cache:1", false))
+	assert.True(t, u.CanAccessKey("cache:1", true))
+	assert.True(t, u.CanAccessKey("config:1", false))
+	assert.False(t, u.CanAccessKey("config:1", true))
+	assert.False(t, u.CanAccessKey("log:1", false))
+	assert.True(t, u.CanAccessKey("log:1", true))
+	assert.False(t, u.CanAccessKey("other", false))
+
+	assert.NoError(t, u.SetRules(fakeCommands{}, "resetkeys"))
+	assert.False(t, u.CanAccessKey("cache:1", false))
+}
+
+func TestUser_CanAccessChannel(t *testing.T) {
+	u := newUser("alice")
+	assert.NoError(t, u.SetRules(fakeCommands{}, "&news:*"))
+
+	assert.True(t, u.CanAccessChannel("news:sport", false))
+	assert.False(t, u.CanAccessChannel("weather", false))
+	assert.True(t, u.CanAccessChannel("news:*", true))
+	assert.False(t, u.CanAccessChannel("news:s*", true), "patterns are compared literally")
+
+	assert.NoError(t, u.SetRules(fakeCommands{}, "allchannels"))
+	assert.True(t, u.CanAccessChannel("weather", false))
+}
+
+func TestUser_Passwords(t *testing.T) {
+	u := newUser("alice")
+	assert.NoError(t, u.SetRules(fakeCommands{}, ">secret", "#"+HashPassword("other")))
+	assert.True(t, u.checkPassword("secret"))
+	assert.True(t, u.checkPassword("other"))
+	assert.False(t, u.checkPassword("wrong"))
+
+	assert.NoError(t, u.SetRules(fakeCommands{}, "<secret"))
+	assert.False(t, u.checkPassword("secret"))
+	assert.Equal(t, []string{HashPassword("other")}, u.Passwords())
+
+	assert.NoError(t, u.SetRules(fakeCommands{}, "nopass"))
+	assert.True(t, u.checkPassword("anything"))
+	assert.Empty(t, u.Passwords())
+}
+
+func TestUser_SetRulesErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want error
+	}{
+		{name: "UnknownRule", rule: "whatever", want: gerror.ErrSyntax},
+		{name: "UnknownCommand", rule: "+nonexistent", want: gerror.ErrUnknownCommand},
+		{name: "UnknownCategory", rule: "+@nonexistent", want: gerror.ErrUnknownCategory},
+		{name: "InvalidHash", rule: "#1234", want: gerror.ErrInvalidPassHash},
+		{name: "NoSuchPassword", rule: "<missing", want: gerror.ErrNoSuchPassword},
+		{name: "InvalidKeyPermission", rule: "%X~key", want: gerror.ErrSyntax},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newUser("alice")
+			err := u.SetRules(fakeCommands{}, "on", tt.rule)
+			assert.True(t, errors.Is(err, tt.want), "got %v", err)
+			assert.False(t, u.Enabled(), "no rule should be applied when one is invalid")
+		})
+	}
+}
+
+func TestUser_String(t *testing.T) {
+	u := newUser("alice")
+	assert.Equal(t, "user alice off resetchannels -@all", u.String())
+
+	assert.NoError(t, u.SetRules(fakeCommands{}, "on", ">secret", "~cache:*", "%R~config:*", "&news", "+@read", "-get"))
+	want := "user alice on #" + HashPassword("secret") + " ~cache:* %R~config:* &news -@all +@read -get"
+	assert.Equal(t, want, u.String())
+}
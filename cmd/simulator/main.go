@@ -0,0 +1,118 @@
+// Command simulator compares the eviction policies of gcache by replaying a trace of key accesses.
+//
+// Usage:
+//
+//	simulator -trace accesses.txt [-sizes 100,1000,10000] [-policies lru,lfu,arc]
+//	simulator -capture 127.0.0.1:6379 -n 100000 -duration 1m -out accesses.txt
+//
+// A trace holds one access per line, a key optionally followed by the size of its value, separated by a comma
+// or spaces. The capture mode records such a trace from a live server through the MONITOR command.
+// Each policy replays the trace at each cache size, expressed in number of keys, and the hit ratio,
+// byte hit ratio and throughput are reported side by side.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ynachi/gcache/db"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func main() {
+	tracePath := flag.String("trace", "", "path of the trace to replay, - for the standard input")
+	sizes := flag.String("sizes", "100,1000,10000", "comma separated cache sizes, in number of keys")
+	policies := flag.String("policies", strings.Join(db.EvictionPolicies(), ","), "comma separated policies to compare")
+	captureAddress := flag.String("capture", "", "address of a server to capture a trace from with MONITOR")
+	captureCount := flag.Int("n", 100000, "number of accesses to capture")
+	captureDuration := flag.Duration("duration", time.Minute, "maximum duration of the capture")
+	out := flag.String("out", "-", "path of the captured trace, - for the standard output")
+	flag.Parse()
+
+	var err error
+	if *captureAddress != "" {
+		err = runCapture(*captureAddress, *captureCount, *captureDuration, *out)
+	} else {
+		err = runSimulation(*tracePath, *sizes, *policies, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "simulator: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runCapture records a trace from a live server and writes it to a file.
+func runCapture(address string, n int, duration time.Duration, out string) error {
+	trace, err := capture(address, n, duration)
+	if err != nil {
+		return err
+	}
+	if out == "-" {
+		return writeTrace(os.Stdout, trace)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err = writeTrace(f, trace); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// runSimulation replays a trace against each policy and size, and writes a report.
+func runSimulation(tracePath string, sizes string, policies string, w io.Writer) error {
+	if tracePath == "" {
+		return fmt.Errorf("a trace is required, see -help")
+	}
+	var in io.Reader = os.Stdin
+	if tracePath != "-" {
+		f, err := os.Open(tracePath)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+	trace, err := readTrace(in)
+	if err != nil {
+		return err
+	}
+
+	var cacheSizes []int64
+	for _, s := range strings.Split(sizes, ",") {
+		size, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		if err != nil || size <= 0 {
+			return fmt.Errorf("invalid cache size %q", s)
+		}
+		cacheSizes = append(cacheSizes, size)
+	}
+
+	var results []result
+	for _, size := range cacheSizes {
+		for _, policy := range strings.Split(policies, ",") {
+			r, err := simulate(strings.TrimSpace(policy), size, trace)
+			if err != nil {
+				return fmt.Errorf("%s: %w", policy, err)
+			}
+			results = append(results, r)
+		}
+	}
+	return report(w, results)
+}
+
+// report writes the results as a table.
+func report(w io.Writer, results []result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintln(tw, "size\tpolicy\thit ratio\tbyte hit ratio\trequests/s\t")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%.2f%%\t%.2f%%\t%.0f\t\n",
+			r.size, r.policy, 100*r.hitRatio(), 100*r.byteHitRatio(), r.throughput())
+	}
+	return tw.Flush()
+}
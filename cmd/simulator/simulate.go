@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/ynachi/gcache/db"
+	"time"
+)
+
+// result holds the performance of a policy replaying a trace with a given cache size.
+type result struct {
+	policy       string
+	size         int64
+	hits         int
+	requests     int
+	hitBytes     int64
+	requestBytes int64
+	elapsed      time.Duration
+}
+
+// hitRatio returns the share of the requests served by the cache.
+func (r result) hitRatio() float64 {
+	if r.requests == 0 {
+		return 0
+	}
+	return float64(r.hits) / float64(r.requests)
+}
+
+// byteHitRatio returns the share of the requested bytes served by the cache.
+func (r result) byteHitRatio() float64 {
+	if r.requestBytes == 0 {
+		return 0
+	}
+	return float64(r.hitBytes) / float64(r.requestBytes)
+}
+
+// throughput returns the number of requests replayed per second.
+func (r result) throughput() float64 {
+	if r.elapsed <= 0 {
+		return 0
+	}
+	return float64(r.requests) / r.elapsed.Seconds()
+}
+
+// simulate replays a trace against a cache using the given policy, like a read-through cache would:
+// a key missing from the cache is stored once requested. Only the keys are stored, the sizes being
+// accounted for by the simulation itself.
+func simulate(policy string, size int64, trace []access) (result, error) {
+	cache, err := db.NewCache(size, policy)
+	if err != nil {
+		return result{}, err
+	}
+	r := result{policy: policy, size: size, requests: len(trace)}
+	start := time.Now()
+	for _, a := range trace {
+		r.requestBytes += int64(a.size)
+		if _, ok := cache.Get(a.key); ok {
+			r.hits++
+			r.hitBytes += int64(a.size)
+			continue
+		}
+		// policies which cannot evict anything refuse new keys, which then keep missing
+		_ = cache.Set(a.key, "")
+	}
+	r.elapsed = time.Since(start)
+	return r, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errMalformedMonitorLine = errors.New("malformed MONITOR line")
+
+// access is a request for a key in a trace. size is the number of bytes of its value.
+type access struct {
+	key  string
+	size int
+}
+
+// readTrace reads a trace made of one access per line: a key optionally followed by the size of its value,
+// separated by a comma or spaces, so that both plain text and CSV files are accepted. The size defaults to 1,
+// which makes the byte hit ratio equal to the hit ratio. Empty lines and lines starting with # are ignored,
+// as well as a CSV header, the first of the other lines, whose size column is not a number.
+func readTrace(r io.Reader) ([]access, error) {
+	var trace []access
+	scanner := bufio.NewScanner(r)
+	line := 0
+	header := true
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		// a line made of separators only holds no access
+		if len(fields) == 0 {
+			continue
+		}
+		first := header
+		header = false
+		a := access{key: fields[0], size: 1}
+		if len(fields) > 1 {
+			size, err := strconv.Atoi(fields[1])
+			if err != nil {
+				if first {
+					continue
+				}
+				return nil, fmt.Errorf("line %d: invalid size %q", line, fields[1])
+			}
+			a.size = size
+		}
+		trace = append(trace, a)
+	}
+	return trace, scanner.Err()
+}
+
+// writeTrace writes a trace in the format read by readTrace.
+func writeTrace(w io.Writer, trace []access) error {
+	bw := bufio.NewWriter(w)
+	for _, a := range trace {
+		if _, err := fmt.Fprintf(bw, "%s %d\n", a.key, a.size); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// redisKeySpecs locate the keys of the Redis commands the server does not implement, as traces may be captured
+// from Redis. Like the specs of the server, the command name is at index 0.
+var redisKeySpecs = map[string]*command.Spec{
+	"exists": {FirstKey: 1, LastKey: -1, Step: 1},
+	"mget":   {FirstKey: 1, LastKey: -1, Step: 1},
+	"unlink": {FirstKey: 1, LastKey: -1, Step: 1},
+	"touch":  {FirstKey: 1, LastKey: -1, Step: 1},
+	"mset":   {FirstKey: 1, LastKey: -1, Step: 2},
+	"msetnx": {FirstKey: 1, LastKey: -1, Step: 2},
+	"setnx":  {FirstKey: 1, LastKey: 1, Step: 1},
+	"getset": {FirstKey: 1, LastKey: 1, Step: 1},
+	"setex":  {FirstKey: 1, LastKey: 1, Step: 1},
+	"psetex": {FirstKey: 1, LastKey: 1, Step: 1},
+	"incr":   {FirstKey: 1, LastKey: 1, Step: 1},
+	"decr":   {FirstKey: 1, LastKey: 1, Step: 1},
+	"append": {FirstKey: 1, LastKey: 1, Step: 1},
+	"strlen": {FirstKey: 1, LastKey: 1, Step: 1},
+}
+
+// valueCommands are the commands setting a key to a value, with the position of the value in their arguments.
+var valueCommands = map[string]int{"set": 2, "setnx": 2, "getset": 2, "setex": 3, "psetex": 3}
+
+// keySpec returns the spec locating the keys of a command, the one of the server if it implements the command.
+func keySpec(name string) (*command.Spec, bool) {
+	if spec, ok := command.Lookup(name); ok {
+		return spec, true
+	}
+	spec, ok := redisKeySpecs[name]
+	return spec, ok
+}
+
+// parseMonitorLine returns the keys accessed by a command logged by MONITOR, whose lines look like:
+//
+//	1339518083.107412 [0 127.0.0.1:60866] "set" "key" "value"
+//
+// The keys are located by the spec of the command. Commands without keys, like PING, SELECT or PUBLISH, and
+// unknown ones return no access.
+func parseMonitorLine(line string) ([]access, error) {
+	start := strings.IndexByte(line, ']')
+	if start < 0 {
+		return nil, errMalformedMonitorLine
+	}
+	args, err := splitQuoted(line[start+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 2 {
+		return nil, nil
+	}
+	name := strings.ToLower(args[0])
+	spec, ok := keySpec(name)
+	if !ok {
+		return nil, nil
+	}
+	keys := spec.Keys(args)
+	trace := make([]access, 0, len(keys))
+	for i, key := range keys {
+		a := access{key: key, size: 1}
+		if spec.Step == 2 {
+			// keys followed by their value, like with MSET
+			if value := spec.FirstKey + 2*i + 1; value < len(args) {
+				a.size = len(args[value])
+			}
+		} else if value, ok := valueCommands[name]; ok && i == 0 && value < len(args) {
+			a.size = len(args[value])
+		}
+		trace = append(trace, a)
+	}
+	return trace, nil
+}
+
+// splitQuoted splits a sequence of double-quoted and escaped strings.
+func splitQuoted(s string) ([]string, error) {
+	var args []string
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return args, nil
+		}
+		if s[0] != '"' {
+			return nil, errMalformedMonitorLine
+		}
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return nil, errMalformedMonitorLine
+		}
+		arg, err := strconv.Unquote(s[:end+1])
+		if err != nil {
+			return nil, errMalformedMonitorLine
+		}
+		args = append(args, arg)
+		s = s[end+1:]
+	}
+}
+
+// capture records the keys accessed on a live server through MONITOR, until n accesses were recorded
+// or the duration elapsed.
+func capture(address string, n int, duration time.Duration) ([]access, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetReadDeadline(time.Now().Add(duration))
+
+	cmd := frame.NewArray(1)
+	_ = cmd.Append(frame.NewBulkString("MONITOR"))
+	if _, err = cmd.WriteTo(conn); err != nil {
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	var trace []access
+	for len(trace) < n {
+		f, err := frame.Decode(reader)
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch f := f.(type) {
+		case *frame.SimpleString:
+			if f.Value() == "OK" {
+				continue
+			}
+			accesses, err := parseMonitorLine(f.Value())
+			if err != nil {
+				return nil, err
+			}
+			trace = append(trace, accesses...)
+		case *frame.Error:
+			return nil, errors.New(f.String())
+		}
+	}
+	return trace, nil
+}
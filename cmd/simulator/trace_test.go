@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestReadTrace(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    []access
+		wantErr bool
+	}{
+		{
+			name: "Text",
+			give: "# comment\nfirst\nsecond 10\n\nthird\t20\n",
+			want: []access{{key: "first", size: 1}, {key: "second", size: 10}, {key: "third", size: 20}},
+		},
+		{
+			name: "CSV",
+			give: "key,size\nfirst,10\nsecond,20\n",
+			want: []access{{key: "first", size: 10}, {key: "second", size: 20}},
+		},
+		{
+			name: "CSVAfterComment",
+			give: "# captured on a test server\n\nkey,size\nfirst,10\n",
+			want: []access{{key: "first", size: 10}},
+		},
+		{
+			name: "SeparatorsOnly",
+			give: "first,10\n,\n , \t\nsecond,20\n",
+			want: []access{{key: "first", size: 10}, {key: "second", size: 20}},
+		},
+		{
+			name:    "InvalidSize",
+			give:    "first,10\nsecond,big\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTrace(strings.NewReader(tt.give))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// a written trace reads back identically
+			buf := &bytes.Buffer{}
+			assert.NoError(t, writeTrace(buf, got))
+			again, err := readTrace(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, got, again)
+		})
+	}
+}
+
+func TestParseMonitorLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    []access
+		wantErr bool
+	}{
+		{
+			name: "Get",
+			give: `1339518083.107412 [0 127.0.0.1:60866] "GET" "user:1"`,
+			want: []access{{key: "user:1", size: 1}},
+		},
+		{
+			name: "SetWithEscapes",
+			give: `1339518083.107412 [0 127.0.0.1:60866] "set" "say \"hi\"" "a\x00b\n"`,
+			want: []access{{key: `say "hi"`, size: 4}},
+		},
+		{
+			name: "Del",
+			give: `1339518083.107412 [1 unix:/tmp/redis.sock] "del" "first" "second"`,
+			want: []access{{key: "first", size: 1}, {key: "second", size: 1}},
+		},
+		{
+			name: "MSet",
+			give: `1339518083.107412 [0 127.0.0.1:60866] "mset" "first" "a" "second" "bcd"`,
+			want: []access{{key: "first", size: 1}, {key: "second", size: 3}},
+		},
+		{
+			name: "UnknownCommand",
+			give: `1339518083.107412 [0 127.0.0.1:60866] "xadd" "stream" "*" "field" "value"`,
+		},
+		{
+			name: "NoKey",
+			give: `1339518083.107412 [0 127.0.0.1:60866] "ping"`,
+		},
+		{
+			name:    "Malformed",
+			give:    `1339518083.107412 [0 127.0.0.1:60866] "get" "unterminated`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMonitorLine(tt.give)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	trace := []access{{key: "a", size: 10}, {key: "b", size: 1}, {key: "a", size: 10}, {key: "c", size: 1}}
+	r, err := simulate("lru", 10, trace)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.hits)
+	assert.Equal(t, 0.25, r.hitRatio())
+	assert.InDelta(t, 10.0/22, r.byteHitRatio(), 1e-9)
+
+	_, err = simulate("unknown", 10, trace)
+	assert.Error(t, err)
+}
+
+func TestParseMonitorLine_MixedCapture(t *testing.T) {
+	capture := []string{
+		`1339518083.107412 [0 127.0.0.1:60866] "HELLO" "3" "AUTH" "default" "(redacted)"`,
+		`1339518083.107413 [0 127.0.0.1:60866] "SELECT" "1"`,
+		`1339518083.107414 [1 127.0.0.1:60866] "SET" "user:1" "alice"`,
+		`1339518083.107415 [1 127.0.0.1:60866] "PUBLISH" "news" "hello"`,
+		`1339518083.107416 [1 127.0.0.1:60866] "CONFIG" "SET" "maxmemory-samples" "10"`,
+		`1339518083.107417 [1 127.0.0.1:60866] "MSET" "user:2" "bob" "user:3" "carol"`,
+		`1339518083.107418 [1 127.0.0.1:60866] "CLIENT" "SETNAME" "worker"`,
+		`1339518083.107419 [1 127.0.0.1:60866] "GET" "user:2"`,
+	}
+	var trace []access
+	for _, line := range capture {
+		accesses, err := parseMonitorLine(line)
+		assert.NoError(t, err, line)
+		trace = append(trace, accesses...)
+	}
+	assert.Equal(t, []access{
+		{key: "user:1", size: 5},
+		{key: "user:2", size: 3},
+		{key: "user:3", size: 5},
+		{key: "user:2", size: 1},
+	}, trace, "only the keys should be recorded")
+}
@@ -0,0 +1,197 @@
+package command
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "acl",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Manages the users and their permissions.",
+		Group:      "server",
+		Since:      "6.0.0",
+		New:        func() Command { return new(ACL) },
+	})
+}
+
+// ChannelsAccessor is implemented by pub/sub commands accessing channels, or channel patterns when patterns is set.
+// The channels are checked against the permissions of the user running the command.
+type ChannelsAccessor interface {
+	Channels() (channels []string, patterns bool)
+}
+
+// ACL manages the users with the SETUSER, GETUSER, DELUSER, LIST, USERS, WHOAMI, CAT and LOG subcommands.
+type ACL struct {
+	subcommand string
+	args       []string
+	session    Session
+}
+
+func (c *ACL) Apply(_ *db.Cache, dest ReplyWriter) {
+	users := c.session.ACL()
+	switch c.subcommand {
+	case "setuser":
+		if err := users.SetUser(c.args[0], c.args[1:]...); err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteOK()
+	case "getuser":
+		user, ok := users.User(c.args[0])
+		if !ok {
+			dest.WriteNull()
+			return
+		}
+		dest.WriteFrame(describeUser(user))
+	case "deluser":
+		deleted, err := c.session.DelUsers(c.args...)
+		if err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteFrame(frame.NewInteger(int64(deleted)))
+	case "list", "users":
+		all := users.Users()
+		resp := frame.NewArray(len(all))
+		for _, user := range all {
+			if c.subcommand == "list" {
+				_ = resp.Append(frame.NewBulkString(user.String()))
+				continue
+			}
+			_ = resp.Append(frame.NewBulkString(user.Name()))
+		}
+		dest.WriteFrame(resp)
+	case "whoami":
+		dest.WriteFrame(frame.NewBulkString(c.session.User()))
+	case "cat":
+		c.replyCategories(dest)
+	case "log":
+		c.replyLog(dest, users.Log())
+	}
+}
+
+// describeUser returns the permissions of a user, as replied by ACL GETUSER.
+func describeUser(user *acl.User) *frame.Map {
+	flags := user.Flags()
+	flagsResp := frame.NewArray(len(flags))
+	for _, flag := range flags {
+		_ = flagsResp.Append(frame.NewBulkString(flag))
+	}
+	passwords := user.Passwords()
+	passwordsResp := frame.NewArray(len(passwords))
+	for _, hash := range passwords {
+		_ = passwordsResp.Append(frame.NewBulkString(hash))
+	}
+	resp := frame.NewMap(6)
+	_ = resp.Append(frame.NewBulkString("flags"), flagsResp)
+	_ = resp.Append(frame.NewBulkString("passwords"), passwordsResp)
+	_ = resp.Append(frame.NewBulkString("commands"), frame.NewBulkString(user.CommandRules()))
+	_ = resp.Append(frame.NewBulkString("keys"), frame.NewBulkString(user.KeyRules()))
+	_ = resp.Append(frame.NewBulkString("channels"), frame.NewBulkString(user.ChannelRules()))
+	_ = resp.Append(frame.NewBulkString("selectors"), frame.NewArray(0))
+	return resp
+}
+
+// replyCategories replies the ACL categories, or the commands of the category given.
+func (c *ACL) replyCategories(dest ReplyWriter) {
+	var names []string
+	if len(c.args) == 0 {
+		names = Categories()
+	} else {
+		category := strings.ToLower(c.args[0])
+		for _, spec := range Specs() {
+			if spec.HasCategory(category) {
+				names = append(names, spec.Name)
+			}
+		}
+		if len(names) == 0 {
+			dest.WriteError(fmt.Errorf("%w '%s'", gerror.ErrUnknownCategory, c.args[0]))
+			return
+		}
+	}
+	resp := frame.NewArray(len(names))
+	for _, name := range names {
+		_ = resp.Append(frame.NewBulkString(name))
+	}
+	dest.WriteFrame(resp)
+}
+
+// replyLog replies the most recent entries of the log, or resets it.
+func (c *ACL) replyLog(dest ReplyWriter, log *acl.Log) {
+	count := 10
+	if len(c.args) == 1 {
+		if strings.EqualFold(c.args[0], "reset") {
+			log.Reset()
+			dest.WriteOK()
+			return
+		}
+		n, err := strconv.Atoi(c.args[0])
+		if err != nil || n < 0 {
+			dest.WriteError(gerror.ErrNotInteger)
+			return
+		}
+		count = n
+	}
+	entries := log.Entries(count)
+	now := time.Now()
+	resp := frame.NewArray(len(entries))
+	for _, e := range entries {
+		entry := frame.NewMap(10)
+		_ = entry.Append(frame.NewBulkString("count"), frame.NewInteger(int64(e.Count)))
+		_ = entry.Append(frame.NewBulkString("reason"), frame.NewBulkString(e.Reason))
+		_ = entry.Append(frame.NewBulkString("context"), frame.NewBulkString(e.Context))
+		_ = entry.Append(frame.NewBulkString("object"), frame.NewBulkString(e.Object))
+		_ = entry.Append(frame.NewBulkString("username"), frame.NewBulkString(e.Username))
+		age := strconv.FormatFloat(now.Sub(e.Created).Seconds(), 'f', 3, 64)
+		_ = entry.Append(frame.NewBulkString("age-seconds"), frame.NewBulkString(age))
+		_ = entry.Append(frame.NewBulkString("client-info"), frame.NewBulkString(e.ClientInfo))
+		_ = entry.Append(frame.NewBulkString("entry-id"), frame.NewInteger(e.EntryID))
+		_ = entry.Append(frame.NewBulkString("timestamp-created"), frame.NewInteger(e.Created.UnixMilli()))
+		_ = entry.Append(frame.NewBulkString("timestamp-last-updated"), frame.NewInteger(e.Updated.UnixMilli()))
+		_ = resp.Append(entry)
+	}
+	dest.WriteFrame(resp)
+}
+
+func (c *ACL) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.subcommand, c.args = strings.ToLower(args[0]), args[1:]
+	valid := false
+	switch c.subcommand {
+	case "setuser", "deluser":
+		valid = len(c.args) >= 1
+	case "getuser":
+		valid = len(c.args) == 1
+	case "list", "users", "whoami":
+		valid = len(c.args) == 0
+	case "cat", "log":
+		valid = len(c.args) <= 1
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	if !valid {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *ACL) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *ACL) Name() string {
+	return "acl"
+}
@@ -0,0 +1,69 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "auth",
+		Arity:      -2,
+		Flags:      []string{FlagNoAuth, FlagFast},
+		Categories: []string{CategoryFast, CategoryConnection},
+		Summary:    "Authenticates the connection.",
+		Group:      "connection",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Auth) },
+	})
+}
+
+// Auth authenticates the client as a user. With the password only, it authenticates as the default user,
+// which must have been given a password, with requirepass for instance.
+type Auth struct {
+	username string
+	password string
+	session  Session
+}
+
+func (c *Auth) Apply(_ *db.Cache, dest ReplyWriter) {
+	username := c.username
+	if username == "" {
+		username = acl.DefaultUser
+		if user, ok := c.session.ACL().User(username); ok && user.NoPass() {
+			dest.WriteError(gerror.ErrAuthNotEnabled)
+			return
+		}
+	}
+	if err := c.session.Auth(username, c.password); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *Auth) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	switch len(args) {
+	case 1:
+		c.password = args[0]
+	case 2:
+		c.username, c.password = args[0], args[1]
+	default:
+		return gerror.ErrSyntax
+	}
+	return nil
+}
+
+func (c *Auth) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Auth) Name() string {
+	return "auth"
+}
@@ -0,0 +1,360 @@
+package command
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "client",
+		Arity:      -2,
+		Flags:      []string{},
+		Categories: []string{CategorySlow, CategoryConnection},
+		// acting on the other clients is restricted to the administrators
+		Subcommands: map[string]Subcommand{
+			"kill":    {Categories: []string{CategorySlow, CategoryAdmin, CategoryDangerous}},
+			"list":    {Categories: []string{CategorySlow, CategoryAdmin, CategoryDangerous}},
+			"pause":   {Categories: []string{CategorySlow, CategoryAdmin, CategoryDangerous}},
+			"unpause": {Categories: []string{CategorySlow, CategoryAdmin, CategoryDangerous}},
+		},
+		Summary: "Manages client connections and client side caching.",
+		Group:   "connection",
+		Since:   "2.4.0",
+		New:     func() Command { return new(Client) },
+	})
+}
+
+// ClientFilter selects the clients of CLIENT LIST and CLIENT KILL. Zero fields match all the clients.
+type ClientFilter struct {
+	IDs []int64
+	// Type is normal or pubsub. Clients are never of the master and replica types.
+	Type  string
+	Addr  string
+	LAddr string
+	User  string
+	// MaxAge only matches the clients connected for longer.
+	MaxAge time.Duration
+	// SkipMe leaves out the client running the command.
+	SkipMe bool
+}
+
+// Client manages the connections with the ID, INFO, LIST, KILL, SETNAME, GETNAME, PAUSE and UNPAUSE subcommands,
+// and client side caching with the TRACKING, CACHING and GETREDIR ones.
+type Client struct {
+	subcommand string
+	on         bool
+	tracking   TrackingOptions
+	filter     ClientFilter
+	// addrKill is set for the old form of CLIENT KILL, which takes an address only and fails if nobody is killed.
+	addrKill bool
+	name     string
+	timeout  time.Duration
+	pauseAll bool
+	session  Session
+}
+
+func (c *Client) Apply(_ *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "id":
+		dest.WriteFrame(frame.NewInteger(c.session.ClientID()))
+	case "tracking":
+		if err := c.session.Tracking(c.on, c.tracking); err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteOK()
+	case "caching":
+		if err := c.session.Caching(c.on); err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteOK()
+	case "getredir":
+		dest.WriteFrame(frame.NewInteger(c.session.TrackingRedirect()))
+	case "info":
+		lines := c.session.ClientList(ClientFilter{IDs: []int64{c.session.ClientID()}})
+		dest.WriteFrame(frame.NewBulkString(strings.Join(lines, "\n") + "\n"))
+	case "list":
+		var sb strings.Builder
+		for _, line := range c.session.ClientList(c.filter) {
+			sb.WriteString(line)
+			sb.WriteString("\n")
+		}
+		dest.WriteFrame(frame.NewBulkString(sb.String()))
+	case "kill":
+		killed := c.session.KillClients(c.filter)
+		switch {
+		case !c.addrKill:
+			dest.WriteFrame(frame.NewInteger(int64(killed)))
+		case killed == 0:
+			dest.WriteError(gerror.ErrNoSuchClient)
+		default:
+			dest.WriteOK()
+		}
+	case "setname":
+		c.session.SetClientName(c.name)
+		dest.WriteOK()
+	case "getname":
+		name := c.session.ClientName()
+		if name == "" {
+			dest.WriteNull()
+			return
+		}
+		dest.WriteFrame(frame.NewBulkString(name))
+	case "pause":
+		c.session.PauseClients(c.timeout, c.pauseAll)
+		dest.WriteOK()
+	case "unpause":
+		c.session.UnpauseClients()
+		dest.WriteOK()
+	}
+}
+
+func (c *Client) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.subcommand, args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "id", "getredir", "info", "getname", "unpause":
+		if len(args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "list":
+		return c.listFromArgs(args)
+	case "kill":
+		return c.killFromArgs(args)
+	case "setname":
+		if len(args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		if !validClientName(args[0]) {
+			return gerror.ErrInvalidClientName
+		}
+		c.name = args[0]
+	case "pause":
+		return c.pauseFromArgs(args)
+	case "tracking":
+		return c.trackingFromArgs(args)
+	case "caching":
+		if len(args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		c.on, err = parseYesNo(args[0])
+		return err
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+// trackingFromArgs reads CLIENT TRACKING ON|OFF [REDIRECT id] [PREFIX prefix ...] [BCAST] [OPTIN] [OPTOUT] [NOLOOP].
+func (c *Client) trackingFromArgs(args []string) error {
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		c.on = true
+	case "off":
+		c.on = false
+	default:
+		return gerror.ErrSyntax
+	}
+	for i := 1; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "redirect":
+			if i+1 >= len(args) {
+				return gerror.ErrSyntax
+			}
+			i++
+			id, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				return gerror.ErrNotInteger
+			}
+			c.tracking.Redirect = id
+		case "prefix":
+			if i+1 >= len(args) {
+				return gerror.ErrSyntax
+			}
+			i++
+			c.tracking.Prefixes = append(c.tracking.Prefixes, args[i])
+		case "bcast":
+			c.tracking.BCast = true
+		case "optin":
+			c.tracking.OptIn = true
+		case "optout":
+			c.tracking.OptOut = true
+		case "noloop":
+			c.tracking.NoLoop = true
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	switch {
+	case len(c.tracking.Prefixes) > 0 && !c.tracking.BCast:
+		return gerror.ErrPrefixNeedsBCast
+	case c.tracking.OptIn && c.tracking.OptOut:
+		return gerror.ErrOptInAndOptOut
+	case (c.tracking.OptIn || c.tracking.OptOut) && c.tracking.BCast:
+		return gerror.ErrOptWithBCast
+	}
+	return nil
+}
+
+// listFromArgs reads CLIENT LIST [TYPE normal|master|replica|pubsub] [ID id [id ...]].
+func (c *Client) listFromArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		switch strings.ToLower(args[i]) {
+		case "type":
+			if i+1 >= len(args) {
+				return gerror.ErrSyntax
+			}
+			i++
+			clientType, err := parseClientType(args[i])
+			if err != nil {
+				return err
+			}
+			c.filter.Type = clientType
+		case "id":
+			if i+1 >= len(args) {
+				return gerror.ErrSyntax
+			}
+			for i+1 < len(args) {
+				i++
+				id, err := strconv.ParseInt(args[i], 10, 64)
+				if err != nil || id <= 0 {
+					return gerror.ErrNotInteger
+				}
+				c.filter.IDs = append(c.filter.IDs, id)
+			}
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	return nil
+}
+
+// killFromArgs reads CLIENT KILL addr, or CLIENT KILL followed by filters among ID, TYPE, USER, ADDR, LADDR,
+// SKIPME and MAXAGE. Unlike the old form, the new one skips the client running it unless SKIPME no is given.
+func (c *Client) killFromArgs(args []string) error {
+	if len(args) == 1 {
+		c.addrKill, c.filter.Addr = true, args[0]
+		return nil
+	}
+	if len(args) == 0 || len(args)%2 != 0 {
+		return gerror.ErrSyntax
+	}
+	c.filter.SkipMe = true
+	for i := 0; i < len(args); i += 2 {
+		value := args[i+1]
+		switch strings.ToLower(args[i]) {
+		case "id":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id <= 0 {
+				return gerror.ErrNotInteger
+			}
+			c.filter.IDs = append(c.filter.IDs, id)
+		case "type":
+			clientType, err := parseClientType(value)
+			if err != nil {
+				return err
+			}
+			c.filter.Type = clientType
+		case "user":
+			c.filter.User = value
+		case "addr":
+			c.filter.Addr = value
+		case "laddr":
+			c.filter.LAddr = value
+		case "skipme":
+			skip, err := parseYesNo(value)
+			if err != nil {
+				return err
+			}
+			c.filter.SkipMe = skip
+		case "maxage":
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || seconds < 0 {
+				return gerror.ErrNotInteger
+			}
+			c.filter.MaxAge = time.Duration(seconds) * time.Second
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	return nil
+}
+
+// pauseFromArgs reads CLIENT PAUSE timeout [WRITE|ALL], the timeout being in milliseconds.
+func (c *Client) pauseFromArgs(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	ms, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil || ms < 0 {
+		return gerror.ErrInvalidTimeout
+	}
+	c.timeout, c.pauseAll = time.Duration(ms)*time.Millisecond, true
+	if len(args) == 2 {
+		switch strings.ToLower(args[1]) {
+		case "write":
+			c.pauseAll = false
+		case "all":
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	return nil
+}
+
+// parseClientType reads a client type. Master and replica are accepted although no client is of these types.
+func parseClientType(arg string) (string, error) {
+	clientType := strings.ToLower(arg)
+	switch clientType {
+	case "normal", "pubsub", "master", "replica", "slave":
+		return clientType, nil
+	default:
+		return "", fmt.Errorf("%w '%s'", gerror.ErrInvalidClientType, arg)
+	}
+}
+
+// validClientName tells if a client name is made of printable characters other than spaces only.
+func validClientName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if name[i] < '!' || name[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseYesNo reads a yes or no argument.
+func parseYesNo(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	default:
+		return false, gerror.ErrSyntax
+	}
+}
+
+func (c *Client) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Client) Name() string {
+	return "client"
+}
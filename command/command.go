@@ -1,7 +1,6 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
@@ -13,38 +12,24 @@ type Command interface {
 	// Name returns the command name
 	Name() string
 
-	// Apply applies the command et write back the response to the client
-	Apply(db *db.Cache, dest *bufio.Writer)
+	// Apply applies the command et write back the response to the client.
+	// Replies are buffered by dest, whose owner flushes them.
+	Apply(db *db.Cache, dest ReplyWriter)
 
 	// FromFrame form the command from a Frame
 	FromFrame(f *frame.Array) error
 }
 
-// NewCommand instantiates a concrete command type base on its name.
+// NewCommand instantiates a concrete command type base on its name, as registered with Register.
 // NewCommand should rely on
 // GetCmdName to extract the command name from an Array frame in most cases.
-// This should avoid returning a nil command struct.
+// It returns nil if the command is unknown.
 func NewCommand(cmdName string) Command {
-	switch cmdName {
-	case "ping":
-		return new(Ping)
-	case "set":
-		return new(Set)
-	case "get":
-		return new(Get)
-	case "del":
-		return new(Del)
-	default:
+	spec, ok := Lookup(cmdName)
+	if !ok {
 		return nil
 	}
-}
-
-// use that to fail fast when the command is not valid.
-var registeredCommands = map[string]struct{}{
-	"ping": {},
-	"set":  {},
-	"get":  {},
-	"del":  {},
+	return spec.New()
 }
 
 // GetCmdName gets a command name from a Frame Array.
@@ -58,8 +43,22 @@ func GetCmdName(f *frame.Array) (string, error) {
 		return "", gerror.ErrNotGcacheCmd
 	}
 	cmdNameValue := strings.ToLower(cmdName.Value())
-	if _, ok = registeredCommands[cmdNameValue]; !ok {
+	if _, ok = Lookup(cmdNameValue); !ok {
 		return "", gerror.ErrInvalidCmdName
 	}
 	return cmdNameValue, nil
 }
+
+// argsFromFrame returns the arguments of a command, the command name excluded.
+// It fails if one of them is not a bulk string.
+func argsFromFrame(f *frame.Array) ([]string, error) {
+	args := make([]string, 0, f.Size())
+	for i := 1; i < f.Size(); i++ {
+		arg, ok := f.Get(i).(*frame.BulkString)
+		if !ok {
+			return nil, gerror.ErrInvalidCmdArgs
+		}
+		args = append(args, arg.Value())
+	}
+	return args, nil
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "command",
+		Arity:      -1,
+		Flags:      []string{},
+		Categories: []string{CategorySlow, CategoryConnection},
+		Summary:    "Returns detailed information about all commands.",
+		Group:      "server",
+		Since:      "2.8.13",
+		New:        func() Command { return new(Commands) },
+	})
+}
+
+// Commands describes the commands the server supports. Without argument, it replies the information of all of them.
+// The COUNT subcommand replies their number, INFO the information of the commands given, or all of them,
+// and DOCS their documentation.
+type Commands struct {
+	subcommand string
+	names      []string
+}
+
+func (c *Commands) Apply(_ *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "count":
+		dest.WriteFrame(frame.NewInteger(int64(len(Specs()))))
+	case "docs":
+		c.replyDocs(dest)
+	default:
+		specs := c.specs()
+		resp := frame.NewArray(len(specs))
+		for _, spec := range specs {
+			if spec == nil {
+				_ = resp.Append(&frame.Null{})
+				continue
+			}
+			_ = resp.Append(commandInfo(spec))
+		}
+		dest.WriteFrame(resp)
+	}
+}
+
+// specs returns the specs of the commands given, nil for the unknown ones, or all of them if none was given.
+func (c *Commands) specs() []*Spec {
+	if len(c.names) == 0 {
+		return Specs()
+	}
+	specs := make([]*Spec, 0, len(c.names))
+	for _, name := range c.names {
+		spec, _ := Lookup(name)
+		specs = append(specs, spec)
+	}
+	return specs
+}
+
+// replyDocs replies a map of the documentation of the commands, the unknown ones being skipped.
+func (c *Commands) replyDocs(dest ReplyWriter) {
+	specs := c.specs()
+	resp := frame.NewMap(len(specs))
+	for _, spec := range specs {
+		if spec == nil {
+			continue
+		}
+		doc := frame.NewMap(3)
+		_ = doc.Append(frame.NewBulkString("summary"), frame.NewBulkString(spec.Summary))
+		_ = doc.Append(frame.NewBulkString("since"), frame.NewBulkString(spec.Since))
+		_ = doc.Append(frame.NewBulkString("group"), frame.NewBulkString(spec.Group))
+		_ = resp.Append(frame.NewBulkString(spec.Name), doc)
+	}
+	dest.WriteFrame(resp)
+}
+
+// commandInfo returns the description of a command in the format of COMMAND INFO: name, arity, flags,
+// first key, last key, step, ACL categories, tips, key specifications and subcommands.
+func commandInfo(spec *Spec) *frame.Array {
+	info := frame.NewArray(10)
+	_ = info.Append(frame.NewBulkString(spec.Name))
+	_ = info.Append(frame.NewInteger(int64(spec.Arity)))
+	flags := frame.NewArray(len(spec.Flags))
+	for _, flag := range spec.Flags {
+		f, _ := frame.NewSimpleString(flag)
+		_ = flags.Append(f)
+	}
+	_ = info.Append(flags)
+	_ = info.Append(frame.NewInteger(int64(spec.FirstKey)))
+	_ = info.Append(frame.NewInteger(int64(spec.LastKey)))
+	_ = info.Append(frame.NewInteger(int64(spec.Step)))
+	categories := frame.NewArray(len(spec.Categories))
+	for _, category := range spec.Categories {
+		f, _ := frame.NewSimpleString("@" + category)
+		_ = categories.Append(f)
+	}
+	_ = info.Append(categories)
+	_ = info.Append(frame.NewArray(0))
+	_ = info.Append(frame.NewArray(0))
+	_ = info.Append(frame.NewArray(0))
+	return info
+}
+
+func (c *Commands) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	c.subcommand = strings.ToLower(args[0])
+	switch c.subcommand {
+	case "count":
+		if len(args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "info", "docs":
+		c.names = args[1:]
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+func (c *Commands) Name() string {
+	return "command"
+}
@@ -0,0 +1,90 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "config",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Gets or sets the configuration parameters.",
+		Group:      "server",
+		Since:      "2.0.0",
+		New:        func() Command { return new(Config) },
+	})
+}
+
+// Config reads and changes the runtime parameters of the server with the GET and SET subcommands.
+type Config struct {
+	subcommand string
+	args       []string
+	session    Session
+}
+
+func (c *Config) Apply(_ *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "get":
+		pairs := make([]string, 0)
+		seen := make(map[string]struct{})
+		for _, pattern := range c.args {
+			params := c.session.ConfigGet(strings.ToLower(pattern))
+			for i := 0; i < len(params); i += 2 {
+				if _, ok := seen[params[i]]; !ok {
+					seen[params[i]] = struct{}{}
+					pairs = append(pairs, params[i], params[i+1])
+				}
+			}
+		}
+		resp := frame.NewMap(len(pairs) / 2)
+		for i := 0; i < len(pairs); i += 2 {
+			_ = resp.Append(frame.NewBulkString(pairs[i]), frame.NewBulkString(pairs[i+1]))
+		}
+		dest.WriteFrame(resp)
+	case "set":
+		for i := 0; i < len(c.args); i += 2 {
+			if err := c.session.ConfigSet(strings.ToLower(c.args[i]), c.args[i+1]); err != nil {
+				dest.WriteError(err)
+				return
+			}
+		}
+		dest.WriteOK()
+	}
+}
+
+func (c *Config) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.subcommand, c.args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "get":
+		if len(c.args) < 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "set":
+		if len(c.args) < 2 || len(c.args)%2 != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+func (c *Config) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Config) Name() string {
+	return "config"
+}
@@ -0,0 +1,189 @@
+package command
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:  "debug",
+		Arity: -2,
+		Flags: []string{FlagAdmin, FlagProtected},
+		GetKeys: func(args []string) []string {
+			switch {
+			case len(args) == 3 && strings.EqualFold(args[1], "object"):
+				return args[2:]
+			case len(args) > 2 && strings.EqualFold(args[1], "digest-value"):
+				return args[2:]
+			}
+			return nil
+		},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Simulates conditions like slow commands or restarts, and inspects the internals of the keys.",
+		Group:      "server",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Debug) },
+	})
+}
+
+// defaultPopulatePrefix is the prefix of the keys created by DEBUG POPULATE unless told otherwise.
+const defaultPopulatePrefix = "key"
+
+// Debug helps testing the server. SLEEP blocks the client for a duration, RELOAD rebuilds the databases as a
+// restart would, SET-ACTIVE-EXPIRE toggles the background expiration of the keys and POPULATE creates keys in the
+// selected database. OBJECT describes the internals of a key, DIGEST and DIGEST-VALUE reply digests of the
+// databases and of values, to compare the data of two servers.
+type Debug struct {
+	subcommand string
+	args       []string
+	sleep      time.Duration
+	enabled    bool
+	count      int
+	prefix     string
+	size       int
+	session    Session
+}
+
+func (c *Debug) Apply(cache *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "sleep":
+		time.Sleep(c.sleep)
+		dest.WriteOK()
+	case "reload":
+		for i := 0; i < c.session.DatabaseCount(); i++ {
+			if database, err := c.session.Database(i); err == nil {
+				database.Reload()
+			}
+		}
+		dest.WriteOK()
+	case "set-active-expire":
+		c.session.SetActiveExpire(c.enabled)
+		dest.WriteOK()
+	case "populate":
+		if _, err := cache.Populate(c.count, c.prefix, c.size); err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteOK()
+	case "object":
+		info, ok := cache.Object(c.args[0])
+		if !ok {
+			dest.WriteError(gerror.ErrNoSuchKey)
+			return
+		}
+		resp, _ := frame.NewSimpleString(describeObject(info))
+		dest.WriteFrame(resp)
+	case "digest":
+		dest.WriteFrame(frame.NewBulkString(hex.EncodeToString(c.digest())))
+	case "digest-value":
+		resp := frame.NewArray(len(c.args))
+		for _, key := range c.args {
+			digest := cache.DigestValue(key)
+			_ = resp.Append(frame.NewBulkString(hex.EncodeToString(digest[:])))
+		}
+		dest.WriteFrame(resp)
+	}
+}
+
+// describeObject formats the internals of a key the way DEBUG OBJECT replies them.
+func describeObject(info db.ObjectInfo) string {
+	desc := fmt.Sprintf("refcount:1 encoding:%s serializedlength:%d lru:%d", info.Encoding,
+		info.SerializedLength, info.Access)
+	if info.LFU {
+		return desc + fmt.Sprintf(" lfu_freq:%d", info.Freq)
+	}
+	return desc + fmt.Sprintf(" lru_seconds_idle:%d", int64(info.Idle/time.Second))
+}
+
+// digest combines the digests of the databases with their index, so that the same data in another database gives
+// another digest. It is all zeroes when the databases are empty.
+func (c *Debug) digest() []byte {
+	digest := make([]byte, db.DigestSize)
+	for i := 0; i < c.session.DatabaseCount(); i++ {
+		database, err := c.session.Database(i)
+		if err != nil {
+			continue
+		}
+		dbDigest := database.Digest()
+		if dbDigest == ([db.DigestSize]byte{}) {
+			continue
+		}
+		mixed := sha1.Sum(append([]byte(strconv.Itoa(i)+":"), dbDigest[:]...))
+		for j := range digest {
+			digest[j] ^= mixed[j]
+		}
+	}
+	return digest
+}
+
+func (c *Debug) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.subcommand, args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "sleep":
+		if len(args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		seconds, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || seconds < 0 {
+			return gerror.ErrNotFloat
+		}
+		c.sleep = time.Duration(seconds * float64(time.Second))
+	case "reload", "digest":
+		if len(args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "set-active-expire":
+		if len(args) != 1 || (args[0] != "0" && args[0] != "1") {
+			return gerror.ErrSyntax
+		}
+		c.enabled = args[0] == "1"
+	case "populate":
+		if len(args) < 1 || len(args) > 3 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		count, err := strconv.Atoi(args[0])
+		if err != nil || count < 0 {
+			return gerror.ErrNotInteger
+		}
+		c.count, c.prefix = count, defaultPopulatePrefix
+		if len(args) > 1 {
+			c.prefix = args[1]
+		}
+		if len(args) > 2 {
+			size, err := strconv.Atoi(args[2])
+			if err != nil || size < 0 {
+				return gerror.ErrNotInteger
+			}
+			c.size = size
+		}
+	case "object":
+		if len(args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "digest-value":
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	c.args = args
+	return nil
+}
+
+func (c *Debug) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Debug) Name() string {
+	return "debug"
+}
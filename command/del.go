@@ -1,31 +1,34 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
-	"log/slog"
 )
 
+func init() {
+	Register(Spec{
+		Name:       "del",
+		Arity:      -2,
+		Flags:      []string{FlagWrite},
+		FirstKey:   1,
+		LastKey:    -1,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategorySlow},
+		Summary:    "Deletes one or more keys.",
+		Group:      "generic",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Del) },
+	})
+}
+
 type Del struct {
-	keys   []string
-	logger *slog.Logger
+	keys []string
 }
 
-func (c *Del) Apply(cache *db.Cache, dest *bufio.Writer) {
-	defer func(dest *bufio.Writer) {
-		err := dest.Flush()
-		if err != nil {
-			c.logger.Error("unable to write to destination", "error", err)
-		}
-	}(dest)
+func (c *Del) Apply(cache *db.Cache, dest ReplyWriter) {
 	numKeys := cache.Delete(c.keys...)
-	resp := frame.NewInteger(int64(numKeys))
-	_, err := resp.WriteTo(dest)
-	if err != nil {
-		c.logger.Error("failed to write response", "error", err)
-	}
+	dest.WriteFrame(frame.NewInteger(int64(numKeys)))
 }
 
 func (c *Del) FromFrame(f *frame.Array) error {
@@ -38,6 +41,10 @@ func (c *Del) FromFrame(f *frame.Array) error {
 	return nil
 }
 
+func (c *Del) WriteKeys() []string {
+	return c.keys
+}
+
 func (c *Del) Name() string {
 	return "del"
 }
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "discard",
+		Arity:      1,
+		Flags:      []string{FlagFast},
+		Categories: []string{CategoryFast, CategoryTransaction},
+		Summary:    "Discards a transaction.",
+		Group:      "transactions",
+		Since:      "2.0.0",
+		New:        func() Command { return new(Discard) },
+	})
+}
+
+// Discard drops all the commands queued in a transaction and forgets the watched keys.
+type Discard struct {
+	session Session
+}
+
+func (c *Discard) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.Discard(); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *Discard) FromFrame(f *frame.Array) error {
+	if f.Size() != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *Discard) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Discard) Name() string {
+	return "discard"
+}
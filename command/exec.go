@@ -0,0 +1,46 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "exec",
+		Arity:      1,
+		Flags:      []string{},
+		Categories: []string{CategorySlow, CategoryTransaction},
+		Summary:    "Executes all commands in a transaction.",
+		Group:      "transactions",
+		Since:      "1.2.0",
+		New:        func() Command { return new(Exec) },
+	})
+}
+
+// Exec applies all the commands queued in a transaction.
+type Exec struct {
+	session Session
+}
+
+func (c *Exec) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.Exec(dest); err != nil {
+		dest.WriteError(err)
+	}
+}
+
+func (c *Exec) FromFrame(f *frame.Array) error {
+	if f.Size() != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *Exec) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Exec) Name() string {
+	return "exec"
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"math"
+	"strconv"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "expire",
+		Arity:      3,
+		Flags:      []string{FlagWrite, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategoryFast},
+		Summary:    "Sets the expiration time of a key in seconds.",
+		Group:      "generic",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Expire) },
+	})
+}
+
+// Expire sets a time to live, in seconds, on a key. It replies 1 if the key exists and 0 otherwise.
+type Expire struct {
+	key string
+	ttl time.Duration
+}
+
+func (c *Expire) Apply(cache *db.Cache, dest ReplyWriter) {
+	updated := int64(0)
+	if cache.Expire(c.key, c.ttl) {
+		updated = 1
+	}
+	dest.WriteFrame(frame.NewInteger(updated))
+}
+
+func (c *Expire) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	ttl, err := parseTTL(args[1], time.Second)
+	if err != nil {
+		return err
+	}
+	c.key, c.ttl = args[0], ttl
+	return nil
+}
+
+// parseTTL reads a time to live given as an integer amount of unit. It fails with gerror.ErrInvalidExpire if the
+// duration does not fit in a time.Duration.
+func parseTTL(value string, unit time.Duration) (time.Duration, error) {
+	amount, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, gerror.ErrNotInteger
+	}
+	if amount > math.MaxInt64/int64(unit) || amount < math.MinInt64/int64(unit) {
+		return 0, gerror.ErrInvalidExpire
+	}
+	return time.Duration(amount) * unit, nil
+}
+
+func (c *Expire) WriteKeys() []string {
+	return []string{c.key}
+}
+
+func (c *Expire) Name() string {
+	return "expire"
+}
@@ -0,0 +1,54 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "flushall",
+		Arity:      -1,
+		Flags:      []string{FlagWrite},
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategorySlow, CategoryDangerous},
+		Summary:    "Removes all keys from all databases.",
+		Group:      "server",
+		Since:      "1.0.0",
+		New:        func() Command { return new(FlushAll) },
+	})
+}
+
+// FlushAll removes all the keys of all the databases.
+type FlushAll struct {
+	async   bool
+	session Session
+}
+
+func (c *FlushAll) Apply(_ *db.Cache, dest ReplyWriter) {
+	for i := 0; i < c.session.DatabaseCount(); i++ {
+		cache, err := c.session.Database(i)
+		if err != nil {
+			dest.WriteError(err)
+			return
+		}
+		cache.Flush()
+	}
+	dest.WriteOK()
+}
+
+func (c *FlushAll) FromFrame(f *frame.Array) error {
+	async, err := parseFlushMode(f)
+	if err != nil {
+		return err
+	}
+	c.async = async
+	return nil
+}
+
+func (c *FlushAll) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *FlushAll) Name() string {
+	return "flushall"
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "flushdb",
+		Arity:      -1,
+		Flags:      []string{FlagWrite},
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategorySlow, CategoryDangerous},
+		Summary:    "Removes all keys from the current database.",
+		Group:      "server",
+		Since:      "1.0.0",
+		New:        func() Command { return new(FlushDB) },
+	})
+}
+
+// FlushDB removes all the keys of the selected database.
+type FlushDB struct {
+	async bool
+}
+
+func (c *FlushDB) Apply(cache *db.Cache, dest ReplyWriter) {
+	cache.Flush()
+	dest.WriteOK()
+}
+
+func (c *FlushDB) FromFrame(f *frame.Array) error {
+	async, err := parseFlushMode(f)
+	if err != nil {
+		return err
+	}
+	c.async = async
+	return nil
+}
+
+func (c *FlushDB) Name() string {
+	return "flushdb"
+}
+
+// parseFlushMode reads the optional ASYNC or SYNC modifier of the flush commands.
+// Flushing detaches the storage in constant time, so both modes behave the same and are only accepted for
+// compatibility with Redis clients.
+func parseFlushMode(f *frame.Array) (async bool, err error) {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case len(args) == 0:
+		return false, nil
+	case len(args) > 1:
+		return false, gerror.ErrInvalidCmdArgs
+	}
+	switch strings.ToLower(args[0]) {
+	case "async":
+		return true, nil
+	case "sync":
+		return false, nil
+	default:
+		return false, gerror.ErrSyntax
+	}
+}
@@ -1,39 +1,38 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
-	"log/slog"
 )
 
+func init() {
+	Register(Spec{
+		Name:       "get",
+		Arity:      2,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryRead, CategoryString, CategoryFast},
+		Summary:    "Returns the string value of a key.",
+		Group:      "string",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Get) },
+	})
+}
+
 type Get struct {
-	key    string
-	logger *slog.Logger
+	key string
 }
 
-func (c *Get) Apply(cache *db.Cache, dest *bufio.Writer) {
-	defer func(dest *bufio.Writer) {
-		err := dest.Flush()
-		if err != nil {
-			c.logger.Error("unable to write to destination", "error", err)
-		}
-	}(dest)
+func (c *Get) Apply(cache *db.Cache, dest ReplyWriter) {
 	value, ok := cache.Get(c.key)
 	if !ok {
-		resp := frame.Null{}
-		_, err := resp.WriteTo(dest)
-		if err != nil {
-			c.logger.Error("failed to write response", "error", err)
-		}
+		dest.WriteNull()
 		return
 	}
-	resp := frame.NewBulkString(value)
-	_, err := resp.WriteTo(dest)
-	if err != nil {
-		c.logger.Error("failed to write response", "error", err)
-	}
+	dest.WriteFrame(frame.NewBulkString(value))
 }
 
 func (c *Get) FromFrame(f *frame.Array) error {
@@ -45,6 +44,10 @@ func (c *Get) FromFrame(f *frame.Array) error {
 	return nil
 }
 
+func (c *Get) ReadKeys() []string {
+	return []string{c.key}
+}
+
 func (c *Get) Name() string {
 	return "get"
 }
@@ -0,0 +1,101 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "hello",
+		Arity:      -1,
+		Flags:      []string{FlagNoAuth, FlagFast},
+		Categories: []string{CategoryFast, CategoryConnection},
+		Summary:    "Handshakes with the server.",
+		Group:      "connection",
+		Since:      "6.0.0",
+		New:        func() Command { return new(Hello) },
+	})
+}
+
+// Hello negotiates the protocol version spoken by the client and replies with information about the server.
+// Without argument, the current protocol is kept. With the AUTH option, it authenticates the client first,
+// which is the only way to run it before being authenticated. The SETNAME option names the client.
+type Hello struct {
+	protocol   int
+	username   string
+	password   string
+	auth       bool
+	clientName string
+	setName    bool
+	session    Session
+}
+
+func (c *Hello) Apply(_ *db.Cache, dest ReplyWriter) {
+	if c.auth {
+		if err := c.session.Auth(c.username, c.password); err != nil {
+			dest.WriteError(err)
+			return
+		}
+	} else if c.session.User() == "" {
+		dest.WriteError(gerror.ErrHelloNoAuth)
+		return
+	}
+	if c.protocol != 0 {
+		c.session.SetProtocol(c.protocol)
+	}
+	if c.setName {
+		c.session.SetClientName(c.clientName)
+	}
+	resp := frame.NewMap(4)
+	_ = resp.Append(frame.NewBulkString("server"), frame.NewBulkString("gcache"))
+	_ = resp.Append(frame.NewBulkString("proto"), frame.NewInteger(int64(c.session.Protocol())))
+	_ = resp.Append(frame.NewBulkString("mode"), frame.NewBulkString("standalone"))
+	_ = resp.Append(frame.NewBulkString("role"), frame.NewBulkString("master"))
+	dest.WriteFrame(resp)
+}
+
+func (c *Hello) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	for i := 1; i < len(args); i++ {
+		switch {
+		case strings.EqualFold(args[i], "auth") && i+2 < len(args):
+			c.auth, c.username, c.password = true, args[i+1], args[i+2]
+			i += 2
+		case strings.EqualFold(args[i], "setname") && i+1 < len(args):
+			if !validClientName(args[i+1]) {
+				return gerror.ErrInvalidClientName
+			}
+			c.setName, c.clientName = true, args[i+1]
+			i++
+		default:
+			return gerror.ErrSyntax
+		}
+	}
+	protocol, err := strconv.Atoi(args[0])
+	if err != nil {
+		return gerror.ErrInvalidProtocol
+	}
+	if protocol != frame.RESP2 && protocol != frame.RESP3 {
+		return gerror.ErrNoProto
+	}
+	c.protocol = protocol
+	return nil
+}
+
+func (c *Hello) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Hello) Name() string {
+	return "hello"
+}
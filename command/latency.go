@@ -0,0 +1,107 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "latency",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Reports the latency of the server events and the distribution of the run time of the commands.",
+		Group:      "server",
+		Since:      "2.8.13",
+		New:        func() Command { return new(Latency) },
+	})
+}
+
+// Latency inspects the latency monitor with the LATEST, HISTORY, RESET and HISTOGRAM subcommands.
+type Latency struct {
+	subcommand string
+	args       []string
+	session    Session
+}
+
+func (c *Latency) Apply(_ *db.Cache, dest ReplyWriter) {
+	latency := c.session.Latency()
+	switch c.subcommand {
+	case "latest":
+		events := latency.Latest()
+		resp := frame.NewArray(len(events))
+		for _, e := range events {
+			event := frame.NewArray(4)
+			_ = event.Append(frame.NewBulkString(e.Event))
+			_ = event.Append(frame.NewInteger(e.Latest.Time.Unix()))
+			_ = event.Append(frame.NewInteger(e.Latest.Latency.Milliseconds()))
+			_ = event.Append(frame.NewInteger(e.Max.Milliseconds()))
+			_ = resp.Append(event)
+		}
+		dest.WriteFrame(resp)
+	case "history":
+		samples := latency.History(c.args[0])
+		resp := frame.NewArray(len(samples))
+		for _, s := range samples {
+			sample := frame.NewArray(2)
+			_ = sample.Append(frame.NewInteger(s.Time.Unix()))
+			_ = sample.Append(frame.NewInteger(s.Latency.Milliseconds()))
+			_ = resp.Append(sample)
+		}
+		dest.WriteFrame(resp)
+	case "reset":
+		dest.WriteFrame(frame.NewInteger(int64(latency.Reset(c.args...))))
+	case "histogram":
+		histograms := latency.Histograms(c.args...)
+		resp := frame.NewMap(len(histograms))
+		for _, h := range histograms {
+			buckets := h.Cumulative()
+			distribution := frame.NewMap(len(buckets))
+			for _, b := range buckets {
+				_ = distribution.Append(frame.NewInteger(b.UpperBound.Microseconds()), frame.NewInteger(b.Count))
+			}
+			details := frame.NewMap(2)
+			_ = details.Append(frame.NewBulkString("calls"), frame.NewInteger(h.Calls))
+			_ = details.Append(frame.NewBulkString("histogram_usec"), distribution)
+			_ = resp.Append(frame.NewBulkString(h.Command), details)
+		}
+		dest.WriteFrame(resp)
+	}
+}
+
+func (c *Latency) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.subcommand, c.args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "latest":
+		if len(c.args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "history":
+		if len(c.args) != 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "histogram":
+		for i, name := range c.args {
+			c.args[i] = strings.ToLower(name)
+		}
+	case "reset":
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+func (c *Latency) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Latency) Name() string {
+	return "latency"
+}
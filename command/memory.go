@@ -0,0 +1,290 @@
+package command
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:     "memory",
+		Arity:    -2,
+		Flags:    []string{FlagReadOnly},
+		FirstKey: 2,
+		LastKey:  2,
+		Step:     1,
+		GetKeys: func(args []string) []string {
+			if len(args) > 2 && strings.EqualFold(args[1], "usage") {
+				return args[2:3]
+			}
+			return nil
+		},
+		Categories: []string{CategoryRead, CategorySlow},
+		Summary:    "Reports the memory used by the keys, the server and its clients.",
+		Group:      "server",
+		Since:      "4.0.0",
+		New:        func() Command { return new(Memory) },
+	})
+}
+
+// defaultScanCount is the number of keys BIGKEYS and HOTKEYS report unless told otherwise.
+const defaultScanCount = 10
+
+// Memory reports the memory used by the keys with the USAGE subcommand, by the server with the STATS and DOCTOR
+// ones, and finds the largest and most accessed keys of the selected database with BIGKEYS and HOTKEYS.
+type Memory struct {
+	subcommand string
+	key        string
+	count      int
+	session    Session
+}
+
+func (c *Memory) Apply(cache *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "usage":
+		size, ok := cache.MemoryUsage(c.key)
+		if !ok {
+			dest.WriteNull()
+			return
+		}
+		dest.WriteFrame(frame.NewInteger(size))
+	case "stats":
+		dest.WriteFrame(c.collect().frame())
+	case "doctor":
+		dest.WriteFrame(frame.NewBulkString(c.collect().diagnose()))
+	case "bigkeys":
+		biggest := cache.BigKeys(c.count)
+		types := make([]string, 0, len(biggest))
+		for typ := range biggest {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		resp := frame.NewMap(len(types))
+		for _, typ := range types {
+			_ = resp.Append(frame.NewBulkString(typ), keyStatsFrame(biggest[typ], false))
+		}
+		dest.WriteFrame(resp)
+	case "hotkeys":
+		hottest, err := cache.HotKeys(c.count)
+		if err != nil {
+			dest.WriteError(err)
+			return
+		}
+		dest.WriteFrame(keyStatsFrame(hottest, true))
+	}
+}
+
+// keyStatsFrame replies keys as pairs of a key and its size, or its access frequency.
+func keyStatsFrame(keys []db.KeyStat, frequency bool) *frame.Array {
+	resp := frame.NewArray(len(keys))
+	for _, key := range keys {
+		value := key.Size
+		if frequency {
+			value = int64(key.Frequency)
+		}
+		pair := frame.NewArray(2)
+		_ = pair.Append(frame.NewBulkString(key.Key))
+		_ = pair.Append(frame.NewInteger(value))
+		_ = resp.Append(pair)
+	}
+	return resp
+}
+
+// memoryReport is the memory used by the server, as reported by MEMORY STATS and analyzed by MEMORY DOCTOR.
+type memoryReport struct {
+	allocated     int64
+	peakAllocated int64
+	clients       map[string]int64
+	// databases holds the stats of the databases by index, total being their sum.
+	databases []db.MemoryStats
+	total     db.MemoryStats
+}
+
+// collect gathers the memory stats of the runtime, of the databases and of the clients.
+func (c *Memory) collect() memoryReport {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	report := memoryReport{
+		allocated: int64(mem.HeapAlloc),
+		// the runtime does not track the peak of the heap, the memory obtained from the OS is its high-water mark
+		peakAllocated: int64(mem.HeapSys),
+		clients:       c.session.ClientsMemory(),
+	}
+	for i := 0; i < c.session.DatabaseCount(); i++ {
+		cache, err := c.session.Database(i)
+		if err != nil {
+			continue
+		}
+		stats := cache.MemoryStats()
+		report.databases = append(report.databases, stats)
+		report.total.Keys += stats.Keys
+		report.total.Volatile += stats.Volatile
+		report.total.Dataset += stats.Dataset
+		report.total.Storage += stats.Storage
+		report.total.Expires += stats.Expires
+		report.total.Eviction += stats.Eviction
+	}
+	return report
+}
+
+// overhead returns the memory used besides the dataset, client buffers included.
+func (r memoryReport) overhead() int64 {
+	return r.total.Overhead() + r.clients["normal"] + r.clients["pubsub"]
+}
+
+func (r memoryReport) frame() *frame.Map {
+	nonEmpty := 0
+	for _, stats := range r.databases {
+		if stats.Keys > 0 {
+			nonEmpty++
+		}
+	}
+	resp := frame.NewMap(14 + nonEmpty)
+	field := func(name string, value frame.Framer) {
+		_ = resp.Append(frame.NewBulkString(name), value)
+	}
+	field("peak.allocated", frame.NewInteger(r.peakAllocated))
+	field("total.allocated", frame.NewInteger(r.allocated))
+	field("clients.normal", frame.NewInteger(r.clients["normal"]))
+	field("clients.pubsub", frame.NewInteger(r.clients["pubsub"]))
+	field("overhead.hashtable.main", frame.NewInteger(r.total.Storage))
+	field("overhead.hashtable.expires", frame.NewInteger(r.total.Expires))
+	field("overhead.eviction-policy", frame.NewInteger(r.total.Eviction))
+	field("overhead.total", frame.NewInteger(r.overhead()))
+	for i, stats := range r.databases {
+		if stats.Keys == 0 {
+			continue
+		}
+		dbStats := frame.NewMap(3)
+		_ = dbStats.Append(frame.NewBulkString("overhead.hashtable.main"), frame.NewInteger(stats.Storage))
+		_ = dbStats.Append(frame.NewBulkString("overhead.hashtable.expires"), frame.NewInteger(stats.Expires))
+		_ = dbStats.Append(frame.NewBulkString("overhead.eviction-policy"), frame.NewInteger(stats.Eviction))
+		field(fmt.Sprintf("db.%d", i), dbStats)
+	}
+	field("keys.count", frame.NewInteger(r.total.Keys))
+	field("keys.volatile", frame.NewInteger(r.total.Volatile))
+	bytesPerKey := int64(0)
+	if r.total.Keys > 0 {
+		bytesPerKey = (r.total.Dataset + r.total.Overhead()) / r.total.Keys
+	}
+	field("keys.bytes-per-key", frame.NewInteger(bytesPerKey))
+	field("dataset.bytes", frame.NewInteger(r.total.Dataset))
+	field("dataset.percentage", frame.NewBulkString(strconv.FormatFloat(r.datasetPercentage(), 'f', 2, 64)))
+	field("overhead.percentage", frame.NewBulkString(strconv.FormatFloat(100-r.datasetPercentage(), 'f', 2, 64)))
+	return resp
+}
+
+// datasetPercentage returns the share of the dataset in the memory estimated for the server.
+func (r memoryReport) datasetPercentage() float64 {
+	total := r.total.Dataset + r.overhead()
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(r.total.Dataset) / float64(total)
+}
+
+// Thresholds over which MEMORY DOCTOR reports an issue.
+const (
+	doctorMaxBytesPerKey  = 512
+	doctorMaxClientBuffer = 32 * 1024 * 1024
+)
+
+// diagnose describes the issues found in the memory report, in plain English.
+func (r memoryReport) diagnose() string {
+	issues := make([]string, 0)
+	if r.total.Keys > 0 && r.total.Overhead() > r.total.Dataset {
+		issues = append(issues, fmt.Sprintf("The keys use more memory to be indexed and tracked by the eviction "+
+			"policy (%d bytes) than to be stored (%d bytes): the values are small, grouping them in fewer keys "+
+			"would save memory.", r.total.Overhead(), r.total.Dataset))
+	}
+	if r.total.Keys > 0 && (r.total.Dataset+r.total.Overhead())/r.total.Keys > doctorMaxBytesPerKey {
+		issues = append(issues, "The keys are large on average. MEMORY BIGKEYS reports the largest ones, which "+
+			"may be worth splitting.")
+	}
+	if r.clients["pubsub"] > doctorMaxClientBuffer {
+		issues = append(issues, fmt.Sprintf("Subscribers hold %d bytes of pending messages: some of them do not "+
+			"read fast enough. Consider lowering client-output-buffer-limit for the pubsub class.",
+			r.clients["pubsub"]))
+	}
+	if r.clients["normal"] > doctorMaxClientBuffer {
+		issues = append(issues, fmt.Sprintf("The buffers of the clients use %d bytes: many clients are connected, "+
+			"or some of them send very large commands. CLIENT LIST shows their buffers.", r.clients["normal"]))
+	}
+	switch {
+	case len(issues) == 0 && r.total.Keys == 0:
+		return "This instance holds no key, there is nothing to diagnose yet.\n"
+	case len(issues) == 0:
+		return "No memory issue detected in this instance.\n"
+	}
+	var sb strings.Builder
+	sb.WriteString("The following memory issues were detected:\n\n")
+	for _, issue := range issues {
+		sb.WriteString(" * ")
+		sb.WriteString(issue)
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+func (c *Memory) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.subcommand, args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "usage":
+		// SAMPLES is accepted for compatibility, the size of the values is always computed exactly
+		if len(args) != 1 && (len(args) != 3 || !strings.EqualFold(args[1], "samples")) {
+			return gerror.ErrInvalidCmdArgs
+		}
+		if len(args) == 3 {
+			if _, err := strconv.Atoi(args[2]); err != nil {
+				return gerror.ErrNotInteger
+			}
+		}
+		c.key = args[0]
+	case "stats", "doctor":
+		if len(args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "bigkeys", "hotkeys":
+		c.count = defaultScanCount
+		if len(args) == 0 {
+			return nil
+		}
+		if len(args) != 2 || !strings.EqualFold(args[0], "count") {
+			return gerror.ErrSyntax
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return gerror.ErrNotInteger
+		}
+		c.count = n
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+// ReadKeys returns the key of MEMORY USAGE. The other subcommands do not read any key.
+func (c *Memory) ReadKeys() []string {
+	if c.subcommand != "usage" {
+		return nil
+	}
+	return []string{c.key}
+}
+
+func (c *Memory) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Memory) Name() string {
+	return "memory"
+}
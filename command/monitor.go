@@ -0,0 +1,45 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "monitor",
+		Arity:      1,
+		Flags:      []string{FlagAdmin, FlagNoMulti},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Listens for all the requests received by the server in real time.",
+		Group:      "server",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Monitor) },
+	})
+}
+
+// Monitor turns the connection into a feed of the commands processed on behalf of the other clients.
+type Monitor struct {
+	session Session
+}
+
+func (c *Monitor) Apply(_ *db.Cache, dest ReplyWriter) {
+	c.session.Monitor()
+	dest.WriteOK()
+}
+
+func (c *Monitor) FromFrame(f *frame.Array) error {
+	if f.Size() != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *Monitor) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Monitor) Name() string {
+	return "monitor"
+}
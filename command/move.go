@@ -0,0 +1,73 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "move",
+		Arity:      3,
+		Flags:      []string{FlagWrite, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategoryFast},
+		Summary:    "Moves a key to another database.",
+		Group:      "generic",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Move) },
+	})
+}
+
+// Move moves a key from the selected database to another one.
+// It replies 1 if the key was moved and 0 if it is missing from the source or already present in the destination.
+type Move struct {
+	key     string
+	dbIndex int
+	session Session
+}
+
+func (c *Move) Apply(cache *db.Cache, dest ReplyWriter) {
+	dst, err := c.session.Database(c.dbIndex)
+	if err != nil {
+		dest.WriteError(err)
+		return
+	}
+	if dst == cache {
+		dest.WriteError(gerror.ErrSameObject)
+		return
+	}
+	moved := int64(0)
+	if cache.Move(c.key, dst) {
+		moved = 1
+	}
+	dest.WriteFrame(frame.NewInteger(moved))
+}
+
+func (c *Move) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.key = args[0]
+	c.dbIndex, err = parseDBIndex(args[1])
+	return err
+}
+
+func (c *Move) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Move) WriteKeys() []string {
+	return []string{c.key}
+}
+
+func (c *Move) Name() string {
+	return "move"
+}
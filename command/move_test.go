@@ -0,0 +1,139 @@
+package command
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"testing"
+)
+
+// fakeSession is a minimal command.Session backed by a slice of databases.
+// The methods it does not override panic through the nil embedded interface.
+type fakeSession struct {
+	Session
+	selected int
+	dbs      []*db.Cache
+}
+
+func newFakeSession(t *testing.T, count int) *fakeSession {
+	s := &fakeSession{}
+	for i := 0; i < count; i++ {
+		cache, err := db.NewCache(10, "LRU")
+		if err != nil {
+			t.Fatalf("unable to create cache: %v", err)
+		}
+		s.dbs = append(s.dbs, cache)
+	}
+	return s
+}
+
+func (s *fakeSession) Select(index int) error {
+	if index >= len(s.dbs) {
+		return gerror.ErrDBIndexOutRange
+	}
+	s.selected = index
+	return nil
+}
+
+func (s *fakeSession) Database(index int) (*db.Cache, error) {
+	if index >= len(s.dbs) {
+		return nil, gerror.ErrDBIndexOutRange
+	}
+	return s.dbs[index], nil
+}
+
+func (s *fakeSession) DatabaseCount() int {
+	return len(s.dbs)
+}
+
+func (s *fakeSession) SwapDB(i, j int) error {
+	s.dbs[i], s.dbs[j] = s.dbs[j], s.dbs[i]
+	return nil
+}
+
+// cmdFrame builds the Array frame a client would send for the given arguments.
+func cmdFrame(args ...string) *frame.Array {
+	f := frame.NewArray(len(args))
+	for _, arg := range args {
+		_ = f.Append(frame.NewBulkString(arg))
+	}
+	return f
+}
+
+func TestMove_Apply(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       string
+		dbIndex   int
+		inDest    bool
+		want      string
+		wantMoved bool
+	}{
+		{name: "Moved", key: "hello", dbIndex: 1, want: ":1\r\n", wantMoved: true},
+		{name: "MissingKey", key: "missing", dbIndex: 1, want: ":0\r\n"},
+		{name: "ExistsInDestination", key: "hello", dbIndex: 1, inDest: true, want: ":0\r\n"},
+		{name: "SameDatabase", key: "hello", dbIndex: 0, want: "-" + gerror.ErrSameObject.Error() + "\r\n"},
+		{name: "OutOfRange", key: "hello", dbIndex: 5, want: "-" + gerror.ErrDBIndexOutRange.Error() + "\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newFakeSession(t, 2)
+			session.dbs[0].Set("hello", "world")
+			if tt.inDest {
+				session.dbs[1].Set("hello", "other")
+			}
+
+			replies := NewRecorder(frame.RESP2)
+			cmd := Move{key: tt.key, dbIndex: tt.dbIndex}
+			cmd.BindSession(session)
+			cmd.Apply(session.dbs[0], replies)
+
+			assert.Equal(t, tt.want, replies.String())
+			assert.Equal(t, !tt.wantMoved, session.dbs[0].Exists("hello"))
+		})
+	}
+}
+
+func TestSelect_FromFrame(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     *frame.Array
+		want      int
+		wantError error
+	}{
+		{name: "Valid", frame: cmdFrame("SELECT", "3"), want: 3},
+		{name: "NotANumber", frame: cmdFrame("SELECT", "three"), wantError: gerror.ErrInvalidDBIndex},
+		{name: "Negative", frame: cmdFrame("SELECT", "-1"), wantError: gerror.ErrDBIndexOutRange},
+		{name: "MissingIndex", frame: cmdFrame("SELECT"), wantError: gerror.ErrInvalidCmdArgs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Select{}
+			err := cmd.FromFrame(tt.frame)
+			assert.Equal(t, tt.wantError, err)
+			assert.Equal(t, tt.want, cmd.index)
+		})
+	}
+}
+
+func TestFlushAll_Apply(t *testing.T) {
+	session := newFakeSession(t, 3)
+	for _, cache := range session.dbs {
+		cache.Set("hello", "world")
+	}
+
+	replies := NewRecorder(frame.RESP2)
+	cmd := FlushAll{}
+	assert.NoError(t, cmd.FromFrame(cmdFrame("FLUSHALL", "ASYNC")))
+	cmd.BindSession(session)
+	cmd.Apply(nil, replies)
+
+	assert.Equal(t, "+OK\r\n", replies.String())
+	for _, cache := range session.dbs {
+		assert.Equal(t, int64(0), cache.Size())
+		assert.False(t, cache.Exists("hello"))
+	}
+}
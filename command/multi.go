@@ -0,0 +1,48 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "multi",
+		Arity:      1,
+		Flags:      []string{FlagFast},
+		Categories: []string{CategoryFast, CategoryTransaction},
+		Summary:    "Starts a transaction.",
+		Group:      "transactions",
+		Since:      "1.2.0",
+		New:        func() Command { return new(Multi) },
+	})
+}
+
+// Multi marks the start of a transaction block.
+type Multi struct {
+	session Session
+}
+
+func (c *Multi) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.Multi(); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *Multi) FromFrame(f *frame.Array) error {
+	if f.Size() != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *Multi) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Multi) Name() string {
+	return "multi"
+}
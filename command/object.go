@@ -0,0 +1,93 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "object",
+		Arity:      -2,
+		Flags:      []string{FlagReadOnly},
+		FirstKey:   2,
+		LastKey:    2,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryRead, CategorySlow},
+		Summary:    "Returns information about the internals of a key.",
+		Group:      "generic",
+		Since:      "2.2.3",
+		New:        func() Command { return new(Object) },
+	})
+}
+
+// Object inspects the internals of a key. It supports the ENCODING, REFCOUNT, FREQ and IDLETIME subcommands,
+// which reply a null value if the key does not exist.
+type Object struct {
+	subcommand string
+	key        string
+}
+
+func (c *Object) Apply(cache *db.Cache, dest ReplyWriter) {
+	var value int64
+	var ok bool
+	var err error
+	switch c.subcommand {
+	case "encoding":
+		info, ok := cache.Object(c.key)
+		if !ok {
+			dest.WriteNull()
+			return
+		}
+		dest.WriteFrame(frame.NewBulkString(info.Encoding))
+		return
+	case "refcount":
+		// values are never shared between keys
+		_, ok = cache.Object(c.key)
+		value = 1
+	case "freq":
+		var freq int
+		freq, ok, err = cache.Frequency(c.key)
+		value = int64(freq)
+	case "idletime":
+		var idle time.Duration
+		idle, ok, err = cache.IdleTime(c.key)
+		value = int64(idle / time.Second)
+	}
+	switch {
+	case !ok:
+		dest.WriteNull()
+	case err != nil:
+		dest.WriteError(err)
+	default:
+		dest.WriteFrame(frame.NewInteger(value))
+	}
+}
+
+func (c *Object) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.subcommand = strings.ToLower(args[0])
+	switch c.subcommand {
+	case "encoding", "refcount", "freq", "idletime":
+		if len(args) != 2 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		c.key = args[1]
+		return nil
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+}
+
+func (c *Object) Name() string {
+	return "object"
+}
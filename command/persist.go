@@ -0,0 +1,57 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "persist",
+		Arity:      2,
+		Flags:      []string{FlagWrite, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategoryFast},
+		Summary:    "Removes the expiration time of a key.",
+		Group:      "generic",
+		Since:      "2.2.0",
+		New:        func() Command { return new(Persist) },
+	})
+}
+
+// Persist removes the time to live of a key.
+// It replies 1 if it was removed and 0 if the key does not exist or has no time to live.
+type Persist struct {
+	key string
+}
+
+func (c *Persist) Apply(cache *db.Cache, dest ReplyWriter) {
+	removed := int64(0)
+	if cache.Persist(c.key) {
+		removed = 1
+	}
+	dest.WriteFrame(frame.NewInteger(removed))
+}
+
+func (c *Persist) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.key = args[0]
+	return nil
+}
+
+func (c *Persist) WriteKeys() []string {
+	return []string{c.key}
+}
+
+func (c *Persist) Name() string {
+	return "persist"
+}
@@ -1,40 +1,35 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
-	"log/slog"
 )
 
+func init() {
+	Register(Spec{
+		Name:       "ping",
+		Arity:      -1,
+		Flags:      []string{FlagFast},
+		Categories: []string{CategoryFast, CategoryConnection},
+		Summary:    "Returns the server's liveliness response.",
+		Group:      "connection",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Ping) },
+	})
+}
+
 type Ping struct {
 	message string
-	logger  *slog.Logger
 }
 
-func (c *Ping) Apply(_ *db.Cache, dest *bufio.Writer) {
-	defer func(dest *bufio.Writer) {
-		err := dest.Flush()
-		if err != nil {
-			c.logger.Error("unable to write to destination", "error", err)
-		}
-	}(dest)
+func (c *Ping) Apply(_ *db.Cache, dest ReplyWriter) {
 	if c.message == "PONG" {
-		if resp, err := frame.NewSimpleString(c.message); err == nil {
-			_, err = resp.WriteTo(dest)
-			if err != nil {
-				// handle error
-				return
-			}
-		}
-	}
-	resp := frame.NewBulkString(c.message)
-	_, err := resp.WriteTo(dest)
-	if err != nil {
-		// handle error
+		resp, _ := frame.NewSimpleString(c.message)
+		dest.WriteFrame(resp)
 		return
 	}
+	dest.WriteFrame(frame.NewBulkString(c.message))
 }
 
 func (c *Ping) FromFrame(f *frame.Array) error {
@@ -1,12 +1,9 @@
 package command
 
 import (
-	"bufio"
-	"bytes"
 	"github.com/stretchr/testify/assert"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
-	"log/slog"
 	"strings"
 	"testing"
 )
@@ -24,17 +21,14 @@ func TestPing_Apply(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			writeBuffer := &bytes.Buffer{}
-			writer := bufio.NewWriter(writeBuffer)
+			replies := NewRecorder(frame.RESP2)
 
 			ping := Ping{
 				message: tt.give,
-				logger:  &slog.Logger{},
 			}
-			ping.Apply(nil, writer)
+			ping.Apply(nil, replies)
 
-			f, _ := frame.Decode(bufio.NewReader(writeBuffer))
-			got, ok := f.(*frame.BulkString)
+			got, ok := replies.Frames[0].(*frame.BulkString)
 			if !ok {
 				t.Fatalf("expected success, got gerror")
 			}
@@ -72,7 +66,7 @@ func TestPing_FromFrame(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cmd := Ping{logger: &slog.Logger{}}
+			cmd := Ping{}
 			err := cmd.FromFrame(tt.frame)
 			assert.Equal(t, tt.wantError, err)
 			assert.Equal(t, tt.want, cmd.message)
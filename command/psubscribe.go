@@ -0,0 +1,55 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "psubscribe",
+		Arity:      -2,
+		Flags:      []string{FlagPubSub},
+		Categories: []string{CategoryPubSub, CategorySlow},
+		Summary:    "Listens for messages published to channels that match one or more patterns.",
+		Group:      "pubsub",
+		Since:      "2.0.0",
+		New:        func() Command { return new(PSubscribe) },
+	})
+}
+
+// PSubscribe subscribes the client to the channels matching glob-style patterns.
+type PSubscribe struct {
+	patterns []string
+	session  Session
+}
+
+func (c *PSubscribe) Apply(_ *db.Cache, dest ReplyWriter) {
+	counts := c.session.PSubscribe(c.patterns...)
+	replySubscriptions(dest, "psubscribe", c.patterns, counts)
+}
+
+func (c *PSubscribe) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.patterns = args
+	return nil
+}
+
+func (c *PSubscribe) Channels() ([]string, bool) {
+	return c.patterns, true
+}
+
+func (c *PSubscribe) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *PSubscribe) Name() string {
+	return "psubscribe"
+}
@@ -0,0 +1,56 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "publish",
+		Arity:      3,
+		Flags:      []string{FlagPubSub, FlagFast},
+		Categories: []string{CategoryPubSub, CategoryFast},
+		Summary:    "Posts a message to a channel.",
+		Group:      "pubsub",
+		Since:      "2.0.0",
+		New:        func() Command { return new(Publish) },
+	})
+}
+
+// Publish posts a message to a channel. It replies with the number of clients the message was delivered to.
+type Publish struct {
+	channel string
+	message string
+	session Session
+}
+
+func (c *Publish) Apply(_ *db.Cache, dest ReplyWriter) {
+	receivers := c.session.Publish(c.channel, c.message)
+	dest.WriteFrame(frame.NewInteger(int64(receivers)))
+}
+
+func (c *Publish) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.channel, c.message = args[0], args[1]
+	return nil
+}
+
+func (c *Publish) Channels() ([]string, bool) {
+	return []string{c.channel}, false
+}
+
+func (c *Publish) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Publish) Name() string {
+	return "publish"
+}
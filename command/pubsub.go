@@ -0,0 +1,85 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "pubsub",
+		Arity:      -2,
+		Flags:      []string{FlagPubSub},
+		Categories: []string{CategoryPubSub, CategorySlow},
+		Summary:    "Inspects the state of the Pub/Sub subsystem.",
+		Group:      "pubsub",
+		Since:      "2.8.0",
+		New:        func() Command { return new(PubSub) },
+	})
+}
+
+// PubSub inspects the state of the pub/sub subsystem with the CHANNELS, NUMSUB and NUMPAT subcommands.
+type PubSub struct {
+	subcommand string
+	args       []string
+	session    Session
+}
+
+func (c *PubSub) Apply(_ *db.Cache, dest ReplyWriter) {
+	switch c.subcommand {
+	case "channels":
+		pattern := ""
+		if len(c.args) == 1 {
+			pattern = c.args[0]
+		}
+		channels := c.session.ActiveChannels(pattern)
+		resp := frame.NewArray(len(channels))
+		for _, channel := range channels {
+			_ = resp.Append(frame.NewBulkString(channel))
+		}
+		dest.WriteFrame(resp)
+	case "numsub":
+		resp := frame.NewMap(len(c.args))
+		for _, channel := range c.args {
+			_ = resp.Append(frame.NewBulkString(channel), frame.NewInteger(int64(c.session.NumSub(channel))))
+		}
+		dest.WriteFrame(resp)
+	case "numpat":
+		dest.WriteFrame(frame.NewInteger(int64(c.session.NumPat())))
+	}
+}
+
+func (c *PubSub) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.subcommand, c.args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "channels":
+		if len(c.args) > 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	case "numsub":
+	case "numpat":
+		if len(c.args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+func (c *PubSub) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *PubSub) Name() string {
+	return "pubsub"
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "punsubscribe",
+		Arity:      -1,
+		Flags:      []string{FlagPubSub},
+		Categories: []string{CategoryPubSub, CategorySlow},
+		Summary:    "Stops listening to messages published to channels that match one or more patterns.",
+		Group:      "pubsub",
+		Since:      "2.0.0",
+		New:        func() Command { return new(PUnsubscribe) },
+	})
+}
+
+// PUnsubscribe unsubscribes the client from the given patterns, or from all of them when none is given.
+type PUnsubscribe struct {
+	patterns []string
+	session  Session
+}
+
+func (c *PUnsubscribe) Apply(_ *db.Cache, dest ReplyWriter) {
+	patterns, counts := c.session.PUnsubscribe(c.patterns...)
+	replySubscriptions(dest, "punsubscribe", patterns, counts)
+}
+
+func (c *PUnsubscribe) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.patterns = args
+	return nil
+}
+
+func (c *PUnsubscribe) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *PUnsubscribe) Name() string {
+	return "punsubscribe"
+}
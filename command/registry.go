@@ -0,0 +1,207 @@
+package command
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"sort"
+	"strings"
+)
+
+// Command flags, as reported by COMMAND INFO.
+const (
+	// FlagWrite marks the commands which may modify the keyspace.
+	FlagWrite = "write"
+	// FlagReadOnly marks the commands which only read the keyspace.
+	FlagReadOnly = "readonly"
+	// FlagDenyOOM marks the commands which may add data, denied when the cache is full and nothing can be evicted.
+	FlagDenyOOM = "denyoom"
+	// FlagAdmin marks the administrative commands.
+	FlagAdmin = "admin"
+	// FlagPubSub marks the publish/subscribe commands.
+	FlagPubSub = "pubsub"
+	// FlagFast marks the commands running in constant or logarithmic time.
+	FlagFast = "fast"
+	// FlagNoAuth marks the commands clients can run before being authenticated.
+	FlagNoAuth = "no_auth"
+	// FlagNoMulti marks the commands which cannot be queued in a transaction.
+	FlagNoMulti = "no_multi"
+	// FlagProtected marks the commands denied unless the server configuration enables them.
+	FlagProtected = "protected"
+)
+
+// ACL categories, as reported by COMMAND INFO without their @ prefix.
+const (
+	CategoryKeyspace    = "keyspace"
+	CategoryRead        = "read"
+	CategoryWrite       = "write"
+	CategoryString      = "string"
+	CategoryPubSub      = "pubsub"
+	CategoryAdmin       = "admin"
+	CategoryFast        = "fast"
+	CategorySlow        = "slow"
+	CategoryDangerous   = "dangerous"
+	CategoryConnection  = "connection"
+	CategoryTransaction = "transaction"
+)
+
+// Spec describes a command: how to create it, and the metadata used to validate it and answer COMMAND.
+type Spec struct {
+	Name string
+	// Arity is the number of arguments, the command name included. A negative arity -N means at least N arguments.
+	Arity int
+	Flags []string
+	// FirstKey, LastKey and Step locate the keys in the arguments, the command name being at index 0.
+	// A negative LastKey counts from the end, -1 being the last argument. FirstKey is 0 for commands without keys.
+	FirstKey int
+	LastKey  int
+	Step     int
+	// GetKeys locates the keys of the commands whose keys depend on their subcommand, in place of FirstKey,
+	// LastKey and Step. It is given the arguments of the command, its name first.
+	GetKeys func(args []string) []string
+	// Categories are the ACL categories of the command.
+	Categories []string
+	// Subcommands holds the subcommands whose ACL categories differ from the ones of the command, by lowercase
+	// name, like the administrative subcommands of CLIENT.
+	Subcommands map[string]Subcommand
+	// Summary, Group and Since document the command for COMMAND DOCS.
+	Summary string
+	Group   string
+	Since   string
+	// New creates an empty command, to be filled with FromFrame.
+	New func() Command
+}
+
+// Subcommand describes a subcommand whose permissions are checked apart from the ones of its command.
+type Subcommand struct {
+	Categories []string
+}
+
+// registry holds the specs of the commands, by lowercase name.
+var registry = make(map[string]*Spec)
+
+// Register makes a command available. It panics if a command is registered twice,
+// which can only be a programming error.
+func Register(spec Spec) {
+	name := strings.ToLower(spec.Name)
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("command %s registered twice", name))
+	}
+	spec.Name = name
+	registry[name] = &spec
+}
+
+// Lookup returns the spec of a command by name, regardless of its case.
+func Lookup(name string) (*Spec, bool) {
+	spec, ok := registry[strings.ToLower(name)]
+	return spec, ok
+}
+
+// SpecOf returns the spec of a command.
+func SpecOf(cmd Command) *Spec {
+	return registry[cmd.Name()]
+}
+
+// Specs returns the specs of all the commands, sorted by name.
+func Specs() []*Spec {
+	specs := make([]*Spec, 0, len(registry))
+	for _, spec := range registry {
+		specs = append(specs, spec)
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+	return specs
+}
+
+// Categories returns the ACL categories of all the commands, sorted.
+func Categories() []string {
+	seen := make(map[string]struct{})
+	categories := make([]string, 0)
+	for _, spec := range registry {
+		for _, category := range spec.Categories {
+			if _, ok := seen[category]; !ok {
+				seen[category] = struct{}{}
+				categories = append(categories, category)
+			}
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// HasFlag tells if a command has a flag.
+func (s *Spec) HasFlag(flag string) bool {
+	for _, f := range s.Flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the name and the ACL categories the permissions of a command are checked on, given its
+// arguments, its name first. Subcommands having their own categories are named after their command followed by |
+// and their name, like client|kill.
+func (s *Spec) Permissions(args []string) (string, []string) {
+	if len(args) > 1 {
+		name := strings.ToLower(args[1])
+		if sub, ok := s.Subcommands[name]; ok {
+			return s.Name + "|" + name, sub.Categories
+		}
+	}
+	return s.Name, s.Categories
+}
+
+// HasCategory tells if a command belongs to an ACL category.
+func (s *Spec) HasCategory(category string) bool {
+	for _, c := range s.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckArity checks the number of arguments of a command, its name included.
+func (s *Spec) CheckArity(n int) error {
+	if (s.Arity >= 0 && n != s.Arity) || (s.Arity < 0 && n < -s.Arity) {
+		return fmt.Errorf("%w for '%s' command", gerror.ErrWrongArity, s.Name)
+	}
+	return nil
+}
+
+// Keys returns the keys among the arguments of a command, its name included at index 0.
+func (s *Spec) Keys(args []string) []string {
+	if s.GetKeys != nil {
+		return s.GetKeys(args)
+	}
+	if s.FirstKey <= 0 || s.FirstKey >= len(args) {
+		return nil
+	}
+	last := s.LastKey
+	if last < 0 {
+		last += len(args)
+	}
+	last = min(last, len(args)-1)
+	keys := make([]string, 0, (last-s.FirstKey)/max(s.Step, 1)+1)
+	for i := s.FirstKey; i <= last; i += max(s.Step, 1) {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// Parse creates a command from a frame array, checking its name and arity first.
+func Parse(f *frame.Array) (Command, error) {
+	name, err := GetCmdName(f)
+	if err != nil {
+		return nil, err
+	}
+	spec, _ := Lookup(name)
+	if err = spec.CheckArity(f.Size()); err != nil {
+		return nil, err
+	}
+	cmd := spec.New()
+	if err = cmd.FromFrame(f); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
@@ -0,0 +1,148 @@
+package command
+
+import (
+	"bufio"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"testing"
+)
+
+func TestSpec_CheckArity(t *testing.T) {
+	tests := []struct {
+		name  string
+		arity int
+		n     int
+		ok    bool
+	}{
+		{name: "Exact", arity: 2, n: 2, ok: true},
+		{name: "ExactTooFew", arity: 2, n: 1},
+		{name: "ExactTooMany", arity: 2, n: 3},
+		{name: "AtLeast", arity: -2, n: 5, ok: true},
+		{name: "AtLeastMinimum", arity: -2, n: 2, ok: true},
+		{name: "AtLeastTooFew", arity: -2, n: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &Spec{Name: "test", Arity: tt.arity}
+			err := spec.CheckArity(tt.n)
+			if tt.ok {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, gerror.ErrWrongArity)
+			assert.Equal(t, "wrong number of arguments for 'test' command", err.Error())
+		})
+	}
+}
+
+func TestSpec_Keys(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "SingleKey", args: []string{"get", "key"}, want: []string{"key"}},
+		{name: "KeyAndValue", args: []string{"set", "key", "value", "EX", "10"}, want: []string{"key"}},
+		{name: "AllKeys", args: []string{"del", "a", "b", "c"}, want: []string{"a", "b", "c"}},
+		{name: "NoKey", args: []string{"ping"}, want: nil},
+		{name: "Subcommand", args: []string{"object", "freq", "key"}, want: []string{"key"}},
+		{name: "SubcommandWithKey", args: []string{"memory", "usage", "key", "samples", "5"}, want: []string{"key"}},
+		{name: "SubcommandWithoutKey", args: []string{"memory", "bigkeys", "count", "5"}, want: nil},
+		{name: "DebugKeys", args: []string{"debug", "digest-value", "a", "b"}, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, ok := Lookup(tt.args[0])
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, spec.Keys(tt.args))
+		})
+	}
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	spec, ok := Lookup("SeT")
+	assert.True(t, ok)
+	assert.Equal(t, "set", spec.Name)
+	assert.True(t, spec.HasFlag(FlagWrite))
+	assert.True(t, spec.HasFlag(FlagDenyOOM))
+	assert.True(t, spec.HasCategory(CategoryString))
+
+	_, ok = Lookup("nonexistent")
+	assert.False(t, ok)
+
+	assert.Panics(t, func() { Register(Spec{Name: "GET"}) })
+	assert.Equal(t, spec, SpecOf(&Set{}))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     *frame.Array
+		want      string
+		wantError error
+	}{
+		{name: "Valid", frame: cmdFrame("GET", "key"), want: "get"},
+		{name: "Unknown", frame: cmdFrame("NONEXISTENT"), wantError: gerror.ErrInvalidCmdName},
+		{name: "TooFewArguments", frame: cmdFrame("GET"), wantError: gerror.ErrWrongArity},
+		{name: "TooManyArguments", frame: cmdFrame("GET", "a", "b"), wantError: gerror.ErrWrongArity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := Parse(tt.frame)
+			if tt.wantError != nil {
+				assert.True(t, errors.Is(err, tt.wantError), "got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, cmd.Name())
+		})
+	}
+}
+
+func TestCommands_Apply(t *testing.T) {
+	apply := func(protocol int, args ...string) frame.Framer {
+		cmd, err := Parse(cmdFrame(append([]string{"COMMAND"}, args...)...))
+		assert.NoError(t, err)
+		replies := NewRecorder(protocol)
+		cmd.Apply(nil, replies)
+		resp, err := frame.Decode(bufio.NewReader(strings.NewReader(replies.String())))
+		assert.NoError(t, err)
+		return resp
+	}
+
+	count := apply(frame.RESP2, "COUNT")
+	assert.Equal(t, frame.NewInteger(int64(len(Specs()))), count)
+
+	all := apply(frame.RESP2).(*frame.Array)
+	assert.Equal(t, len(Specs()), all.Size())
+
+	info := apply(frame.RESP2, "INFO", "get", "nonexistent").(*frame.Array)
+	assert.Equal(t, 2, info.Size())
+	get := info.Get(0).(*frame.Array)
+	assert.Equal(t, 10, get.Size())
+	assert.Equal(t, frame.NewBulkString("get"), get.Get(0))
+	assert.Equal(t, frame.NewInteger(2), get.Get(1))
+	assert.Equal(t, frame.NewInteger(1), get.Get(3))
+	assert.IsType(t, &frame.NullBulkString{}, info.Get(1))
+
+	docs := apply(frame.RESP2, "DOCS", "get").(*frame.Array)
+	assert.Equal(t, 2, docs.Size())
+	assert.Equal(t, frame.NewBulkString("get"), docs.Get(0))
+	assert.IsType(t, &frame.Array{}, docs.Get(1))
+
+	docs3 := apply(frame.RESP3, "DOCS", "get")
+	assert.IsType(t, &frame.Map{}, docs3)
+}
+
+func TestCommands_FromFrame(t *testing.T) {
+	_, err := Parse(cmdFrame("COMMAND", "UNKNOWN"))
+	assert.Equal(t, gerror.ErrUnknownSubCmd, err)
+	_, err = Parse(cmdFrame("COMMAND", "COUNT", "extra"))
+	assert.Equal(t, gerror.ErrInvalidCmdArgs, err)
+}
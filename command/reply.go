@@ -0,0 +1,169 @@
+package command
+
+import (
+	"bufio"
+	"github.com/ynachi/gcache/frame"
+	"strings"
+)
+
+// ReplyWriter receives the replies of the commands. Commands build RESP3 replies and leave it to the writer to
+// adapt them to the protocol spoken by the client, so that they do not depend on where their replies go:
+// a connection, a transaction or a test.
+type ReplyWriter interface {
+	// WriteFrame writes a reply.
+	WriteFrame(f frame.Framer)
+
+	// WriteOK acknowledges a command with the OK simple string.
+	WriteOK()
+
+	// WriteNull writes a null reply.
+	WriteNull()
+
+	// WriteNullArray writes a null reply in place of an array, which RESP2 tells apart from a null bulk string.
+	WriteNullArray()
+
+	// WriteError reports an error to the client.
+	WriteError(err error)
+
+	// Protocol returns the RESP version the replies are written for.
+	Protocol() int
+}
+
+// RespWriter writes the replies to a buffered stream, downgrading them for RESP2 clients.
+// It does not flush the stream: the owner of the stream does, once the command is applied.
+// Write errors are remembered and reported by Err, the first one making subsequent writes no-ops.
+type RespWriter struct {
+	dest *bufio.Writer
+	// protocol is read on each write, as HELLO switches the protocol of the client it replies to.
+	protocol func() int
+	err      error
+	// pending is the number of bytes written since the last flush, checked by limit if set.
+	pending int
+	limit   func(pending int) error
+}
+
+// NewRespWriter creates a writer of replies for a stream whose protocol is given by a function.
+func NewRespWriter(dest *bufio.Writer, protocol func() int) *RespWriter {
+	return &RespWriter{dest: dest, protocol: protocol}
+}
+
+// SetLimit makes the writer call limit with the number of bytes written since the last flush after each reply.
+// An error returned by limit is reported by Err and makes subsequent writes no-ops, like a write error.
+func (w *RespWriter) SetLimit(limit func(pending int) error) {
+	w.limit = limit
+}
+
+func (w *RespWriter) WriteFrame(f frame.Framer) {
+	if w.err != nil {
+		return
+	}
+	n, err := frame.ForProtocol(w.Protocol(), f).WriteTo(w.dest)
+	w.pending += int(n)
+	w.err = err
+	if w.err == nil && w.limit != nil {
+		w.err = w.limit(w.pending)
+	}
+}
+
+func (w *RespWriter) WriteOK() {
+	writeOK(w)
+}
+
+func (w *RespWriter) WriteNull() {
+	w.WriteFrame(&frame.Null{})
+}
+
+func (w *RespWriter) WriteNullArray() {
+	writeNullArray(w)
+}
+
+func (w *RespWriter) WriteError(err error) {
+	writeError(w, err)
+}
+
+func (w *RespWriter) Protocol() int {
+	return w.protocol()
+}
+
+// Flush sends the buffered replies to the stream.
+func (w *RespWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = w.dest.Flush()
+	w.pending = 0
+	return w.err
+}
+
+// Err returns the first error which occurred while writing, if any.
+func (w *RespWriter) Err() error {
+	return w.err
+}
+
+// Recorder keeps the replies in memory, as written by the commands. It serves to collect the replies of the
+// commands of a transaction, and to test commands.
+type Recorder struct {
+	protocol int
+	Frames   []frame.Framer
+}
+
+// NewRecorder creates a recorder of replies for a protocol.
+func NewRecorder(protocol int) *Recorder {
+	return &Recorder{protocol: protocol}
+}
+
+func (r *Recorder) WriteFrame(f frame.Framer) {
+	r.Frames = append(r.Frames, f)
+}
+
+func (r *Recorder) WriteOK() {
+	writeOK(r)
+}
+
+func (r *Recorder) WriteNull() {
+	r.WriteFrame(&frame.Null{})
+}
+
+func (r *Recorder) WriteNullArray() {
+	writeNullArray(r)
+}
+
+func (r *Recorder) WriteError(err error) {
+	writeError(r, err)
+}
+
+func (r *Recorder) Protocol() int {
+	return r.protocol
+}
+
+// String returns the replies as the client would read them.
+func (r *Recorder) String() string {
+	sb := strings.Builder{}
+	for _, f := range r.Frames {
+		sb.WriteString(frame.ForProtocol(r.protocol, f).String())
+	}
+	return sb.String()
+}
+
+func writeOK(w ReplyWriter) {
+	resp, _ := frame.NewSimpleString("OK")
+	w.WriteFrame(resp)
+}
+
+// writeNullArray writes the null array of RESP2, or the null of RESP3 which has a single null type.
+func writeNullArray(w ReplyWriter) {
+	if w.Protocol() < frame.RESP3 {
+		w.WriteFrame(&frame.NullArray{})
+		return
+	}
+	w.WriteFrame(&frame.Null{})
+}
+
+// writeError reports an error as an error frame. Errors cannot hold line breaks, which are replaced by spaces.
+func writeError(w ReplyWriter, err error) {
+	resp, fErr := frame.NewError(err.Error())
+	if fErr != nil {
+		resp, _ = frame.NewError(strings.NewReplacer("\r", " ", "\n", " ").Replace(err.Error()))
+	}
+	w.WriteFrame(resp)
+}
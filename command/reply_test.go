@@ -0,0 +1,78 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/frame"
+	"testing"
+)
+
+func TestRespWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	protocol := frame.RESP2
+	w := NewRespWriter(bufio.NewWriter(out), func() int { return protocol })
+
+	w.WriteNull()
+	w.WriteOK()
+	assert.Empty(t, out.String(), "replies should be buffered until flushed")
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "$-1\r\n+OK\r\n", out.String())
+
+	out.Reset()
+	protocol = frame.RESP3
+	w.WriteNull()
+	w.WriteError(errors.New("ERR line\r\nbreak"))
+	assert.NoError(t, w.Flush())
+	assert.Equal(t, "_\r\n-ERR line  break\r\n", out.String())
+	assert.NoError(t, w.Err())
+}
+
+// failingWriter fails all the writes.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("connection closed")
+}
+
+func TestRespWriter_Err(t *testing.T) {
+	w := NewRespWriter(bufio.NewWriterSize(failingWriter{}, 16), func() int { return frame.RESP2 })
+	w.WriteFrame(frame.NewBulkString("a value longer than the buffer"))
+	assert.Error(t, w.Err())
+	assert.Error(t, w.Flush())
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(frame.RESP2)
+	cmd := Get{key: "missing"}
+	cmd.Apply(_cache, r)
+
+	assert.Len(t, r.Frames, 1)
+	assert.IsType(t, &frame.Null{}, r.Frames[0])
+	assert.Equal(t, "$-1\r\n", r.String())
+}
+
+func TestRespWriter_Limit(t *testing.T) {
+	out := &bytes.Buffer{}
+	w := NewRespWriter(bufio.NewWriter(out), func() int { return frame.RESP2 })
+	limitErr := errors.New("limit reached")
+	var checked []int
+	w.SetLimit(func(pending int) error {
+		checked = append(checked, pending)
+		if pending > 10 {
+			return limitErr
+		}
+		return nil
+	})
+
+	w.WriteOK()
+	assert.NoError(t, w.Flush())
+	w.WriteOK()
+	w.WriteOK()
+	w.WriteOK()
+	assert.Equal(t, []int{5, 5, 10, 15}, checked, "the bytes should be counted from the last flush")
+	assert.ErrorIs(t, w.Flush(), limitErr)
+	w.WriteOK()
+	assert.Len(t, checked, 4, "writes should stop once the limit is reached")
+}
@@ -0,0 +1,68 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "select",
+		Arity:      2,
+		Flags:      []string{FlagFast},
+		Categories: []string{CategoryFast, CategoryConnection},
+		Summary:    "Changes the selected database.",
+		Group:      "connection",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Select) },
+	})
+}
+
+// Select changes the database used by the connection.
+type Select struct {
+	index   int
+	session Session
+}
+
+func (c *Select) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.Select(c.index); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *Select) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.index, err = parseDBIndex(args[0])
+	return err
+}
+
+func (c *Select) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Select) Name() string {
+	return "select"
+}
+
+// parseDBIndex reads a database index from a command argument.
+// Only the syntax is checked here, the upper bound is known by the server.
+func parseDBIndex(arg string) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, gerror.ErrInvalidDBIndex
+	}
+	if index < 0 {
+		return 0, gerror.ErrDBIndexOutRange
+	}
+	return index, nil
+}
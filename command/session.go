@@ -0,0 +1,148 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/monitor"
+	"time"
+)
+
+// Session is the view a command has on the connection it was issued from.
+// Through it, commands can reach the state owned by the connection and the server, beyond the
+// database they are applied to.
+type Session interface {
+	// Select makes the database at the given index the one used by subsequent commands.
+	Select(index int) error
+
+	// Database returns the database at the given index.
+	Database(index int) (*db.Cache, error)
+
+	// DatabaseCount returns the number of logical databases held by the server.
+	DatabaseCount() int
+
+	// SwapDB swaps two databases, so that clients connected to one of them immediately see the other.
+	SwapDB(i, j int) error
+
+	// Multi opens a transaction. Subsequent commands are queued until Exec or Discard is called.
+	Multi() error
+
+	// Exec atomically applies the commands queued since Multi and writes their replies as an array.
+	// A null reply is written instead if one of the watched keys was modified in the meantime.
+	Exec(dest ReplyWriter) error
+
+	// Discard drops the commands queued since Multi.
+	Discard() error
+
+	// Watch marks keys of the selected database so that the next transaction fails if one of them is modified.
+	Watch(keys ...string) error
+
+	// Unwatch forgets all the watched keys.
+	Unwatch()
+
+	// Protocol returns the RESP version spoken by the client.
+	Protocol() int
+
+	// SetProtocol switches the RESP version spoken by the client.
+	SetProtocol(version int)
+
+	// Subscribe subscribes the client to channels.
+	// It returns the number of subscriptions held by the client after each of them.
+	Subscribe(channels ...string) []int
+
+	// PSubscribe subscribes the client to channel patterns.
+	// It returns the number of subscriptions held by the client after each of them.
+	PSubscribe(patterns ...string) []int
+
+	// Unsubscribe unsubscribes the client from channels, or from all of them if none is given.
+	// It returns the channels along with the number of subscriptions left after each of them.
+	Unsubscribe(channels ...string) ([]string, []int)
+
+	// PUnsubscribe unsubscribes the client from patterns, or from all of them if none is given.
+	// It returns the patterns along with the number of subscriptions left after each of them.
+	PUnsubscribe(patterns ...string) ([]string, []int)
+
+	// Publish sends a message to a channel and returns the number of clients it was delivered to.
+	Publish(channel, message string) int
+
+	// ActiveChannels returns the channels with subscribers matching a pattern, all of them if the pattern is empty.
+	ActiveChannels(pattern string) []string
+
+	// NumSub returns the number of subscribers of a channel.
+	NumSub(channel string) int
+
+	// NumPat returns the number of patterns subscribed to by all the clients.
+	NumPat() int
+
+	// ConfigGet returns the runtime parameters matching a glob-style pattern as alternating names and values.
+	ConfigGet(pattern string) []string
+
+	// ConfigSet changes a runtime parameter of the server.
+	ConfigSet(name, value string) error
+
+	// ClientID returns the unique identifier of the client.
+	ClientID() int64
+
+	// Tracking enables client side caching for the client with the given options, or disables it.
+	Tracking(on bool, opts TrackingOptions) error
+
+	// Caching decides if the keys read by the next command are tracked, for clients in OPTIN or OPTOUT mode.
+	Caching(yes bool) error
+
+	// Auth authenticates the client as a user.
+	Auth(username, password string) error
+
+	// User returns the name of the user the client is authenticated as, or an empty string if it is not.
+	User() string
+
+	// ACL returns the users of the server and their permissions.
+	ACL() *acl.ACL
+
+	// DelUsers removes users and disconnects the clients authenticated as them.
+	// It returns the number of users which existed.
+	DelUsers(names ...string) (int, error)
+
+	// ClientName returns the name the client set for itself, empty if none.
+	ClientName() string
+
+	// SetClientName names the client, or clears its name if empty.
+	SetClientName(name string)
+
+	// ClientList describes the clients selected by a filter, one line per client.
+	ClientList(filter ClientFilter) []string
+
+	// KillClients disconnects the clients selected by a filter and returns how many were. The client itself is
+	// disconnected once its reply is sent.
+	KillClients(filter ClientFilter) int
+
+	// PauseClients suspends the processing of the commands of all the clients, or only of the ones writing if
+	// all is not set, for a duration.
+	PauseClients(timeout time.Duration, all bool)
+
+	// UnpauseClients resumes the processing of the commands suspended by PauseClients.
+	UnpauseClients()
+
+	// Monitor makes the client receive the commands processed on behalf of the other clients.
+	Monitor()
+
+	// SetActiveExpire enables or disables the background expiration of the keys. Keys keep expiring when accessed.
+	SetActiveExpire(enabled bool)
+
+	// ClientsMemory returns the memory used by the buffers of the clients, by class (normal and pubsub).
+	ClientsMemory() map[string]int64
+
+	// SlowLog returns the log of the commands slower than the configured threshold.
+	SlowLog() *monitor.SlowLog
+
+	// Latency returns the latency monitor of the server.
+	Latency() *monitor.Latency
+
+	// TrackingRedirect returns the ID of the client receiving the invalidation messages, 0 if the client receives
+	// them itself, or -1 if tracking is disabled.
+	TrackingRedirect() int64
+}
+
+// SessionAware is implemented by commands which need the session they were issued from.
+// The server binds the session before applying the command.
+type SessionAware interface {
+	BindSession(s Session)
+}
@@ -1,45 +1,81 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
-	"log/slog"
+	"strings"
+	"time"
 )
 
+func init() {
+	Register(Spec{
+		Name:       "set",
+		Arity:      -3,
+		Flags:      []string{FlagWrite, FlagDenyOOM},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryWrite, CategoryString, CategorySlow},
+		Summary:    "Sets the string value of a key, ignoring its type. The key is created if it doesn't exist.",
+		Group:      "string",
+		Since:      "1.0.0",
+		New:        func() Command { return new(Set) },
+	})
+}
+
 type Set struct {
-	key    string
-	value  string
-	logger *slog.Logger
+	key   string
+	value string
+	ttl   time.Duration
 }
 
-func (c *Set) Apply(cache *db.Cache, dest *bufio.Writer) {
-	defer func(dest *bufio.Writer) {
-		err := dest.Flush()
-		if err != nil {
-			c.logger.Error("unable to write to destination", "error", err)
-		}
-	}(dest)
-	cache.Set(c.key, c.value)
-	resp, _ := frame.NewSimpleString("ok")
-	_, err := resp.WriteTo(dest)
-	if err != nil {
-		c.logger.Error("failed to write response", "error", err)
+func (c *Set) Apply(cache *db.Cache, dest ReplyWriter) {
+	if err := cache.SetWithTTL(c.key, c.value, c.ttl); err != nil {
+		dest.WriteError(err)
+		return
 	}
+	resp, _ := frame.NewSimpleString("ok")
+	dest.WriteFrame(resp)
 }
 
-// @TODO Minimal implementation for now, to fix.
+// FromFrame reads SET key value [EX seconds | PX milliseconds].
 func (c *Set) FromFrame(f *frame.Array) error {
-	if f.Size() != 3 {
+	if f.Size() != 3 && f.Size() != 5 {
 		return gerror.ErrInvalidCmdArgs
 	}
-	c.key = f.Get(1).(*frame.BulkString).Value()
-	c.value = f.Get(2).(*frame.BulkString).Value()
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return gerror.ErrSyntax
+	}
+	c.key, c.value = args[0], args[1]
+	if len(args) == 2 {
+		return nil
+	}
 
+	unit := time.Second
+	switch strings.ToLower(args[2]) {
+	case "ex":
+	case "px":
+		unit = time.Millisecond
+	default:
+		return gerror.ErrSyntax
+	}
+	ttl, err := parseTTL(args[3], unit)
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return gerror.ErrInvalidExpire
+	}
+	c.ttl = ttl
 	return nil
 }
 
+func (c *Set) WriteKeys() []string {
+	return []string{c.key}
+}
+
 func (c *Set) Name() string {
 	return "set"
 }
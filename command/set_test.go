@@ -1,12 +1,12 @@
 package command
 
 import (
-	"bufio"
-	"bytes"
+	"github.com/stretchr/testify/assert"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
-	"log/slog"
+	"github.com/ynachi/gcache/gerror"
 	"testing"
+	"time"
 )
 
 var _cache, _ = db.NewCache(5, "LRU")
@@ -23,18 +23,15 @@ func TestSet_Apply_Ok(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			writeBuffer := &bytes.Buffer{}
-			writer := bufio.NewWriter(writeBuffer)
+			replies := NewRecorder(frame.RESP2)
 
-			ping := Set{
-				key:    tt.giveKey,
-				value:  tt.giveValue,
-				logger: &slog.Logger{},
+			cmd := Set{
+				key:   tt.giveKey,
+				value: tt.giveValue,
 			}
-			ping.Apply(_cache, writer)
+			cmd.Apply(_cache, replies)
 
-			f, _ := frame.Decode(bufio.NewReader(writeBuffer))
-			got, ok := f.(*frame.SimpleString)
+			got, ok := replies.Frames[0].(*frame.SimpleString)
 			if !ok {
 				t.Fatalf("expected success, got gerror")
 			}
@@ -46,36 +43,64 @@ func TestSet_Apply_Ok(t *testing.T) {
 	}
 }
 
-//func TestSet_FromFrame(t *testing.T) {
-//	simplePING := frame.NewArray(1)
-//	_ = simplePING.Append(frame.NewBulkString("PING"))
-//
-//	pingWithMsg := frame.NewArray(2)
-//	_ = pingWithMsg.Append(frame.NewBulkString("PING"))
-//	_ = pingWithMsg.Append(frame.NewBulkString("Hello World"))
-//
-//	pingTooManyArgs := frame.NewArray(3)
-//	_ = pingTooManyArgs.Append(frame.NewBulkString("PING"))
-//	_ = pingTooManyArgs.Append(frame.NewBulkString("Hello World"))
-//	_ = pingTooManyArgs.Append(frame.NewBulkString("Wrong"))
-//
-//	tests := []struct {
-//		name      string
-//		frame     *frame.Array
-//		want      string
-//		wantError error
-//	}{
-//		{name: "ValidSimplePing", frame: simplePING, want: "PONG", wantError: nil},
-//		{name: "ValidPingWithMessage", frame: pingWithMsg, want: "Hello World", wantError: nil},
-//		{name: "InvalidTooManyArgs", frame: pingTooManyArgs, want: "", wantError: gerror.ErrInvalidPingCommand},
-//	}
-//
-//	for _, tt := range tests {
-//		t.Run(tt.name, func(t *testing.T) {
-//			cmd := Ping{logger: &slog.Logger{}}
-//			err := cmd.FromFrame(tt.frame)
-//			assert.Equal(t, tt.wantError, err)
-//			assert.Equal(t, tt.want, cmd.message)
-//		})
-//	}
-//}
+// newFrame builds a request from its frames.
+func newFrame(parts ...frame.Framer) *frame.Array {
+	f := frame.NewArray(len(parts))
+	for _, part := range parts {
+		_ = f.Append(part)
+	}
+	return f
+}
+
+func TestSet_FromFrame(t *testing.T) {
+	bulk := frame.NewBulkString
+	tests := []struct {
+		name    string
+		frame   *frame.Array
+		wantTTL time.Duration
+		wantErr error
+	}{
+		{name: "NoTTL", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"))},
+		{name: "EX", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("ex"), bulk("10")), wantTTL: 10 * time.Second},
+		{name: "PX", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("PX"), bulk("10")), wantTTL: 10 * time.Millisecond},
+		{name: "IntegerOption", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), frame.NewInteger(1), bulk("10")), wantErr: gerror.ErrSyntax},
+		{name: "ArrayAmount", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("EX"), frame.NewArray(0)), wantErr: gerror.ErrSyntax},
+		{name: "UnknownOption", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("KEEP"), bulk("10")), wantErr: gerror.ErrSyntax},
+		{name: "NotInteger", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("EX"), bulk("ten")), wantErr: gerror.ErrNotInteger},
+		{name: "Negative", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("EX"), bulk("-1")), wantErr: gerror.ErrInvalidExpire},
+		{name: "Overflow", frame: newFrame(bulk("SET"), bulk("key"), bulk("value"), bulk("EX"), bulk("9223372036854775807")), wantErr: gerror.ErrInvalidExpire},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := Set{}
+			err := cmd.FromFrame(tt.frame)
+			assert.Equal(t, tt.wantErr, err)
+			if err == nil {
+				assert.Equal(t, "key", cmd.key)
+				assert.Equal(t, tt.wantTTL, cmd.ttl)
+			}
+		})
+	}
+}
+
+func TestExpire_FromFrame(t *testing.T) {
+	bulk := frame.NewBulkString
+	cmd := Expire{}
+	assert.NoError(t, cmd.FromFrame(newFrame(bulk("EXPIRE"), bulk("key"), bulk("9223372036"))))
+	assert.Equal(t, 9223372036*time.Second, cmd.ttl)
+	assert.Equal(t, gerror.ErrInvalidExpire, cmd.FromFrame(newFrame(bulk("EXPIRE"), bulk("key"), bulk("9223372037"))))
+	assert.Equal(t, gerror.ErrInvalidExpire, cmd.FromFrame(newFrame(bulk("EXPIRE"), bulk("key"), bulk("-9223372037"))))
+}
+
+func TestSet_Apply_OOM(t *testing.T) {
+	cache, _ := db.NewCache(0, "noeviction")
+	_ = cache.Set("first", "value")
+	replies := NewRecorder(frame.RESP2)
+	cmd := Set{key: "second", value: "value"}
+	cmd.Apply(cache, replies)
+
+	if got := replies.String(); got != "-"+gerror.ErrOOM.Error()+"\r\n" {
+		t.Errorf("wanted an OOM error but got %q", got)
+	}
+}
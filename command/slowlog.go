@@ -0,0 +1,96 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "slowlog",
+		Arity:      -2,
+		Flags:      []string{FlagAdmin},
+		Categories: []string{CategoryAdmin, CategorySlow, CategoryDangerous},
+		Summary:    "Reads or resets the log of the commands slower than a threshold.",
+		Group:      "server",
+		Since:      "2.2.12",
+		New:        func() Command { return new(SlowLog) },
+	})
+}
+
+// SlowLog inspects the log of the slow commands with the GET, LEN and RESET subcommands.
+type SlowLog struct {
+	subcommand string
+	// count is the number of entries GET replies, all of them if negative.
+	count   int
+	session Session
+}
+
+func (c *SlowLog) Apply(_ *db.Cache, dest ReplyWriter) {
+	log := c.session.SlowLog()
+	switch c.subcommand {
+	case "get":
+		entries := log.Entries(c.count)
+		resp := frame.NewArray(len(entries))
+		for _, e := range entries {
+			args := frame.NewArray(len(e.Args))
+			for _, arg := range e.Args {
+				_ = args.Append(frame.NewBulkString(arg))
+			}
+			entry := frame.NewArray(6)
+			_ = entry.Append(frame.NewInteger(e.ID))
+			_ = entry.Append(frame.NewInteger(e.Time.Unix()))
+			_ = entry.Append(frame.NewInteger(e.Duration.Microseconds()))
+			_ = entry.Append(args)
+			_ = entry.Append(frame.NewBulkString(e.ClientAddr))
+			_ = entry.Append(frame.NewBulkString(e.ClientName))
+			_ = resp.Append(entry)
+		}
+		dest.WriteFrame(resp)
+	case "len":
+		dest.WriteFrame(frame.NewInteger(int64(log.Len())))
+	case "reset":
+		log.Reset()
+		dest.WriteOK()
+	}
+}
+
+func (c *SlowLog) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.subcommand, args = strings.ToLower(args[0]), args[1:]
+	switch c.subcommand {
+	case "get":
+		if len(args) > 1 {
+			return gerror.ErrInvalidCmdArgs
+		}
+		c.count = 10
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < -1 {
+				return gerror.ErrNotInteger
+			}
+			c.count = n
+		}
+	case "len", "reset":
+		if len(args) != 0 {
+			return gerror.ErrInvalidCmdArgs
+		}
+	default:
+		return gerror.ErrUnknownSubCmd
+	}
+	return nil
+}
+
+func (c *SlowLog) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *SlowLog) Name() string {
+	return "slowlog"
+}
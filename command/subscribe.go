@@ -0,0 +1,73 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "subscribe",
+		Arity:      -2,
+		Flags:      []string{FlagPubSub},
+		Categories: []string{CategoryPubSub, CategorySlow},
+		Summary:    "Listens for messages published to channels.",
+		Group:      "pubsub",
+		Since:      "2.0.0",
+		New:        func() Command { return new(Subscribe) },
+	})
+}
+
+// Subscribe subscribes the client to channels. A RESP2 client then enters the subscriber mode.
+type Subscribe struct {
+	channels []string
+	session  Session
+}
+
+func (c *Subscribe) Apply(_ *db.Cache, dest ReplyWriter) {
+	counts := c.session.Subscribe(c.channels...)
+	replySubscriptions(dest, "subscribe", c.channels, counts)
+}
+
+func (c *Subscribe) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.channels = args
+	return nil
+}
+
+func (c *Subscribe) Channels() ([]string, bool) {
+	return c.channels, false
+}
+
+func (c *Subscribe) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Subscribe) Name() string {
+	return "subscribe"
+}
+
+// replySubscriptions confirms (un)subscriptions with one out-of-band frame per channel or pattern,
+// holding the number of subscriptions of the client after it.
+// When unsubscribing a client which had no subscription, a single frame with a null channel is sent.
+func replySubscriptions(dest ReplyWriter, kind string, names []string, counts []int) {
+	if len(names) == 0 {
+		dest.WriteFrame(frame.NewOutOfBand(dest.Protocol(), frame.NewBulkString(kind), &frame.Null{}, frame.NewInteger(0)))
+		return
+	}
+	for i, name := range names {
+		dest.WriteFrame(frame.NewOutOfBand(
+			dest.Protocol(),
+			frame.NewBulkString(kind),
+			frame.NewBulkString(name),
+			frame.NewInteger(int64(counts[i])),
+		))
+	}
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "swapdb",
+		Arity:      3,
+		Flags:      []string{FlagWrite, FlagFast},
+		Categories: []string{CategoryKeyspace, CategoryWrite, CategoryFast, CategoryDangerous},
+		Summary:    "Swaps two databases.",
+		Group:      "server",
+		Since:      "4.0.0",
+		New:        func() Command { return new(SwapDB) },
+	})
+}
+
+// SwapDB swaps two databases. Clients connected to one of them immediately see the content of the other.
+type SwapDB struct {
+	first   int
+	second  int
+	session Session
+}
+
+func (c *SwapDB) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.SwapDB(c.first, c.second); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *SwapDB) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 2 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	if c.first, err = parseDBIndex(args[0]); err != nil {
+		return err
+	}
+	c.second, err = parseDBIndex(args[1])
+	return err
+}
+
+func (c *SwapDB) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *SwapDB) Name() string {
+	return "swapdb"
+}
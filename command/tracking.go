@@ -0,0 +1,29 @@
+package command
+
+// KeysReader is implemented by commands reading keys.
+// The keys they read are remembered for the clients using client side caching.
+type KeysReader interface {
+	ReadKeys() []string
+}
+
+// KeysWriter is implemented by commands modifying keys.
+// The clients caching these keys are sent invalidation messages.
+type KeysWriter interface {
+	WriteKeys() []string
+}
+
+// TrackingOptions are the options of CLIENT TRACKING.
+type TrackingOptions struct {
+	// Redirect is the ID of the client receiving the invalidation messages. 0 means the tracking client itself.
+	Redirect int64
+	// BCast makes the client receive invalidation messages for all the keys matching Prefixes,
+	// instead of the keys it read.
+	BCast    bool
+	Prefixes []string
+	// OptIn only tracks the keys read by the command following CLIENT CACHING yes.
+	OptIn bool
+	// OptOut tracks the keys read by all the commands except the one following CLIENT CACHING no.
+	OptOut bool
+	// NoLoop skips the invalidation messages for the keys modified by the client itself.
+	NoLoop bool
+}
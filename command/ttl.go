@@ -0,0 +1,63 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"time"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "ttl",
+		Arity:      2,
+		Flags:      []string{FlagReadOnly, FlagFast},
+		FirstKey:   1,
+		LastKey:    1,
+		Step:       1,
+		Categories: []string{CategoryKeyspace, CategoryRead, CategoryFast},
+		Summary:    "Returns the expiration time in seconds of a key.",
+		Group:      "generic",
+		Since:      "1.0.0",
+		New:        func() Command { return new(TTL) },
+	})
+}
+
+// TTL returns the remaining time to live of a key in seconds.
+// It replies -2 if the key does not exist and -1 if the key has no time to live.
+type TTL struct {
+	key string
+}
+
+func (c *TTL) Apply(cache *db.Cache, dest ReplyWriter) {
+	ttl, ok := cache.TTL(c.key)
+	switch {
+	case !ok:
+		dest.WriteFrame(frame.NewInteger(-2))
+	case ttl < 0:
+		dest.WriteFrame(frame.NewInteger(-1))
+	default:
+		// round to the closest second, like Redis does
+		dest.WriteFrame(frame.NewInteger(int64((ttl + time.Second/2) / time.Second)))
+	}
+}
+
+func (c *TTL) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.key = args[0]
+	return nil
+}
+
+func (c *TTL) ReadKeys() []string {
+	return []string{c.key}
+}
+
+func (c *TTL) Name() string {
+	return "ttl"
+}
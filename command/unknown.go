@@ -1,14 +1,13 @@
 package command
 
 import (
-	"bufio"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 )
 
 type Unknown struct{}
 
-func (c *Unknown) Apply(_ *db.Cache, _ *bufio.Writer) {
+func (c *Unknown) Apply(_ *db.Cache, _ ReplyWriter) {
 }
 
 func (c *Unknown) FromFrame(_ *frame.Array) error {
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "unsubscribe",
+		Arity:      -1,
+		Flags:      []string{FlagPubSub},
+		Categories: []string{CategoryPubSub, CategorySlow},
+		Summary:    "Stops listening to messages posted to channels.",
+		Group:      "pubsub",
+		Since:      "2.0.0",
+		New:        func() Command { return new(Unsubscribe) },
+	})
+}
+
+// Unsubscribe unsubscribes the client from the given channels, or from all of them when none is given.
+type Unsubscribe struct {
+	channels []string
+	session  Session
+}
+
+func (c *Unsubscribe) Apply(_ *db.Cache, dest ReplyWriter) {
+	channels, counts := c.session.Unsubscribe(c.channels...)
+	replySubscriptions(dest, "unsubscribe", channels, counts)
+}
+
+func (c *Unsubscribe) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	c.channels = args
+	return nil
+}
+
+func (c *Unsubscribe) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Unsubscribe) Name() string {
+	return "unsubscribe"
+}
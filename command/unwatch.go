@@ -0,0 +1,45 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "unwatch",
+		Arity:      1,
+		Flags:      []string{FlagFast},
+		Categories: []string{CategoryFast, CategoryTransaction},
+		Summary:    "Forgets about watched keys of a transaction.",
+		Group:      "transactions",
+		Since:      "2.2.0",
+		New:        func() Command { return new(Unwatch) },
+	})
+}
+
+// Unwatch forgets all the keys watched by the connection.
+type Unwatch struct {
+	session Session
+}
+
+func (c *Unwatch) Apply(_ *db.Cache, dest ReplyWriter) {
+	c.session.Unwatch()
+	dest.WriteOK()
+}
+
+func (c *Unwatch) FromFrame(f *frame.Array) error {
+	if f.Size() != 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	return nil
+}
+
+func (c *Unwatch) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Unwatch) Name() string {
+	return "unwatch"
+}
@@ -0,0 +1,57 @@
+package command
+
+import (
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+func init() {
+	Register(Spec{
+		Name:       "watch",
+		Arity:      -2,
+		Flags:      []string{FlagFast},
+		FirstKey:   1,
+		LastKey:    -1,
+		Step:       1,
+		Categories: []string{CategoryFast, CategoryTransaction},
+		Summary:    "Monitors changes to keys to determine the execution of a transaction.",
+		Group:      "transactions",
+		Since:      "2.2.0",
+		New:        func() Command { return new(Watch) },
+	})
+}
+
+// Watch marks keys to be monitored for the next transaction. EXEC fails if one of them is modified before.
+type Watch struct {
+	keys    []string
+	session Session
+}
+
+func (c *Watch) Apply(_ *db.Cache, dest ReplyWriter) {
+	if err := c.session.Watch(c.keys...); err != nil {
+		dest.WriteError(err)
+		return
+	}
+	dest.WriteOK()
+}
+
+func (c *Watch) FromFrame(f *frame.Array) error {
+	args, err := argsFromFrame(f)
+	if err != nil {
+		return err
+	}
+	if len(args) < 1 {
+		return gerror.ErrInvalidCmdArgs
+	}
+	c.keys = args
+	return nil
+}
+
+func (c *Watch) BindSession(s Session) {
+	c.session = s
+}
+
+func (c *Watch) Name() string {
+	return "watch"
+}
@@ -1,13 +1,20 @@
 package db
 
 import (
+	"github.com/ynachi/gcache/gerror"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 type Entry struct {
 	key   string
 	value string
+	// expireAt is the time after which the entry is considered gone. The zero value means it never expires.
+	expireAt time.Time
+	// access holds 24 bits of access metadata: the time of the last access, or an access counter
+	// when a sampled LFU policy is used.
+	access uint32
 }
 
 func NewEntry(key string, value string) *Entry {
@@ -19,13 +26,29 @@ func NewEntry(key string, value string) *Entry {
 // Later on, we could average RWLocks and fine-grained locking strategy.
 // This means that there is no need for mutexes at the eviction struct side.
 type Cache struct {
-	mu          sync.Mutex
-	maxItems    int64
-	currentSize atomic.Int64
-	storage     map[string]*Entry
-	eviction    Eviction
+	mu           sync.Mutex
+	maxItems     int64
+	currentSize  atomic.Int64
+	storage      map[string]*Entry
+	eviction     Eviction
+	evictionName string
+
+	// versions tracks modifications of watched keys only, with the number of watchers of each key.
+	// Keys nobody watches are not versioned, so that the map does not grow with the keyspace.
+	versions map[string]uint64
+	watchers map[string]int
+
+	// expires indexes the entries having a time to live, so that expiration does not need to scan the whole storage.
+	expires  map[string]*Entry
+	notifier Notifier
+	clock    func() time.Time
+	tunables *Tunables
 }
 
+// crossMu serializes operations spanning two caches, like Move.
+// Taking it before locking both caches avoids deadlocks between concurrent moves in opposite directions.
+var crossMu sync.Mutex
+
 // Size returns the current size of the cache.
 // This size is not computed to reduce costs on system calls due to locking/unlocking mutexes.
 // We use an atomic variable instead.
@@ -46,34 +69,45 @@ func (c *Cache) decrement() {
 func (c *Cache) Get(key string) (string, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	e, ok := c.storage[key]
+	e, ok := c.lookup(key)
 	if ok {
+		c.recordAccess(e)
 		c.eviction.Refresh(e.key)
 		return e.value, ok
 	}
+	c.notify(EventKeyMiss, "keymiss", key)
 	return "", false
 }
 
-func (c *Cache) Set(key string, value string) {
+// Set stores a value without expiration. A time to live previously set on the key is discarded.
+func (c *Cache) Set(key string, value string) error {
+	return c.SetWithTTL(key, value, 0)
+}
+
+// SetWithTTL stores a value which expires after the given duration. A zero ttl means no expiration.
+// It returns gerror.ErrOOM if the key is new and the cache is full without anything the policy can evict.
+func (c *Cache) SetWithTTL(key string, value string, ttl time.Duration) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	e, ok := c.storage[key]
+	e, ok := c.lookup(key)
 	if ok {
 		e.value = value
+		c.recordAccess(e)
 		c.eviction.Refresh(e.key)
 	} else {
-		if c.Size() > c.maxItems {
-			evictKey := c.eviction.Evict()
-			if evictKey != "" {
-				delete(c.storage, evictKey)
-				c.decrement()
-			}
+		if err := c.makeRoom(); err != nil {
+			return err
 		}
-		c.storage[key] = NewEntry(key, value)
-		c.eviction.Add(key)
-		c.increment()
+		e = NewEntry(key, value)
+		c.initAccess(e)
+		c.add(e)
+		c.notify(EventNewKey, "new", key)
 	}
+	c.setExpiration(e, ttl)
+	c.touch(key)
+	c.notify(EventString, "set", key)
+	return nil
 }
 
 // Delete delete keys and return the number of removed keys
@@ -82,26 +116,328 @@ func (c *Cache) Delete(keys ...string) int {
 	defer c.mu.Unlock()
 	deletedKeys := 0
 	for _, key := range keys {
-		e, ok := c.storage[key]
+		e, ok := c.lookup(key)
 		if ok {
-			delete(c.storage, key)
-			c.decrement()
-			c.eviction.Delete(e.key)
+			c.remove(e)
+			c.notify(EventGeneric, "del", key)
 			deletedKeys += 1
 		}
 	}
 	return deletedKeys
 }
 
+// Exists tells if a key is present in the cache. It does not refresh the key.
+func (c *Cache) Exists(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.lookup(key)
+	return ok
+}
+
+// Expire sets the time to live of a key. It returns false if the key does not exist.
+// A ttl which is not positive deletes the key right away.
+func (c *Cache) Expire(key string, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return false
+	}
+	if ttl <= 0 {
+		c.remove(e)
+		c.notify(EventGeneric, "del", key)
+		return true
+	}
+	c.setExpiration(e, ttl)
+	c.touch(key)
+	c.notify(EventGeneric, "expire", key)
+	return true
+}
+
+// Persist removes the time to live of a key. It returns false if the key does not exist or has no time to live.
+func (c *Cache) Persist(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok || e.expireAt.IsZero() {
+		return false
+	}
+	c.setExpiration(e, 0)
+	c.touch(key)
+	c.notify(EventGeneric, "persist", key)
+	return true
+}
+
+// TTL returns the remaining time to live of a key. It returns false if the key does not exist,
+// and a negative duration if the key exists but never expires.
+func (c *Cache) TTL(key string) (time.Duration, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	if e.expireAt.IsZero() {
+		return -1, true
+	}
+	return e.expireAt.Sub(c.clock()), true
+}
+
+// ActiveExpire removes up to samples expired keys among the ones having a time to live.
+// It returns the number of keys removed. It complements the lazy expiration happening on access,
+// which alone would keep the keys that are never accessed again.
+func (c *Cache) ActiveExpire(samples int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock()
+	expired := 0
+	// map iteration starts at a random position, so successive calls sample different keys
+	for _, e := range c.expires {
+		if samples == 0 {
+			break
+		}
+		samples--
+		if now.After(e.expireAt) {
+			c.expire(e)
+			expired++
+		}
+	}
+	return expired
+}
+
+// Flush removes all the keys from the cache.
+// The storage and the eviction policy are detached and replaced in constant time,
+// the memory they hold is then reclaimed by the garbage collector.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the policy name was validated when the cache was created
+	evictionPolicy, _ := c.newEviction(c.evictionName)
+	c.storage = make(map[string]*Entry)
+	c.expires = make(map[string]*Entry)
+	c.eviction = evictionPolicy
+	c.currentSize.Store(0)
+	for key := range c.watchers {
+		c.versions[key]++
+	}
+}
+
+// IdleTime returns the time elapsed since the last access of a key, with a precision of one second.
+// It returns false if the key does not exist, and gerror.ErrLFUSelected if the entries hold access counters instead.
+// Reading the idle time does not count as an access.
+func (c *Cache) IdleTime(key string) (time.Duration, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return 0, false, nil
+	}
+	if c.usesLFU() {
+		return 0, true, gerror.ErrLFUSelected
+	}
+	return idleTime(e, c.clock()), true, nil
+}
+
+// Frequency returns the access frequency of a key, the one HotKeys ranks the keys by: the logarithmic access counter
+// with the sampled LFU policy, or the frequency tracked by the eviction policy. It is 0 for the keys a volatile policy
+// does not track. It returns false if the key does not exist, and gerror.ErrLFUNotSelected if the eviction policy
+// does not track the access frequency of the keys. Reading the frequency does not count as an access.
+func (c *Cache) Frequency(key string) (int, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return 0, false, nil
+	}
+	frequency := c.frequencyReader()
+	if frequency == nil {
+		return 0, true, gerror.ErrLFUNotSelected
+	}
+	freq, _ := frequency(e)
+	return freq, true, nil
+}
+
+// SetTunables sets the settings of the sampled eviction policies.
+func (c *Cache) SetTunables(tunables *Tunables) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tunables = tunables
+}
+
+// Move moves a key to another cache. It returns false if the key does not exist in this cache
+// or if it already exists in the destination, in which case nothing is changed.
+func (c *Cache) Move(key string, dst *Cache) bool {
+	if c == dst {
+		return false
+	}
+	crossMu.Lock()
+	defer crossMu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dst.mu.Lock()
+	defer dst.mu.Unlock()
+
+	e, ok := c.lookup(key)
+	if !ok {
+		return false
+	}
+	if _, ok = dst.lookup(key); ok {
+		return false
+	}
+	c.remove(e)
+	c.notify(EventGeneric, "move_from", key)
+	// like in Redis, moving a key is not denied when the destination is full and nothing can be evicted
+	_ = dst.makeRoom()
+	dst.add(e)
+	dst.setExpireAt(e, e.expireAt)
+	dst.touch(key)
+	dst.notify(EventGeneric, "move_to", key)
+	return true
+}
+
+// Watch starts versioning a key and returns its current version.
+// Each call should be balanced by a call to Unwatch once the caller is no longer interested in the key.
+func (c *Cache) Watch(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.watchers[key]++
+	return c.versions[key]
+}
+
+// Unwatch releases a key previously watched. The key is no longer versioned once all its watchers are gone.
+func (c *Cache) Unwatch(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchers[key] <= 1 {
+		delete(c.watchers, key)
+		delete(c.versions, key)
+		return
+	}
+	c.watchers[key]--
+}
+
+// Version returns the version of a watched key. It changes each time the key is modified, deleted or evicted.
+func (c *Cache) Version(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.versions[key]
+}
+
+// touch bumps the version of a key if it is watched. The caller must hold the lock.
+func (c *Cache) touch(key string) {
+	if _, ok := c.watchers[key]; ok {
+		c.versions[key]++
+	}
+}
+
+// makeRoom evicts a key if the cache is full. It returns gerror.ErrOOM if the policy has nothing to evict.
+// The caller must hold the lock.
+func (c *Cache) makeRoom() error {
+	if c.Size() <= c.maxItems {
+		return nil
+	}
+	evictKey := c.eviction.Evict()
+	if evictKey == "" {
+		return gerror.ErrOOM
+	}
+	if evicted, ok := c.storage[evictKey]; ok {
+		// the policy already forgot the key
+		c.unlink(evicted)
+		c.notify(EventEvicted, "evicted", evictKey)
+	}
+	return nil
+}
+
+// add stores a new entry. The caller must hold the lock and make room for it first.
+func (c *Cache) add(e *Entry) {
+	c.storage[e.key] = e
+	c.eviction.Add(e.key)
+	c.increment()
+}
+
+// lookup returns the entry of a key, expiring it first if its time to live elapsed. The caller must hold the lock.
+func (c *Cache) lookup(key string) (*Entry, bool) {
+	e, ok := c.storage[key]
+	if !ok {
+		return nil, false
+	}
+	if !e.expireAt.IsZero() && c.clock().After(e.expireAt) {
+		c.expire(e)
+		return nil, false
+	}
+	return e, true
+}
+
+// expire removes an entry whose time to live elapsed. The caller must hold the lock.
+func (c *Cache) expire(e *Entry) {
+	c.remove(e)
+	c.notify(EventExpired, "expired", e.key)
+}
+
+// remove deletes an entry from the storage and from the eviction policy. The caller must hold the lock.
+func (c *Cache) remove(e *Entry) {
+	c.eviction.Delete(e.key)
+	c.unlink(e)
+}
+
+// unlink deletes an entry from the storage, leaving the eviction policy untouched. The caller must hold the lock.
+func (c *Cache) unlink(e *Entry) {
+	delete(c.storage, e.key)
+	delete(c.expires, e.key)
+	c.decrement()
+	c.touch(e.key)
+}
+
+// setExpiration sets the time to live of an entry, a zero ttl removing it. The caller must hold the lock.
+func (c *Cache) setExpiration(e *Entry, ttl time.Duration) {
+	if ttl == 0 {
+		c.setExpireAt(e, time.Time{})
+		return
+	}
+	c.setExpireAt(e, c.clock().Add(ttl))
+}
+
+// setExpireAt sets the expiration time of an entry, the zero time removing it. The caller must hold the lock.
+func (c *Cache) setExpireAt(e *Entry, expireAt time.Time) {
+	e.expireAt = expireAt
+	if expireAt.IsZero() {
+		delete(c.expires, e.key)
+	} else {
+		c.expires[e.key] = e
+	}
+	if aware, ok := c.eviction.(ExpirationAware); ok {
+		aware.SetExpiration(e.key, expireAt)
+	}
+}
+
+// newEviction creates an eviction policy for the cache, binding it to the cache if it samples its storage.
+func (c *Cache) newEviction(evictionPolicyType string) (Eviction, error) {
+	evictionPolicy, err := CreateEvictionPolicy(evictionPolicyType, c.maxItems)
+	if err != nil {
+		return nil, err
+	}
+	if sampled, ok := evictionPolicy.(*Sampled); ok {
+		sampled.cache = c
+	}
+	return evictionPolicy, nil
+}
+
 func NewCache(maxItem int64, evictionPolicyType string) (*Cache, error) {
-	evictionPolicy, err := CreateEvictionPolicy(evictionPolicyType)
+	c := &Cache{
+		maxItems:     maxItem,
+		storage:      make(map[string]*Entry),
+		currentSize:  atomic.Int64{},
+		evictionName: evictionPolicyType,
+		versions:     make(map[string]uint64),
+		watchers:     make(map[string]int),
+		expires:      make(map[string]*Entry),
+		clock:        time.Now,
+		tunables:     NewTunables(),
+	}
+	evictionPolicy, err := c.newEviction(evictionPolicyType)
 	if err != nil {
 		return nil, err
 	}
-	return &Cache{
-		maxItems:    maxItem,
-		storage:     make(map[string]*Entry),
-		currentSize: atomic.Int64{},
-		eviction:    evictionPolicy,
-	}, nil
+	c.eviction = evictionPolicy
+	return c, nil
 }
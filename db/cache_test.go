@@ -0,0 +1,156 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recordingNotifier keeps the events it receives as "event:key" strings.
+type recordingNotifier struct {
+	events []string
+}
+
+func (n *recordingNotifier) Notify(_ EventClass, event string, key string) {
+	n.events = append(n.events, event+":"+key)
+}
+
+// newTestCache creates a cache whose clock is controlled by the test.
+func newTestCache(t *testing.T, maxItems int64) (*Cache, *time.Time, *recordingNotifier) {
+	c, err := NewCache(maxItems, "LRU")
+	if err != nil {
+		t.Fatalf("unable to create cache: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	notifier := &recordingNotifier{}
+	c.SetNotifier(notifier)
+	return c, &now, notifier
+}
+
+func TestCache_Expiration(t *testing.T) {
+	c, now, notifier := newTestCache(t, 10)
+	c.SetWithTTL("volatile", "value", 10*time.Second)
+	c.Set("persistent", "value")
+
+	ttl, ok := c.TTL("volatile")
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, ttl)
+	ttl, ok = c.TTL("persistent")
+	assert.True(t, ok)
+	assert.Negative(t, ttl)
+
+	*now = now.Add(11 * time.Second)
+	_, ok = c.Get("volatile")
+	assert.False(t, ok)
+	assert.Equal(t, int64(1), c.Size())
+	assert.Contains(t, notifier.events, "expired:volatile")
+
+	// setting a key again discards its time to live
+	c.SetWithTTL("volatile", "value", time.Second)
+	c.Set("volatile", "value")
+	assert.False(t, c.Persist("volatile"))
+	*now = now.Add(2 * time.Second)
+	assert.True(t, c.Exists("volatile"))
+}
+
+func TestCache_ActiveExpire(t *testing.T) {
+	c, now, notifier := newTestCache(t, 10)
+	c.SetWithTTL("first", "value", time.Second)
+	c.SetWithTTL("second", "value", time.Minute)
+	c.Set("third", "value")
+
+	assert.Equal(t, 0, c.ActiveExpire(10))
+	*now = now.Add(2 * time.Second)
+	assert.Equal(t, 1, c.ActiveExpire(10))
+	assert.Equal(t, int64(2), c.Size())
+	assert.Equal(t, "expired:first", notifier.events[len(notifier.events)-1])
+}
+
+func TestCache_Events(t *testing.T) {
+	c, _, notifier := newTestCache(t, 1)
+	c.Set("first", "value")
+	c.Set("first", "other")
+	c.Get("missing")
+	c.Expire("first", time.Minute)
+	c.Persist("first")
+	c.Set("second", "value")
+	// the cache holds more than maxItems keys, so the least recently used one is evicted
+	c.Set("third", "value")
+	c.Delete("second")
+
+	assert.Equal(t, []string{
+		"new:first", "set:first",
+		"set:first",
+		"keymiss:missing",
+		"expire:first",
+		"persist:first",
+		"new:second", "set:second",
+		"evicted:first", "new:third", "set:third",
+		"del:second",
+	}, notifier.events)
+}
+
+func TestCache_Watch(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	version := c.Watch("watched")
+	c.Set("other", "value")
+	assert.Equal(t, version, c.Version("watched"))
+	c.Set("watched", "value")
+	assert.NotEqual(t, version, c.Version("watched"))
+
+	c.Unwatch("watched")
+	assert.Empty(t, c.watchers)
+	assert.Empty(t, c.versions)
+}
+
+func TestCache_MaxMemoryPolicies(t *testing.T) {
+	tests := []struct {
+		policy string
+		// wantKeys are the keys left once volatile and persistent keys were added to a full cache
+		wantKeys []string
+		wantErr  error
+	}{
+		{policy: "noeviction", wantKeys: []string{"persistent", "short", "long"}, wantErr: gerror.ErrOOM},
+		{policy: "allkeys-lru", wantKeys: []string{"persistent", "short", "new"}},
+		{policy: "volatile-lru", wantKeys: []string{"persistent", "short", "new"}},
+		{policy: "volatile-ttl", wantKeys: []string{"persistent", "long", "new"}},
+		{policy: "volatile-random"},
+		{policy: "allkeys-random"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			c, err := NewCache(2, tt.policy)
+			assert.NoError(t, err)
+			assert.NoError(t, c.Set("persistent", "value"))
+			assert.NoError(t, c.SetWithTTL("short", "value", time.Minute))
+			assert.NoError(t, c.SetWithTTL("long", "value", time.Hour))
+			// the long lived key is the least recently used, which does not matter to volatile-ttl
+			c.Get("short")
+			c.Get("persistent")
+
+			assert.ErrorIs(t, c.Set("new", "value"), tt.wantErr)
+			assert.Equal(t, int64(3), c.Size())
+			for _, key := range tt.wantKeys {
+				assert.True(t, c.Exists(key), key)
+			}
+			if strings.HasPrefix(tt.policy, "volatile") {
+				assert.True(t, c.Exists("persistent"))
+			}
+		})
+	}
+}
+
+func TestCache_VolatileWithoutExpiration(t *testing.T) {
+	c, err := NewCache(1, "volatile-lru")
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetWithTTL("first", "value", time.Minute))
+	assert.NoError(t, c.Set("second", "value"))
+	// the key no longer has a time to live, so nothing can be evicted
+	assert.True(t, c.Persist("first"))
+	assert.ErrorIs(t, c.Set("third", "value"), gerror.ErrOOM)
+	// overwriting a key does not need room
+	assert.NoError(t, c.Set("first", "other"))
+}
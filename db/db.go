@@ -15,6 +15,7 @@ import (
 	"hash/fnv"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Eviction defines how entries are evicted.
@@ -34,13 +35,55 @@ type Eviction interface {
 	Delete(key string)
 }
 
+// ExpirationAware is implemented by the eviction policies which need to know the time to live of the keys,
+// like the volatile ones which only evict keys having one.
+type ExpirationAware interface {
+	// SetExpiration is called when the expiration time of a key changes, the zero time meaning it has none.
+	SetExpiration(key string, expireAt time.Time)
+}
+
+// EvictionPolicies returns the names of the eviction policies CreateEvictionPolicy knows, aliases excluded.
+func EvictionPolicies() []string {
+	return []string{
+		"lru", "lfu", "tinylfu", "arc", "s3fifo", "clock", "sampled-lru", "sampled-lfu",
+		"allkeys-random", "noeviction", "volatile-lru", "volatile-lfu", "volatile-random", "volatile-ttl",
+	}
+}
+
 // CreateEvictionPolicy is a factory method for eviction policies.
-func CreateEvictionPolicy(evictionType string) (Eviction, error) {
+// capacity is the number of keys the cache holds, which some policies use to size their structures.
+// Besides the policy names, the maxmemory-policy values of Redis are supported. A policy which does not find
+// anything to evict, like noeviction or a volatile one without keys having a time to live, makes writes fail.
+func CreateEvictionPolicy(evictionType string, capacity int64) (Eviction, error) {
 	switch strings.ToLower(evictionType) {
-	case "lfu":
+	case "lfu", "allkeys-lfu":
 		return policy.NewLFU(), nil
-	case "lru":
+	case "lru", "allkeys-lru":
 		return policy.NewLRU(), nil
+	case "allkeys-random":
+		return policy.NewRandom(), nil
+	case "noeviction":
+		return policy.NewNoEviction(), nil
+	case "volatile-lru":
+		return policy.NewVolatile(policy.NewLRU()), nil
+	case "volatile-lfu":
+		return policy.NewVolatile(policy.NewLFU()), nil
+	case "volatile-random":
+		return policy.NewVolatile(policy.NewRandom()), nil
+	case "volatile-ttl":
+		return policy.NewTTL(), nil
+	case "sampled-lru":
+		return newSampled(false), nil
+	case "sampled-lfu":
+		return newSampled(true), nil
+	case "tinylfu", "w-tinylfu":
+		return policy.NewTinyLFU(capacity), nil
+	case "arc":
+		return policy.NewARC(capacity), nil
+	case "s3fifo", "s3-fifo":
+		return policy.NewS3FIFO(capacity), nil
+	case "clock":
+		return policy.NewClock(), nil
 	default:
 		return nil, gerror.ErrEvictionPolicyNotFound
 	}
@@ -0,0 +1,19 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"testing"
+)
+
+func TestCreateEvictionPolicy(t *testing.T) {
+	for _, name := range EvictionPolicies() {
+		t.Run(name, func(t *testing.T) {
+			p, err := CreateEvictionPolicy(name, 10)
+			assert.NoError(t, err)
+			assert.NotNil(t, p)
+		})
+	}
+	_, err := CreateEvictionPolicy("unknown", 10)
+	assert.ErrorIs(t, err, gerror.ErrEvictionPolicyNotFound)
+}
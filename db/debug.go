@@ -0,0 +1,181 @@
+package db
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Encodings of the values reported by OBJECT ENCODING, the ones Redis uses for strings.
+const (
+	EncodingInt    = "int"
+	EncodingEmbStr = "embstr"
+	EncodingRaw    = "raw"
+)
+
+// embStrMaxLen is the length up to which Redis embeds a string in its object.
+const embStrMaxLen = 44
+
+// DigestSize is the number of bytes of the digests of the values and of the caches.
+const DigestSize = sha1.Size
+
+// encoding returns the encoding Redis would use for a string value.
+func encoding(value string) string {
+	if len(value) <= 20 {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(n, 10) == value {
+			return EncodingInt
+		}
+	}
+	if len(value) <= embStrMaxLen {
+		return EncodingEmbStr
+	}
+	return EncodingRaw
+}
+
+// ObjectInfo describes the internals of a key, for OBJECT and DEBUG OBJECT.
+type ObjectInfo struct {
+	Encoding string
+	// SerializedLength is the number of bytes of the value.
+	SerializedLength int64
+	// Access is the raw access metadata of the entry: the access clock in LRU mode, the counter and the time of its
+	// last decrement in LFU mode.
+	Access uint32
+	// LFU tells if the entry holds an access counter, in which case Freq is set instead of Idle.
+	LFU  bool
+	Idle time.Duration
+	Freq int
+}
+
+// Object returns the internals of a key. It returns false if the key does not exist.
+// Inspecting a key does not count as an access.
+func (c *Cache) Object(key string) (ObjectInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return ObjectInfo{}, false
+	}
+	info := ObjectInfo{
+		Encoding:         encoding(e.value),
+		SerializedLength: valueSize(e.value),
+		Access:           e.access,
+		LFU:              c.usesLFU(),
+	}
+	now := c.clock()
+	if info.LFU {
+		info.Freq = int(c.lfuDecay(e, now))
+	} else {
+		info.Idle = idleTime(e, now)
+	}
+	return info, true
+}
+
+// entryDigest returns the digest of an entry, made of its key, value and expiration time.
+func entryDigest(e *Entry) [DigestSize]byte {
+	h := sha1.New()
+	h.Write([]byte(e.key))
+	h.Write([]byte{0})
+	h.Write([]byte(e.value))
+	expireAt := int64(-1)
+	if !e.expireAt.IsZero() {
+		expireAt = e.expireAt.UnixMilli()
+	}
+	_ = binary.Write(h, binary.BigEndian, expireAt)
+	var digest [DigestSize]byte
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// Digest returns a digest of the keys, values and expiration times of the cache, all zeroes if it is empty.
+// The digests of the entries are combined with XOR, so that it does not depend on the order of the keys: two caches
+// holding the same data have the same digest.
+func (c *Cache) Digest() [DigestSize]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var digest [DigestSize]byte
+	for _, e := range c.storage {
+		if !e.expireAt.IsZero() && c.clock().After(e.expireAt) {
+			continue
+		}
+		entry := entryDigest(e)
+		for i := range digest {
+			digest[i] ^= entry[i]
+		}
+	}
+	return digest
+}
+
+// DigestValue returns the digest of a value, all zeroes if the key does not exist.
+func (c *Cache) DigestValue(key string) [DigestSize]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return [DigestSize]byte{}
+	}
+	return sha1.Sum([]byte(e.value))
+}
+
+// Reload rebuilds the storage and the eviction policy from the entries of the cache, like restarting the server
+// with the same data would: expired keys are dropped and the access metadata of the keys starts over.
+// Watched keys are considered modified.
+func (c *Cache) Reload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock()
+	entries := make([]*Entry, 0, len(c.storage))
+	for _, e := range c.storage {
+		if e.expireAt.IsZero() || !now.After(e.expireAt) {
+			entries = append(entries, e)
+		}
+	}
+	// the policy name was validated when the cache was created
+	evictionPolicy, _ := c.newEviction(c.evictionName)
+	c.storage = make(map[string]*Entry, len(entries))
+	c.expires = make(map[string]*Entry)
+	c.eviction = evictionPolicy
+	c.currentSize.Store(0)
+	for _, old := range entries {
+		e := NewEntry(old.key, old.value)
+		c.initAccess(e)
+		c.add(e)
+		c.setExpireAt(e, old.expireAt)
+	}
+	for key := range c.watchers {
+		c.versions[key]++
+	}
+}
+
+// Populate creates count keys named prefix:N, N going from 0 to count-1, holding value:N, or a value of size bytes
+// made of it padded with zeroes or truncated if size is positive. Existing keys are left alone.
+// It returns the number of keys created, and gerror.ErrOOM if the cache got full without anything to evict.
+func (c *Cache) Populate(count int, prefix string, size int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	created := 0
+	for i := 0; i < count; i++ {
+		key := fmt.Sprintf("%s:%d", prefix, i)
+		if _, ok := c.lookup(key); ok {
+			continue
+		}
+		value := fmt.Sprintf("value:%d", i)
+		if size > 0 {
+			if len(value) < size {
+				value += strings.Repeat("\x00", size-len(value))
+			}
+			value = value[:size]
+		}
+		if err := c.makeRoom(); err != nil {
+			return created, err
+		}
+		e := NewEntry(key, value)
+		c.initAccess(e)
+		c.add(e)
+		c.touch(key)
+		created++
+	}
+	return created, nil
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncoding(t *testing.T) {
+	assert.Equal(t, EncodingInt, encoding("12345"))
+	assert.Equal(t, EncodingInt, encoding("-7"))
+	assert.Equal(t, EncodingEmbStr, encoding("0123"), "integers are only encoded as such in their canonical form")
+	assert.Equal(t, EncodingEmbStr, encoding(strings.Repeat("a", embStrMaxLen)))
+	assert.Equal(t, EncodingRaw, encoding(strings.Repeat("a", embStrMaxLen+1)))
+}
+
+func TestCache_Object(t *testing.T) {
+	c, now, _ := newTestCache(t, 10)
+	c.Set("key", "value")
+	*now = now.Add(5 * time.Second)
+
+	info, ok := c.Object("key")
+	assert.True(t, ok)
+	assert.Equal(t, EncodingEmbStr, info.Encoding)
+	assert.Equal(t, int64(5), info.SerializedLength)
+	assert.False(t, info.LFU)
+	assert.Equal(t, 5*time.Second, info.Idle)
+	_, ok = c.Object("missing")
+	assert.False(t, ok)
+
+	lfu, _ := newSampledCache(t, 10, "sampled-lfu")
+	lfu.Set("key", "value")
+	info, _ = lfu.Object("key")
+	assert.True(t, info.LFU)
+	assert.Equal(t, lfuInitVal, info.Freq)
+}
+
+func TestCache_Digest(t *testing.T) {
+	first, _, _ := newTestCache(t, 10)
+	second, _, _ := newTestCache(t, 10)
+	assert.Equal(t, [DigestSize]byte{}, first.Digest(), "the digest of an empty cache should be all zeroes")
+
+	first.Set("a", "1")
+	first.Set("b", "2")
+	second.Set("b", "2")
+	second.Set("a", "1")
+	assert.Equal(t, first.Digest(), second.Digest(), "the digest should not depend on the order of the keys")
+	assert.NotEqual(t, [DigestSize]byte{}, first.Digest())
+
+	second.Expire("a", time.Minute)
+	assert.NotEqual(t, first.Digest(), second.Digest(), "the expiration times should be part of the digest")
+
+	assert.Equal(t, first.DigestValue("a"), second.DigestValue("a"))
+	assert.NotEqual(t, first.DigestValue("a"), first.DigestValue("b"))
+	assert.Equal(t, [DigestSize]byte{}, first.DigestValue("missing"))
+}
+
+func TestCache_Reload(t *testing.T) {
+	c, now, _ := newTestCache(t, 10)
+	c.Set("persistent", "value")
+	c.SetWithTTL("volatile", "value", time.Minute)
+	c.SetWithTTL("expired", "value", time.Second)
+	version := c.Watch("persistent")
+	*now = now.Add(2 * time.Second)
+	digest := c.Digest()
+
+	c.Reload()
+	assert.Equal(t, int64(2), c.Size(), "expired keys should be dropped")
+	assert.Equal(t, digest, c.Digest(), "the data should survive a reload")
+	ttl, _ := c.TTL("volatile")
+	assert.Equal(t, 58*time.Second, ttl)
+	info, _ := c.Object("persistent")
+	assert.Zero(t, info.Idle, "the access metadata should start over")
+	assert.NotEqual(t, version, c.Version("persistent"))
+	// the eviction policy should know the keys again, and evict them to stay at its capacity
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, c.Set(fmt.Sprint(i), "value"))
+	}
+	fresh, _, _ := newTestCache(t, 10)
+	for i := 0; i < 12; i++ {
+		fresh.Set(fmt.Sprint(i), "value")
+	}
+	assert.Equal(t, fresh.Size(), c.Size())
+}
+
+func TestCache_Populate(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	c.Set("key:1", "mine")
+
+	created, err := c.Populate(3, "key", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, created, "existing keys should be left alone")
+	value, _ := c.Get("key:2")
+	assert.Equal(t, "value:2", value)
+	value, _ = c.Get("key:1")
+	assert.Equal(t, "mine", value)
+
+	c.Populate(2, "padded", 10)
+	value, _ = c.Get("padded:0")
+	assert.Equal(t, "value:0\x00\x00\x00", value)
+	c.Populate(1, "short", 3)
+	value, _ = c.Get("short:0")
+	assert.Equal(t, "val", value)
+
+	full, err := NewCache(2, "noeviction")
+	assert.NoError(t, err)
+	created, err = full.Populate(5, "key", 0)
+	assert.ErrorIs(t, err, gerror.ErrOOM)
+	assert.Equal(t, 3, created)
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"github.com/ynachi/gcache/db/policy"
+	"github.com/ynachi/gcache/gerror"
+	"sort"
+	"unsafe"
+)
+
+// TypeString is the type of the values held by the cache, which only stores strings for now.
+const TypeString = "string"
+
+// entrySize is the size of an entry, without the bytes of its key and value.
+var entrySize = int64(unsafe.Sizeof(Entry{}))
+
+// MemoryReporter is implemented by the eviction policies able to estimate the memory they use.
+type MemoryReporter interface {
+	// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+	MemoryUsage() int64
+}
+
+// FrequencyReporter is implemented by the eviction policies tracking how often the keys are accessed.
+type FrequencyReporter interface {
+	// Frequency returns the access frequency of a key, and false if the policy does not track it.
+	Frequency(key string) (int, bool)
+}
+
+// MemoryStats is an estimation of the memory used by a cache. Allocations made by the runtime are not counted.
+type MemoryStats struct {
+	Keys int64
+	// Volatile is the number of keys having a time to live.
+	Volatile int64
+	// Dataset is the number of bytes of the keys, the values and the entries holding them.
+	Dataset int64
+	// Storage and Expires are the overhead of the maps indexing the keys and the keys having a time to live.
+	Storage int64
+	Expires int64
+	// Eviction is the memory used by the eviction policy to track the keys.
+	Eviction int64
+}
+
+// Overhead returns the memory used by the cache besides the dataset.
+func (s MemoryStats) Overhead() int64 {
+	return s.Storage + s.Expires + s.Eviction
+}
+
+// KeyStat describes a key found by BigKeys or HotKeys.
+type KeyStat struct {
+	Key  string
+	Type string
+	// Size is the estimated memory used by the key, as returned by MemoryUsage.
+	Size int64
+	// Frequency is the access frequency of the key, for HotKeys only.
+	Frequency int
+}
+
+// valueSize returns the number of bytes of a value, which depends on its type.
+func valueSize(value string) int64 {
+	return int64(len(value))
+}
+
+// memoryUsage returns the estimated memory used by an entry, its slots in the maps of the cache included.
+// The caller must hold the lock.
+func (c *Cache) memoryUsage(e *Entry) int64 {
+	size := entrySize + int64(len(e.key)) + valueSize(e.value) + policy.MapSlotOverhead
+	if !e.expireAt.IsZero() {
+		size += policy.MapSlotOverhead
+	}
+	return size
+}
+
+// MemoryUsage returns the estimated number of bytes used by a key and its value. It returns false if the key does
+// not exist. Reading the usage does not count as an access.
+func (c *Cache) MemoryUsage(key string) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.lookup(key)
+	if !ok {
+		return 0, false
+	}
+	return c.memoryUsage(e), true
+}
+
+// scanBatchSize is the number of keys scan visits before releasing the lock, so that going through the whole
+// keyspace does not block the other clients for long.
+const scanBatchSize = 1024
+
+// scan calls fn with the entries of the cache by batches of scanBatchSize, holding the lock during each call but
+// releasing it between them. The keys are listed when the scan starts: the ones added later are not visited, and
+// the ones removed in between are skipped.
+func (c *Cache) scan(fn func(entries []*Entry)) {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.storage))
+	for key := range c.storage {
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+	entries := make([]*Entry, 0, min(len(keys), scanBatchSize))
+	for start := 0; start < len(keys); start += scanBatchSize {
+		c.mu.Lock()
+		entries = entries[:0]
+		for _, key := range keys[start:min(start+scanBatchSize, len(keys))] {
+			if e, ok := c.storage[key]; ok {
+				entries = append(entries, e)
+			}
+		}
+		fn(entries)
+		c.mu.Unlock()
+	}
+}
+
+// MemoryStats estimates the memory used by the cache. The keys are counted and measured by the same scan, so the
+// figures describe the keys it visited, which may mix states of the keyspace if it is modified meanwhile.
+func (c *Cache) MemoryStats() MemoryStats {
+	var stats MemoryStats
+	c.scan(func(entries []*Entry) {
+		for _, e := range entries {
+			stats.Keys++
+			stats.Dataset += entrySize + int64(len(e.key)) + valueSize(e.value)
+			if !e.expireAt.IsZero() {
+				stats.Volatile++
+			}
+		}
+	})
+	stats.Storage = stats.Keys * policy.MapSlotOverhead
+	stats.Expires = stats.Volatile * policy.MapSlotOverhead
+	c.mu.Lock()
+	if reporter, ok := c.eviction.(MemoryReporter); ok {
+		stats.Eviction = reporter.MemoryUsage()
+	}
+	c.mu.Unlock()
+	return stats
+}
+
+// BigKeys returns the count largest keys of each type, the largest first. It scans the keys by batches, like
+// MemoryStats and HotKeys.
+func (c *Cache) BigKeys(count int) map[string][]KeyStat {
+	biggest := make(map[string]*topKeys)
+	c.scan(func(entries []*Entry) {
+		for _, e := range entries {
+			top, ok := biggest[TypeString]
+			if !ok {
+				top = newTopKeys(count)
+				biggest[TypeString] = top
+			}
+			size := c.memoryUsage(e)
+			top.insert(KeyStat{Key: e.key, Type: TypeString, Size: size}, size)
+		}
+	})
+	keys := make(map[string][]KeyStat, len(biggest))
+	for typ, top := range biggest {
+		keys[typ] = top.sorted()
+	}
+	return keys
+}
+
+// HotKeys returns the count most frequently accessed keys, the hottest first, along with their frequency. It fails
+// with gerror.ErrLFUNotSelected if the eviction policy does not track the access frequency of the keys.
+func (c *Cache) HotKeys(count int) ([]KeyStat, error) {
+	c.mu.Lock()
+	tracked := c.frequencyReader() != nil
+	c.mu.Unlock()
+	if !tracked {
+		return nil, gerror.ErrLFUNotSelected
+	}
+	top := newTopKeys(count)
+	c.scan(func(entries []*Entry) {
+		// the policy may have been replaced, by a flush for instance, since the previous batch
+		frequency := c.frequencyReader()
+		if frequency == nil {
+			return
+		}
+		for _, e := range entries {
+			if freq, ok := frequency(e); ok {
+				top.insert(KeyStat{Key: e.key, Type: TypeString, Size: c.memoryUsage(e), Frequency: freq}, int64(freq))
+			}
+		}
+	})
+	return top.sorted(), nil
+}
+
+// frequencyReader returns how to read the access frequency of an entry, from the entry itself with the sampled LFU
+// policy, or from the eviction policy. It returns nil if the frequency is not tracked. The caller must hold the lock.
+func (c *Cache) frequencyReader() func(e *Entry) (int, bool) {
+	if c.usesLFU() {
+		now := c.clock()
+		return func(e *Entry) (int, bool) { return int(c.lfuDecay(e, now)), true }
+	}
+	var eviction any = c.eviction
+	if volatile, ok := eviction.(*policy.Volatile); ok {
+		// keys without a time to live are not tracked by the wrapped policy
+		eviction = volatile.Unwrap()
+	}
+	reporter, ok := eviction.(FrequencyReporter)
+	if !ok {
+		return nil
+	}
+	return func(e *Entry) (int, bool) { return reporter.Frequency(e.key) }
+}
+
+// MemoryUsage returns the number of bytes of the pool of eviction candidates.
+func (s *Sampled) MemoryUsage() int64 {
+	return int64(cap(s.pool)) * int64(unsafe.Sizeof(poolEntry{}))
+}
+
+// topKeys keeps the keys with the highest scores seen so far, by increasing score.
+type topKeys struct {
+	count  int
+	keys   []KeyStat
+	scores []int64
+}
+
+func newTopKeys(count int) *topKeys {
+	return &topKeys{count: count}
+}
+
+// insert adds a key if its score is among the highest ones, dropping the lowest one if there are too many.
+func (t *topKeys) insert(key KeyStat, score int64) {
+	if t.count <= 0 {
+		return
+	}
+	if len(t.keys) == t.count {
+		if score <= t.scores[0] {
+			return
+		}
+		t.keys, t.scores = t.keys[1:], t.scores[1:]
+	}
+	i := sort.Search(len(t.scores), func(i int) bool { return t.scores[i] >= score })
+	t.keys = append(t.keys, KeyStat{})
+	t.scores = append(t.scores, 0)
+	copy(t.keys[i+1:], t.keys[i:])
+	copy(t.scores[i+1:], t.scores[i:])
+	t.keys[i], t.scores[i] = key, score
+}
+
+// sorted returns the keys by decreasing score.
+func (t *topKeys) sorted() []KeyStat {
+	keys := make([]KeyStat, len(t.keys))
+	for i, key := range t.keys {
+		keys[len(keys)-1-i] = key
+	}
+	return keys
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/db/policy"
+	"github.com/ynachi/gcache/gerror"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache_MemoryUsage(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	c.Set("small", "v")
+	c.Set("large", strings.Repeat("v", 1000))
+	c.SetWithTTL("volatile", "v", time.Minute)
+
+	_, ok := c.MemoryUsage("missing")
+	assert.False(t, ok)
+	small, ok := c.MemoryUsage("small")
+	assert.True(t, ok)
+	assert.Equal(t, entrySize+int64(len("small")+1)+policy.MapSlotOverhead, small)
+	large, _ := c.MemoryUsage("large")
+	assert.Equal(t, small+999, large)
+	volatile, _ := c.MemoryUsage("volatile")
+	assert.Equal(t, small+3+policy.MapSlotOverhead, volatile, "keys with a time to live are also in the expires map")
+}
+
+func TestCache_MemoryStats(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	assert.Equal(t, MemoryStats{}, c.MemoryStats())
+
+	c.Set("key", "value")
+	c.SetWithTTL("volatile", "value", time.Minute)
+	stats := c.MemoryStats()
+	assert.Equal(t, int64(2), stats.Keys)
+	assert.Equal(t, int64(1), stats.Volatile)
+	assert.Equal(t, 2*entrySize+int64(len("keyvaluevolatilevalue")), stats.Dataset)
+	assert.Equal(t, 2*int64(policy.MapSlotOverhead), stats.Storage)
+	assert.Equal(t, int64(policy.MapSlotOverhead), stats.Expires)
+	assert.Positive(t, stats.Eviction, "the LRU policy tracks the keys")
+	assert.Equal(t, stats.Storage+stats.Expires+stats.Eviction, stats.Overhead())
+}
+
+func TestCache_BigKeys(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	for i, key := range []string{"a", "b", "c", "d"} {
+		c.Set(key, strings.Repeat("v", (i+1)*10))
+	}
+
+	keys := c.BigKeys(2)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, []string{"d", "c"}, []string{keys[TypeString][0].Key, keys[TypeString][1].Key})
+	assert.Greater(t, keys[TypeString][0].Size, keys[TypeString][1].Size)
+	assert.Empty(t, c.BigKeys(0)[TypeString])
+}
+
+func TestCache_HotKeys(t *testing.T) {
+	c, _, _ := newTestCache(t, 10)
+	_, err := c.HotKeys(2)
+	assert.ErrorIs(t, err, gerror.ErrLFUNotSelected)
+
+	for _, name := range []string{"lfu", "volatile-lfu"} {
+		c, err = NewCache(10, name)
+		assert.NoError(t, err)
+		for i, key := range []string{"cold", "warm", "hot"} {
+			c.SetWithTTL(key, "value", time.Minute)
+			for j := 0; j < i*3; j++ {
+				c.Get(key)
+			}
+		}
+		keys, err := c.HotKeys(2)
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"hot", "warm"}, []string{keys[0].Key, keys[1].Key}, name)
+		assert.Greater(t, keys[0].Frequency, keys[1].Frequency, name)
+		freq, ok, err := c.Frequency("hot")
+		assert.True(t, ok, name)
+		assert.NoError(t, err, name)
+		assert.Equal(t, keys[0].Frequency, freq, "Frequency should agree with HotKeys")
+	}
+
+	c, _ = NewCache(10, "volatile-lru")
+	_, err = c.HotKeys(2)
+	assert.ErrorIs(t, err, gerror.ErrLFUNotSelected)
+}
+
+func TestCache_HotKeysSampledLFU(t *testing.T) {
+	c, err := NewCache(10, "sampled-lfu")
+	assert.NoError(t, err)
+	c.tunables.LogFactor.Store(0)
+	c.Set("cold", "value")
+	c.Set("hot", "value")
+	for i := 0; i < 10; i++ {
+		c.Get("hot")
+	}
+	keys, err := c.HotKeys(1)
+	assert.NoError(t, err)
+	assert.Equal(t, "hot", keys[0].Key)
+}
+
+func TestCache_Scan(t *testing.T) {
+	c, err := NewCache(3*scanBatchSize, "lru")
+	assert.NoError(t, err)
+	_, err = c.Populate(2*scanBatchSize+scanBatchSize/2, "key", 0)
+	assert.NoError(t, err)
+
+	batches, visited := 0, make(map[string]bool)
+	c.scan(func(entries []*Entry) {
+		batches++
+		for _, e := range entries {
+			visited[e.key] = true
+		}
+		if batches == 2 {
+			c.mu.Unlock()
+			c.Flush()
+			c.mu.Lock()
+		}
+	})
+	assert.Equal(t, 3, batches)
+	assert.Len(t, visited, 2*scanBatchSize, "the keys removed during the scan should be skipped")
+}
@@ -0,0 +1,39 @@
+package db
+
+// EventClass categorizes the events occurring on keys, following the classes of Redis keyspace notifications.
+type EventClass int
+
+const (
+	// EventGeneric is for type-independent commands like DEL, EXPIRE or MOVE.
+	EventGeneric EventClass = 1 << iota
+	// EventString is for commands operating on string values, like SET.
+	EventString
+	// EventExpired is emitted when a key is removed because its time to live elapsed.
+	EventExpired
+	// EventEvicted is emitted when a key is removed to make room for a new one.
+	EventEvicted
+	// EventKeyMiss is emitted when a key is looked up but does not exist.
+	EventKeyMiss
+	// EventNewKey is emitted when a key is created.
+	EventNewKey
+)
+
+// Notifier receives the events occurring on the keys of a cache.
+// It is called while the cache lock is held, so it should be fast and must not call back into the cache.
+type Notifier interface {
+	Notify(class EventClass, event string, key string)
+}
+
+// SetNotifier registers the receiver of the key events of the cache. A nil notifier disables the events.
+func (c *Cache) SetNotifier(n Notifier) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.notifier = n
+}
+
+// notify forwards an event to the notifier, if any. The caller must hold the lock.
+func (c *Cache) notify(class EventClass, event string, key string) {
+	if c.notifier != nil {
+		c.notifier.Notify(class, event, key)
+	}
+}
@@ -0,0 +1,142 @@
+package policy
+
+import (
+	"container/list"
+)
+
+type arcList int
+
+const (
+	arcT1 arcList = iota
+	arcT2
+	arcB1
+	arcB2
+)
+
+type arcItem struct {
+	key  string
+	list arcList
+}
+
+// ARC implements the Adaptive Replacement Cache policy.
+// Keys seen once live in T1 and keys seen at least twice in T2, both ordered by recency. The ghost lists B1 and B2
+// remember the keys recently evicted from T1 and T2 respectively, without their values. A miss on a ghost key tells
+// which list should have been larger, and moves the target size p of T1 accordingly: the policy keeps adapting
+// between recency and frequency, and a scan only flushes T1.
+//
+// The cache asks for a victim before adding a new key, so a ghost hit adapts the target after the eviction it
+// caused instead of before, which only delays the adaptation by one miss.
+type ARC struct {
+	lists    [4]*list.List
+	lookup   map[string]*list.Element
+	capacity int
+	// p is the target size of T1.
+	p int
+}
+
+// NewARC creates an ARC policy for a cache holding up to capacity keys.
+func NewARC(capacity int64) *ARC {
+	a := &ARC{
+		lookup:   make(map[string]*list.Element),
+		capacity: boundedCapacity(capacity),
+	}
+	for i := range a.lists {
+		a.lists[i] = list.New()
+	}
+	return a
+}
+
+// Add a new element. A key found in a ghost list adapts the target size of T1 and goes straight to T2.
+func (a *ARC) Add(key string) {
+	ele, ok := a.lookup[key]
+	if !ok {
+		a.trimGhosts()
+		a.push(arcT1, &arcItem{key: key})
+		return
+	}
+
+	b1, b2 := a.lists[arcB1].Len(), a.lists[arcB2].Len()
+	item := ele.Value.(*arcItem)
+	switch item.list {
+	case arcT1, arcT2:
+		a.Refresh(key)
+		return
+	case arcB1:
+		a.p = min(a.capacity, a.p+max(b2/b1, 1))
+	case arcB2:
+		a.p = max(0, a.p-max(b1/b2, 1))
+	}
+	a.remove(ele)
+	a.push(arcT2, item)
+}
+
+// Refresh moves an existing element to the most recently used position of T2.
+func (a *ARC) Refresh(key string) {
+	ele, ok := a.lookup[key]
+	if !ok {
+		return
+	}
+	item := ele.Value.(*arcItem)
+	switch item.list {
+	case arcT1:
+		a.remove(ele)
+		a.push(arcT2, item)
+	case arcT2:
+		a.lists[arcT2].MoveToFront(ele)
+	}
+}
+
+// Evict evicts the least recently used key of T1 if T1 is larger than its target, of T2 otherwise,
+// and remembers it in the matching ghost list. It returns an empty string if the policy holds no key.
+func (a *ARC) Evict() string {
+	t1, t2 := a.lists[arcT1], a.lists[arcT2]
+	var ele *list.Element
+	var ghost arcList
+	switch {
+	case t1.Len() > 0 && (t1.Len() > a.p || t2.Len() == 0):
+		ele, ghost = t1.Back(), arcB1
+	case t2.Len() > 0:
+		ele, ghost = t2.Back(), arcB2
+	default:
+		return ""
+	}
+	item := ele.Value.(*arcItem)
+	a.remove(ele)
+	a.push(ghost, item)
+	return item.key
+}
+
+// Delete removes an element from the policy. Deleted keys are not remembered as ghosts.
+func (a *ARC) Delete(key string) {
+	if ele, ok := a.lookup[key]; ok {
+		if l := ele.Value.(*arcItem).list; l == arcT1 || l == arcT2 {
+			a.remove(ele)
+		}
+	}
+}
+
+// trimGhosts makes room for a new key in the directory, which remembers up to twice the capacity of keys
+// and up to the capacity for T1 and B1 together.
+func (a *ARC) trimGhosts() {
+	l1 := a.lists[arcT1].Len() + a.lists[arcB1].Len()
+	l2 := a.lists[arcT2].Len() + a.lists[arcB2].Len()
+	switch {
+	case l1 >= a.capacity && a.lists[arcB1].Len() > 0:
+		a.remove(a.lists[arcB1].Back())
+	case l1+l2 >= 2*a.capacity && a.lists[arcB2].Len() > 0:
+		a.remove(a.lists[arcB2].Back())
+	}
+}
+
+// push adds an item to the most recently used position of a list.
+func (a *ARC) push(to arcList, item *arcItem) {
+	item.list = to
+	a.lookup[item.key] = a.lists[to].PushFront(item)
+}
+
+// remove removes an element from its list.
+func (a *ARC) remove(ele *list.Element) {
+	item := ele.Value.(*arcItem)
+	a.lists[item.list].Remove(ele)
+	delete(a.lookup, item.key)
+}
@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestARC_HitRatio(t *testing.T) {
+	// popular keys before and after a scan of keys accessed once
+	scan := append(loopTrace("hot", 50, 10), loopTrace("scan", 500, 1)...)
+	scan = append(scan, loopTrace("hot", 50, 10)...)
+
+	tests := []struct {
+		name  string
+		trace []string
+		// wantBetter is set when ARC should strictly outperform LRU, otherwise it should match it
+		wantBetter bool
+	}{
+		{name: "Scan", trace: scan, wantBetter: true},
+		{name: "Zipf", trace: zipfTrace(1000, 20000), wantBetter: true},
+		{name: "Recency", trace: loopTrace("recent", 80, 20), wantBetter: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lru := replay(NewLRU(), 100, tt.trace)
+			arc := replay(NewARC(100), 100, tt.trace)
+			if tt.wantBetter {
+				assert.Greater(t, arc, lru)
+			} else {
+				assert.Equal(t, lru, arc)
+			}
+		})
+	}
+}
+
+func TestARC_Evict(t *testing.T) {
+	a := NewARC(2)
+	assert.Equal(t, "", a.Evict())
+
+	a.Add("once")
+	a.Add("twice")
+	a.Refresh("twice")
+	// T1 is larger than its target, which starts empty
+	assert.Equal(t, "once", a.Evict())
+	assert.Equal(t, arcB1, a.lookup["once"].Value.(*arcItem).list)
+
+	// a hit on B1 grows the target size of T1 and the key goes to T2
+	a.Add("once")
+	assert.Equal(t, 1, a.p)
+	assert.Equal(t, arcT2, a.lookup["once"].Value.(*arcItem).list)
+	assert.Equal(t, "twice", a.Evict())
+
+	a.Delete("once")
+	assert.Equal(t, "", a.Evict())
+}
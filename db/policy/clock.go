@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"sync/atomic"
+)
+
+type clockSlot struct {
+	index      int
+	key        string
+	used       bool
+	referenced atomic.Bool
+}
+
+// Clock implements the CLOCK (second chance) policy, an approximation of LRU.
+// Keys sit in a circular buffer swept by a hand. Refresh only sets the referenced bit of a key, and the hand gives
+// referenced keys a second chance by clearing their bit instead of evicting them.
+// Since Refresh does not modify the structure of the policy, it is safe to call it concurrently with itself,
+// as long as Add, Evict and Delete are serialized with all the other calls.
+type Clock struct {
+	slots  []*clockSlot
+	lookup map[string]*clockSlot
+	// free holds the indexes of the slots released by Delete, reused by Add.
+	free []int
+	hand int
+}
+
+func NewClock() *Clock {
+	return &Clock{
+		lookup: make(map[string]*clockSlot),
+	}
+}
+
+// Refresh marks an existing element as referenced.
+func (c *Clock) Refresh(key string) {
+	if slot, ok := c.lookup[key]; ok {
+		slot.referenced.Store(true)
+	}
+}
+
+// Add a new element to the clock, reusing a slot freed by a previous eviction or deletion if any.
+func (c *Clock) Add(key string) {
+	if _, ok := c.lookup[key]; ok {
+		c.Refresh(key)
+		return
+	}
+	var slot *clockSlot
+	if n := len(c.free); n > 0 {
+		slot = c.slots[c.free[n-1]]
+		c.free = c.free[:n-1]
+	} else {
+		slot = &clockSlot{index: len(c.slots)}
+		c.slots = append(c.slots, slot)
+	}
+	slot.key = key
+	slot.used = true
+	slot.referenced.Store(false)
+	c.lookup[key] = slot
+}
+
+// Evict sweeps the clock from the hand and evicts the first key which is not referenced, clearing the referenced
+// bit of the keys it passes. It returns an empty string if the policy holds no key.
+func (c *Clock) Evict() string {
+	if len(c.lookup) == 0 {
+		return ""
+	}
+	for {
+		if c.hand >= len(c.slots) {
+			c.hand = 0
+		}
+		slot := c.slots[c.hand]
+		c.hand++
+		if !slot.used {
+			continue
+		}
+		if slot.referenced.Swap(false) {
+			continue
+		}
+		c.release(slot)
+		return slot.key
+	}
+}
+
+// Delete removes an element from the clock.
+func (c *Clock) Delete(key string) {
+	if slot, ok := c.lookup[key]; ok {
+		c.release(slot)
+	}
+}
+
+// release frees the slot of a key.
+func (c *Clock) release(slot *clockSlot) {
+	delete(c.lookup, slot.key)
+	slot.used = false
+	c.free = append(c.free, slot.index)
+}
@@ -0,0 +1,52 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestClock_Evict(t *testing.T) {
+	c := NewClock()
+	assert.Equal(t, "", c.Evict())
+
+	c.Add("first")
+	c.Add("second")
+	c.Add("third")
+	c.Refresh("first")
+	// the referenced key gets a second chance
+	assert.Equal(t, "second", c.Evict())
+	assert.Equal(t, "third", c.Evict())
+
+	// the freed slot is reused and the key is not referenced anymore
+	c.Add("fourth")
+	c.Delete("first")
+	assert.Equal(t, "fourth", c.Evict())
+	assert.Equal(t, "", c.Evict())
+}
+
+func TestClock_HitRatio(t *testing.T) {
+	trace := zipfTrace(1000, 20000)
+	lru := replay(NewLRU(), 100, trace)
+	clock := replay(NewClock(), 100, trace)
+	// CLOCK approximates LRU
+	assert.InEpsilon(t, lru, clock, 0.05)
+}
+
+func TestClock_ConcurrentRefresh(t *testing.T) {
+	c := NewClock()
+	c.Add("hello")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Refresh("hello")
+				c.Refresh("missing")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(t, c.lookup["hello"].referenced.Load())
+}
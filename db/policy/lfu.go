@@ -1,107 +1,148 @@
 package policy
 
 import (
-	"container/heap"
+	"container/list"
+	"time"
 )
 
-type Item struct {
-	key   string
-	freq  int
-	index int
-}
-
-// A PriorityQueue implements heap.Interface and holds Items.
-type PriorityQueue []*Item
+// DefaultLFUDecay is the period after which the LFU policy halves the frequencies.
+const DefaultLFUDecay = time.Minute
 
-//goland:noinspection GoMixedReceiverTypes
-func (pq PriorityQueue) Len() int { return len(pq) }
-
-//goland:noinspection GoMixedReceiverTypes
-func (pq PriorityQueue) Less(i, j int) bool {
-	// We want Pop to give us the lowest.
-	return pq[i].freq < pq[j].freq
+// freqNode groups the keys accessed the same number of times, the least recently used at the back.
+type freqNode struct {
+	freq int
+	keys *list.List
 }
 
-//goland:noinspection GoMixedReceiverTypes
-func (pq PriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-	pq[i].index = i
-	pq[j].index = j
+type lfuItem struct {
+	key  string
+	node *list.Element
+	ele  *list.Element
 }
 
-//goland:noinspection GoMixedReceiverTypes
-func (pq *PriorityQueue) Push(x any) {
-	n := len(*pq)
-	item := x.(*Item)
-	item.index = n
-	*pq = append(*pq, item)
-}
-
-//goland:noinspection GoMixedReceiverTypes
-func (pq *PriorityQueue) Pop() any {
-	old := *pq
-	n := len(old)
-	item := old[n-1]
-	old[n-1] = nil  // avoid memory leak
-	item.index = -1 // for safety
-	*pq = old[0 : n-1]
-	return item
-}
+// LFU evicts the least frequently used key, the least recently used one among equally frequent keys.
+// Keys are grouped in nodes by frequency, nodes being linked by increasing frequency, so that all the operations
+// run in constant time: an access moves a key to the next node, creating it if needed, and eviction picks a key
+// from the first node.
+// Frequencies are halved periodically, so that keys which were popular a long time ago do not stay forever.
+type LFU struct {
+	// nodes holds *freqNode by increasing frequency.
+	nodes  *list.List
+	lookup map[string]*lfuItem
 
-// Update modifies the priority and value of an Item in the queue.
-//
-//goland:noinspection GoMixedReceiverTypes
-func (pq *PriorityQueue) Update(item *Item, priority int) {
-	item.freq = priority
-	heap.Fix(pq, item.index)
+	decay     time.Duration
+	lastDecay time.Time
+	now       func() time.Time
 }
 
-type LFU struct {
-	frequency     map[string]int
-	priorityQueue PriorityQueue
-	lookup        map[string]*Item
+func NewLFU() *LFU {
+	return NewLFUWithDecay(DefaultLFUDecay)
 }
 
-func NewLFU() *LFU {
+// NewLFUWithDecay creates an LFU policy halving the frequencies after each decay period, 0 meaning never.
+func NewLFUWithDecay(decay time.Duration) *LFU {
 	return &LFU{
-		frequency:     make(map[string]int),
-		priorityQueue: make(PriorityQueue, 0),
-		lookup:        make(map[string]*Item),
+		nodes:     list.New(),
+		lookup:    make(map[string]*lfuItem),
+		decay:     decay,
+		lastDecay: time.Now(),
+		now:       time.Now,
 	}
 }
 
+// Add a new element with a frequency of 1.
 func (l *LFU) Add(key string) {
-	l.frequency[key]++
-	if item, exists := l.lookup[key]; exists {
-		l.priorityQueue.Update(item, l.frequency[key])
-	} else {
-		item := &Item{
-			key:  key,
-			freq: l.frequency[key],
-		}
-		heap.Push(&(l.priorityQueue), item)
-		l.lookup[key] = item
+	if _, exists := l.lookup[key]; exists {
+		l.Refresh(key)
+		return
+	}
+	l.age()
+	front := l.nodes.Front()
+	if front == nil || front.Value.(*freqNode).freq != 1 {
+		front = l.nodes.PushFront(&freqNode{freq: 1, keys: list.New()})
 	}
+	item := &lfuItem{key: key, node: front}
+	item.ele = front.Value.(*freqNode).keys.PushFront(item)
+	l.lookup[key] = item
 }
 
-func (l *LFU) Evict() (evicted string) {
-	item := heap.Pop(&(l.priorityQueue)).(*Item)
-	if item == nil {
+// Refresh increments the frequency of an existing element.
+func (l *LFU) Refresh(key string) {
+	item, exists := l.lookup[key]
+	if !exists {
+		return
+	}
+	l.age()
+	// aging may have moved the item
+	current := item.node
+	freq := current.Value.(*freqNode).freq + 1
+	next := current.Next()
+	if next == nil || next.Value.(*freqNode).freq != freq {
+		next = l.nodes.InsertAfter(&freqNode{freq: freq, keys: list.New()}, current)
+	}
+	l.unlink(item)
+	item.node = next
+	item.ele = next.Value.(*freqNode).keys.PushFront(item)
+}
+
+// Evict evicts the least recently used key among the least frequently used ones.
+// It returns an empty string if the policy holds no key.
+func (l *LFU) Evict() string {
+	l.age()
+	front := l.nodes.Front()
+	if front == nil {
 		return ""
 	}
+	item := front.Value.(*freqNode).keys.Back().Value.(*lfuItem)
+	l.unlink(item)
 	delete(l.lookup, item.key)
-	delete(l.frequency, item.key)
 	return item.key
 }
 
 func (l *LFU) Delete(key string) {
 	if item, exists := l.lookup[key]; exists {
-		heap.Remove(&(l.priorityQueue), item.index)
+		l.unlink(item)
 		delete(l.lookup, key)
-		delete(l.frequency, key)
 	}
 }
 
-func (l *LFU) Refresh(key string) {
-	l.Add(key)
+// unlink removes an item from its node, removing the node if it becomes empty.
+func (l *LFU) unlink(item *lfuItem) {
+	node := item.node.Value.(*freqNode)
+	node.keys.Remove(item.ele)
+	if node.keys.Len() == 0 {
+		l.nodes.Remove(item.node)
+	}
+}
+
+// age halves the frequencies once per decay period elapsed since the last time they were. Nodes ending up with
+// the same frequency are merged, the keys of the formerly more frequent one being considered more recent.
+// It costs a pass over the keys, once per period at most.
+func (l *LFU) age() {
+	if l.decay <= 0 {
+		return
+	}
+	periods := int(l.now().Sub(l.lastDecay) / l.decay)
+	if periods == 0 {
+		return
+	}
+	l.lastDecay = l.lastDecay.Add(time.Duration(periods) * l.decay)
+	var previous *list.Element
+	for current := l.nodes.Front(); current != nil; {
+		next := current.Next()
+		node := current.Value.(*freqNode)
+		node.freq = max(1, node.freq>>min(periods, 62))
+		if previous != nil && previous.Value.(*freqNode).freq == node.freq {
+			merged := previous.Value.(*freqNode)
+			for ele := node.keys.Back(); ele != nil; ele = node.keys.Back() {
+				item := node.keys.Remove(ele).(*lfuItem)
+				item.node = previous
+				item.ele = merged.keys.PushFront(item)
+			}
+			l.nodes.Remove(current)
+		} else {
+			previous = current
+		}
+		current = next
+	}
 }
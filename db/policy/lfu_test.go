@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestLFU_Evict(t *testing.T) {
+	l := NewLFU()
+	assert.Equal(t, "", l.Evict())
+
+	l.Add("first")
+	l.Add("second")
+	l.Add("third")
+	l.Refresh("first")
+	l.Refresh("first")
+	l.Refresh("third")
+	// "second" is the least frequently used, then "third" which was used as much as nothing else
+	assert.Equal(t, "second", l.Evict())
+	assert.Equal(t, "third", l.Evict())
+
+	l.Add("fourth")
+	l.Add("fifth")
+	// equally frequent keys are evicted from the least recently used
+	assert.Equal(t, "fourth", l.Evict())
+	l.Delete("fifth")
+	assert.Equal(t, "first", l.Evict())
+	assert.Equal(t, "", l.Evict())
+	assert.Equal(t, 0, l.nodes.Len())
+}
+
+func TestLFU_Aging(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	l := NewLFUWithDecay(time.Minute)
+	l.now = func() time.Time { return now }
+	l.lastDecay = now
+
+	l.Add("old")
+	for i := 0; i < 7; i++ {
+		l.Refresh("old")
+	}
+	l.Add("mid")
+	for i := 0; i < 8; i++ {
+		l.Refresh("mid")
+	}
+	assert.Equal(t, 8, l.lookup["old"].node.Value.(*freqNode).freq)
+
+	// two periods divide the frequencies by four, merging both keys in the same node
+	now = now.Add(2*time.Minute + time.Second)
+	l.Add("new")
+	l.Refresh("new")
+	assert.Equal(t, 2, l.lookup["old"].node.Value.(*freqNode).freq)
+	assert.Same(t, l.lookup["old"].node, l.lookup["mid"].node)
+	assert.Same(t, l.lookup["old"].node, l.lookup["new"].node)
+	// the keys of the formerly less frequent node are evicted first
+	assert.Equal(t, "old", l.Evict())
+	assert.Equal(t, "mid", l.Evict())
+}
+
+func TestLFU_HitRatio(t *testing.T) {
+	// frequency matters more than recency on skewed traces
+	trace := zipfTrace(1000, 20000)
+	assert.Greater(t, replay(NewLFU(), 100, trace), replay(NewLRU(), 100, trace))
+}
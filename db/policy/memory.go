@@ -0,0 +1,122 @@
+package policy
+
+import (
+	"container/list"
+	"unsafe"
+)
+
+// The memory used by the policies is estimated from the number of elements of their structures. Keys are not
+// counted: the policies share the bytes of the key strings with the cache, and only hold their headers.
+const (
+	// MapSlotOverhead is the estimated cost of a map slot holding a string key and a word sized value,
+	// accounting for the load factor of the Go maps.
+	MapSlotOverhead = 40
+	// stringHeader is the size of a string header.
+	stringHeader = int64(unsafe.Sizeof(""))
+	// pointerSize is the size of a pointer.
+	pointerSize = int64(unsafe.Sizeof(uintptr(0)))
+)
+
+// listElementSize is the size of an element of a container/list.
+var listElementSize = int64(unsafe.Sizeof(list.Element{}))
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+func (l *LRU) MemoryUsage() int64 {
+	// each element boxes its key in an interface
+	return int64(l.queue.Len())*(listElementSize+stringHeader) + int64(len(l.lookup))*MapSlotOverhead
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+func (l *LFU) MemoryUsage() int64 {
+	item := int64(unsafe.Sizeof(lfuItem{})) + listElementSize + MapSlotOverhead
+	node := int64(unsafe.Sizeof(freqNode{})+unsafe.Sizeof(list.List{})) + listElementSize
+	return int64(len(l.lookup))*item + int64(l.nodes.Len())*node
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+func (r *Random) MemoryUsage() int64 {
+	return int64(cap(r.keys))*stringHeader + int64(len(r.index))*MapSlotOverhead
+}
+
+// MemoryUsage returns 0 as the policy does not track anything.
+func (NoEviction) MemoryUsage() int64 {
+	return 0
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+func (c *Clock) MemoryUsage() int64 {
+	slot := int64(unsafe.Sizeof(clockSlot{})) + pointerSize
+	return int64(len(c.slots))*slot + int64(len(c.lookup))*MapSlotOverhead + int64(cap(c.free))*pointerSize
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys, ghosts included.
+func (a *ARC) MemoryUsage() int64 {
+	elements := 0
+	for _, l := range a.lists {
+		elements += l.Len()
+	}
+	item := int64(unsafe.Sizeof(arcItem{})) + listElementSize
+	return int64(elements)*item + int64(len(a.lookup))*MapSlotOverhead
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys, ghosts included.
+func (s *S3FIFO) MemoryUsage() int64 {
+	item := int64(unsafe.Sizeof(s3FIFOItem{})) + listElementSize + MapSlotOverhead
+	ghost := stringHeader + listElementSize + MapSlotOverhead
+	return int64(len(s.lookup))*item + int64(len(s.ghostLookup))*ghost
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys, sketch included.
+func (t *TinyLFU) MemoryUsage() int64 {
+	item := int64(unsafe.Sizeof(tinyLFUItem{})) + listElementSize + MapSlotOverhead
+	return int64(len(t.lookup))*item + t.sketch.MemoryUsage()
+}
+
+// MemoryUsage returns the number of bytes of the counters and of the doorkeeper.
+func (s *CountMinSketch) MemoryUsage() int64 {
+	size := int64(0)
+	for _, row := range s.rows {
+		size += int64(len(row))
+	}
+	return size + int64(len(s.doorkeeper.bits))*8
+}
+
+// MemoryUsage returns the estimated number of bytes used to track the keys having a time to live, including the
+// wrapped policy.
+func (v *Volatile) MemoryUsage() int64 {
+	size := int64(len(v.volatile)) * MapSlotOverhead
+	if reporter, ok := v.policy.(interface{ MemoryUsage() int64 }); ok {
+		size += reporter.MemoryUsage()
+	}
+	return size
+}
+
+// MemoryUsage returns the estimated number of bytes used by the policy to track its keys.
+func (t *TTL) MemoryUsage() int64 {
+	item := int64(unsafe.Sizeof(ttlItem{})) + pointerSize + MapSlotOverhead
+	return int64(len(t.lookup)) * item
+}
+
+// Frequency returns the number of accesses of a key since it was added, halved at each decay period.
+// It returns false if the policy does not hold the key.
+func (l *LFU) Frequency(key string) (int, bool) {
+	item, ok := l.lookup[key]
+	if !ok {
+		return 0, false
+	}
+	return item.node.Value.(*freqNode).freq, true
+}
+
+// Frequency returns the access frequency of a key estimated by the sketch. It returns false if the policy does
+// not hold the key.
+func (t *TinyLFU) Frequency(key string) (int, bool) {
+	if _, ok := t.lookup[key]; !ok {
+		return 0, false
+	}
+	return t.sketch.Estimate(key), true
+}
+
+// Unwrap returns the policy restricted to the keys having a time to live.
+func (v *Volatile) Unwrap() Policy {
+	return v.policy
+}
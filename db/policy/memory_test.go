@@ -0,0 +1,55 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemoryUsage(t *testing.T) {
+	policies := map[string]interface {
+		Policy
+		MemoryUsage() int64
+	}{
+		"lru":     NewLRU(),
+		"lfu":     NewLFU(),
+		"random":  NewRandom(),
+		"clock":   NewClock(),
+		"arc":     NewARC(10),
+		"s3fifo":  NewS3FIFO(10),
+		"tinylfu": NewTinyLFU(10),
+		"ttl":     NewTTL(),
+	}
+	for name, p := range policies {
+		empty := p.MemoryUsage()
+		for _, key := range []string{"a", "b", "c"} {
+			p.Add(key)
+			if ttl, ok := p.(*TTL); ok {
+				ttl.SetExpiration(key, time.Now().Add(time.Minute))
+			}
+		}
+		assert.Greater(t, p.MemoryUsage(), empty, name)
+	}
+	assert.Zero(t, NewNoEviction().MemoryUsage())
+}
+
+func TestFrequency(t *testing.T) {
+	l := NewLFU()
+	l.Add("key")
+	l.Refresh("key")
+	freq, ok := l.Frequency("key")
+	assert.True(t, ok)
+	assert.Equal(t, 2, freq)
+	_, ok = l.Frequency("missing")
+	assert.False(t, ok)
+
+	tiny := NewTinyLFU(100)
+	tiny.Add("key")
+	tiny.Refresh("key")
+	tiny.Refresh("key")
+	freq, ok = tiny.Frequency("key")
+	assert.True(t, ok)
+	assert.Positive(t, freq)
+	_, ok = tiny.Frequency("missing")
+	assert.False(t, ok)
+}
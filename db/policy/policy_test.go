@@ -0,0 +1,48 @@
+package policy
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// replay drives a policy like a cache holding up to capacity keys would and returns the number of hits.
+func replay(p Policy, capacity int, trace []string) int {
+	present := make(map[string]bool)
+	hits := 0
+	for _, key := range trace {
+		if present[key] {
+			p.Refresh(key)
+			hits++
+			continue
+		}
+		if len(present) >= capacity {
+			delete(present, p.Evict())
+		}
+		p.Add(key)
+		present[key] = true
+	}
+	return hits
+}
+
+// loopTrace returns a trace cycling n times through size keys, which LRU misses entirely when size exceeds
+// the capacity of the cache.
+func loopTrace(prefix string, size int, n int) []string {
+	trace := make([]string, 0, size*n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < size; j++ {
+			trace = append(trace, fmt.Sprintf("%s:%d", prefix, j))
+		}
+	}
+	return trace
+}
+
+// zipfTrace returns a trace of length keys drawn among size ones following a Zipf distribution,
+// where a few keys get most of the accesses.
+func zipfTrace(size uint64, length int) []string {
+	zipf := rand.NewZipf(rand.New(rand.NewSource(42)), 1.1, 1, size-1)
+	trace := make([]string, 0, length)
+	for i := 0; i < length; i++ {
+		trace = append(trace, fmt.Sprintf("zipf:%d", zipf.Uint64()))
+	}
+	return trace
+}
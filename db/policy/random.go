@@ -0,0 +1,70 @@
+package policy
+
+import (
+	"math/rand"
+)
+
+// Random evicts a key picked at random, which costs nothing on access.
+type Random struct {
+	keys  []string
+	index map[string]int
+}
+
+func NewRandom() *Random {
+	return &Random{
+		index: make(map[string]int),
+	}
+}
+
+// Refresh does nothing as accesses do not matter to this policy.
+func (r *Random) Refresh(string) {}
+
+// Evict evicts a random key. It returns an empty string if the policy holds no key.
+func (r *Random) Evict() string {
+	if len(r.keys) == 0 {
+		return ""
+	}
+	key := r.keys[rand.Intn(len(r.keys))]
+	r.Delete(key)
+	return key
+}
+
+// Add a new element to the policy.
+func (r *Random) Add(key string) {
+	if _, ok := r.index[key]; ok {
+		return
+	}
+	r.index[key] = len(r.keys)
+	r.keys = append(r.keys, key)
+}
+
+// Delete removes an element by moving the last one in its place.
+func (r *Random) Delete(key string) {
+	i, ok := r.index[key]
+	if !ok {
+		return
+	}
+	last := len(r.keys) - 1
+	r.keys[i] = r.keys[last]
+	r.index[r.keys[i]] = i
+	r.keys = r.keys[:last]
+	delete(r.index, key)
+}
+
+// NoEviction never evicts keys, so that writes adding keys to a full cache fail instead.
+type NoEviction struct{}
+
+func NewNoEviction() NoEviction {
+	return NoEviction{}
+}
+
+func (NoEviction) Refresh(string) {}
+
+// Evict always returns an empty string, meaning there is nothing to evict.
+func (NoEviction) Evict() string {
+	return ""
+}
+
+func (NoEviction) Add(string) {}
+
+func (NoEviction) Delete(string) {}
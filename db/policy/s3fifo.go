@@ -0,0 +1,153 @@
+package policy
+
+import (
+	"container/list"
+	"sync/atomic"
+)
+
+// s3FIFOMaxFreq caps the access counter of the keys, enough to tell the keys accessed again from the others.
+const s3FIFOMaxFreq = 3
+
+type s3FIFOItem struct {
+	key  string
+	freq atomic.Int32
+	main bool
+}
+
+// S3FIFO implements the S3-FIFO policy, made of three FIFO queues.
+// New keys enter a small queue taking 10% of the capacity, which quickly evicts the many keys accessed only once.
+// Keys accessed while in the small queue move to the main queue when they reach its end, the others being
+// remembered by a ghost queue holding keys only. A key found in the ghost queue goes straight to the main queue,
+// which reinserts the keys accessed since their insertion instead of evicting them.
+// Refresh only increments the access counter of a key, so it is safe to call it concurrently with itself,
+// as long as Add, Evict and Delete are serialized with all the other calls.
+type S3FIFO struct {
+	small  *list.List
+	main   *list.List
+	lookup map[string]*list.Element
+
+	ghost       *list.List
+	ghostLookup map[string]*list.Element
+
+	smallCapacity int
+	ghostCapacity int
+}
+
+// NewS3FIFO creates an S3-FIFO policy for a cache holding up to capacity keys.
+func NewS3FIFO(capacity int64) *S3FIFO {
+	bounded := boundedCapacity(capacity)
+	smallCapacity := max(1, bounded/10)
+	return &S3FIFO{
+		small:         list.New(),
+		main:          list.New(),
+		lookup:        make(map[string]*list.Element),
+		ghost:         list.New(),
+		ghostLookup:   make(map[string]*list.Element),
+		smallCapacity: smallCapacity,
+		ghostCapacity: max(1, bounded-smallCapacity),
+	}
+}
+
+// Refresh records an access to an existing element.
+func (s *S3FIFO) Refresh(key string) {
+	ele, ok := s.lookup[key]
+	if !ok {
+		return
+	}
+	freq := &ele.Value.(*s3FIFOItem).freq
+	for {
+		current := freq.Load()
+		if current >= s3FIFOMaxFreq || freq.CompareAndSwap(current, current+1) {
+			return
+		}
+	}
+}
+
+// Add a new element to the small queue, or to the main queue if it was recently evicted from the small one.
+func (s *S3FIFO) Add(key string) {
+	if _, ok := s.lookup[key]; ok {
+		s.Refresh(key)
+		return
+	}
+	item := &s3FIFOItem{key: key}
+	if ghost, ok := s.ghostLookup[key]; ok {
+		s.ghost.Remove(ghost)
+		delete(s.ghostLookup, key)
+		item.main = true
+		s.lookup[key] = s.main.PushFront(item)
+		return
+	}
+	s.lookup[key] = s.small.PushFront(item)
+}
+
+// Evict evicts a key from the small queue if it is full, from the main queue otherwise.
+// It returns an empty string if the policy holds no key.
+func (s *S3FIFO) Evict() string {
+	if s.small.Len() >= s.smallCapacity || s.main.Len() == 0 {
+		if key, ok := s.evictSmall(); ok {
+			return key
+		}
+	}
+	key, _ := s.evictMain()
+	return key
+}
+
+// evictSmall evicts the oldest key of the small queue which was not accessed since its insertion,
+// moving the accessed ones to the main queue.
+func (s *S3FIFO) evictSmall() (string, bool) {
+	for ele := s.small.Back(); ele != nil; ele = s.small.Back() {
+		item := ele.Value.(*s3FIFOItem)
+		s.small.Remove(ele)
+		if item.freq.Load() > 0 {
+			item.freq.Store(0)
+			item.main = true
+			s.lookup[item.key] = s.main.PushFront(item)
+			continue
+		}
+		delete(s.lookup, item.key)
+		s.remember(item.key)
+		return item.key, true
+	}
+	return "", false
+}
+
+// evictMain evicts the oldest key of the main queue which was not accessed since its last reinsertion,
+// reinserting the accessed ones with a decremented counter.
+func (s *S3FIFO) evictMain() (string, bool) {
+	for ele := s.main.Back(); ele != nil; ele = s.main.Back() {
+		item := ele.Value.(*s3FIFOItem)
+		if freq := item.freq.Load(); freq > 0 {
+			item.freq.Store(freq - 1)
+			s.main.MoveToFront(ele)
+			continue
+		}
+		s.main.Remove(ele)
+		delete(s.lookup, item.key)
+		return item.key, true
+	}
+	return "", false
+}
+
+// remember adds a key evicted from the small queue to the ghost queue, forgetting the oldest one if it is full.
+func (s *S3FIFO) remember(key string) {
+	if s.ghost.Len() >= s.ghostCapacity {
+		oldest := s.ghost.Back()
+		s.ghost.Remove(oldest)
+		delete(s.ghostLookup, oldest.Value.(string))
+	}
+	s.ghostLookup[key] = s.ghost.PushFront(key)
+}
+
+// Delete removes an element from the policy.
+func (s *S3FIFO) Delete(key string) {
+	ele, ok := s.lookup[key]
+	if !ok {
+		return
+	}
+	if ele.Value.(*s3FIFOItem).main {
+		s.main.Remove(ele)
+	} else {
+		s.small.Remove(ele)
+	}
+	delete(s.lookup, key)
+}
@@ -0,0 +1,65 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestS3FIFO_Evict(t *testing.T) {
+	s := NewS3FIFO(10)
+	assert.Equal(t, "", s.Evict())
+
+	s.Add("twice")
+	s.Refresh("twice")
+	s.Add("once")
+	// the accessed key moves to the main queue while the other one is evicted and remembered
+	assert.Equal(t, "once", s.Evict())
+	assert.True(t, s.lookup["twice"].Value.(*s3FIFOItem).main)
+	assert.Contains(t, s.ghostLookup, "once")
+
+	// a remembered key goes straight to the main queue
+	s.Add("once")
+	assert.True(t, s.lookup["once"].Value.(*s3FIFOItem).main)
+	s.Delete("once")
+	assert.Equal(t, "twice", s.Evict())
+	assert.Equal(t, "", s.Evict())
+}
+
+func TestS3FIFO_HitRatio(t *testing.T) {
+	// popular keys before and after a scan of keys accessed once
+	scan := append(loopTrace("hot", 50, 10), loopTrace("scan", 500, 1)...)
+	scan = append(scan, loopTrace("hot", 50, 10)...)
+
+	tests := []struct {
+		name  string
+		trace []string
+	}{
+		{name: "Scan", trace: scan},
+		{name: "Zipf", trace: zipfTrace(1000, 20000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lru := replay(NewLRU(), 100, tt.trace)
+			s3fifo := replay(NewS3FIFO(100), 100, tt.trace)
+			assert.Greater(t, s3fifo, lru)
+		})
+	}
+}
+
+func TestS3FIFO_ConcurrentRefresh(t *testing.T) {
+	s := NewS3FIFO(10)
+	s.Add("hello")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Refresh("hello")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(s3FIFOMaxFreq), s.lookup["hello"].Value.(*s3FIFOItem).freq.Load())
+}
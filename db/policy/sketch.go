@@ -0,0 +1,163 @@
+package policy
+
+import (
+	"hash/fnv"
+)
+
+const (
+	sketchDepth = 4
+	// counterMax is the saturation value of the counters, which only need to tell hot keys from cold ones.
+	counterMax = 15
+)
+
+// hashKey returns two independent hashes of a key, combined to derive as many hash functions as needed.
+func hashKey(key string) (uint32, uint32) {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(key))
+	sum := hasher.Sum64()
+	// an odd second hash visits all the slots of a power of two table
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+// maxTableSize bounds the tables and queues sized from the capacity of the cache, so that a huge
+// maxmemory-items does not allocate gigabytes upfront: 4 rows of that many counters take 64 MiB.
+const maxTableSize = 1 << 24
+
+// boundedCapacity returns a capacity between 1 and maxTableSize.
+func boundedCapacity(capacity int64) int {
+	return int(min(max(capacity, 1), maxTableSize))
+}
+
+// nextPowerOfTwo returns the smallest power of two greater than or equal to n, at least 16 and at most
+// maxTableSize.
+func nextPowerOfTwo(n int64) uint32 {
+	size := uint32(16)
+	for int64(size) < n && size < maxTableSize {
+		size <<= 1
+	}
+	return size
+}
+
+// CountMinSketch estimates the access frequency of keys in constant memory.
+// Each key increments one counter per row and its frequency is estimated by the smallest of them,
+// so that collisions can only overestimate it. Counters are halved once enough increments were recorded,
+// so that the frequency of keys which are no longer accessed decays over time.
+type CountMinSketch struct {
+	rows [sketchDepth][]uint8
+	mask uint32
+	// additions counts the increments since the last reset, which happens after sampleSize of them.
+	additions  int64
+	sampleSize int64
+	// doorkeeper filters out the keys seen only once, so that they do not pollute the counters.
+	doorkeeper *BloomFilter
+}
+
+// NewCountMinSketch creates a sketch sized for a cache holding up to capacity keys.
+func NewCountMinSketch(capacity int64) *CountMinSketch {
+	width := nextPowerOfTwo(capacity)
+	s := &CountMinSketch{
+		mask:       width - 1,
+		sampleSize: 10 * int64(width),
+		doorkeeper: NewBloomFilter(capacity),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// Increment records an access to a key.
+func (s *CountMinSketch) Increment(key string) {
+	h1, h2 := hashKey(key)
+	if !s.doorkeeper.Add(h1, h2) {
+		// first access since the last reset, only remembered by the doorkeeper
+		s.countAddition()
+		return
+	}
+	for i := range s.rows {
+		counter := &s.rows[i][(h1+uint32(i)*h2)&s.mask]
+		if *counter < counterMax {
+			*counter++
+		}
+	}
+	s.countAddition()
+}
+
+// Estimate returns the estimated access frequency of a key.
+func (s *CountMinSketch) Estimate(key string) int {
+	h1, h2 := hashKey(key)
+	estimate := uint8(counterMax)
+	for i := range s.rows {
+		estimate = min(estimate, s.rows[i][(h1+uint32(i)*h2)&s.mask])
+	}
+	if s.doorkeeper.Contains(h1, h2) {
+		return int(estimate) + 1
+	}
+	return int(estimate)
+}
+
+func (s *CountMinSketch) countAddition() {
+	s.additions++
+	if s.additions >= s.sampleSize {
+		s.reset()
+	}
+}
+
+// reset halves all the counters and clears the doorkeeper.
+func (s *CountMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.doorkeeper.Clear()
+	s.additions /= 2
+}
+
+const bloomHashes = 3
+
+// BloomFilter is a set of hashed keys which may report false positives but never false negatives.
+type BloomFilter struct {
+	bits []uint64
+	mask uint32
+}
+
+// NewBloomFilter creates a filter sized for capacity keys.
+func NewBloomFilter(capacity int64) *BloomFilter {
+	// about 8 bits per key keeps the false positive rate around 5% with 3 hash functions, the bound of the
+	// capacity keeping the multiplication from overflowing
+	size := nextPowerOfTwo(8 * int64(boundedCapacity(capacity)))
+	return &BloomFilter{
+		bits: make([]uint64, size/64+1),
+		mask: size - 1,
+	}
+}
+
+// Add inserts hashed key and tells if it was already present.
+func (b *BloomFilter) Add(h1, h2 uint32) bool {
+	present := true
+	for i := uint32(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) & b.mask
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			present = false
+			b.bits[bit/64] |= 1 << (bit % 64)
+		}
+	}
+	return present
+}
+
+// Contains tells if a hashed key may have been added.
+func (b *BloomFilter) Contains(h1, h2 uint32) bool {
+	for i := uint32(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) & b.mask
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clear removes all the keys.
+func (b *BloomFilter) Clear() {
+	clear(b.bits)
+}
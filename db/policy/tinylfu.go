@@ -0,0 +1,149 @@
+package policy
+
+import (
+	"container/list"
+)
+
+type segment int
+
+const (
+	windowSegment segment = iota
+	probationSegment
+	protectedSegment
+)
+
+type tinyLFUItem struct {
+	key     string
+	segment segment
+}
+
+// TinyLFU implements the W-TinyLFU policy.
+// New keys enter a small LRU window, which absorbs bursts of recent keys. Keys leaving the window are candidates
+// to the main space, a segmented LRU made of a probation and a protected segment, the latter holding the keys
+// accessed at least twice while in the main space. A candidate is only admitted if it was accessed more often
+// than the victim of the main space according to a count-min sketch, otherwise the candidate itself is evicted.
+// This way, keys seen once do not evict frequently used ones.
+type TinyLFU struct {
+	window    *list.List
+	probation *list.List
+	protected *list.List
+	lookup    map[string]*list.Element
+	sketch    *CountMinSketch
+
+	windowCapacity    int
+	mainCapacity      int
+	protectedCapacity int
+}
+
+// NewTinyLFU creates a W-TinyLFU policy for a cache holding up to capacity keys.
+// The window takes 1% of the capacity and the protected segment 80% of the main space.
+func NewTinyLFU(capacity int64) *TinyLFU {
+	windowCapacity := max(1, int(capacity/100))
+	mainCapacity := max(1, int(capacity)-windowCapacity)
+	return &TinyLFU{
+		window:            list.New(),
+		probation:         list.New(),
+		protected:         list.New(),
+		lookup:            make(map[string]*list.Element),
+		sketch:            NewCountMinSketch(capacity),
+		windowCapacity:    windowCapacity,
+		mainCapacity:      mainCapacity,
+		protectedCapacity: mainCapacity * 8 / 10,
+	}
+}
+
+// Add a new element to the window. Until the main space is full, keys leaving the window enter it unfiltered.
+func (t *TinyLFU) Add(key string) {
+	if _, ok := t.lookup[key]; ok {
+		t.Refresh(key)
+		return
+	}
+	t.sketch.Increment(key)
+	t.lookup[key] = t.window.PushFront(&tinyLFUItem{key: key, segment: windowSegment})
+	if t.window.Len() > t.windowCapacity && t.probation.Len()+t.protected.Len() < t.mainCapacity {
+		t.moveToProbation(t.window.Back())
+	}
+}
+
+// Refresh records an access to an existing element. A key accessed in probation is promoted to the protected
+// segment, whose least recently used key is demoted to probation if the segment is full.
+func (t *TinyLFU) Refresh(key string) {
+	ele, ok := t.lookup[key]
+	if !ok {
+		return
+	}
+	t.sketch.Increment(key)
+	item := ele.Value.(*tinyLFUItem)
+	switch item.segment {
+	case windowSegment:
+		t.window.MoveToFront(ele)
+	case protectedSegment:
+		t.protected.MoveToFront(ele)
+	case probationSegment:
+		t.probation.Remove(ele)
+		item.segment = protectedSegment
+		t.lookup[key] = t.protected.PushFront(item)
+		if t.protected.Len() > t.protectedCapacity {
+			t.moveToProbation(t.protected.Back())
+		}
+	}
+}
+
+// Evict evicts the key with the least value. When the window is full, its least recently used key competes with
+// the victim of the main space and the loser is evicted. Ties are lost by the candidate, so that a cache full of
+// equally popular keys is not churned by new ones.
+// It returns an empty string if the policy holds no key.
+func (t *TinyLFU) Evict() string {
+	victim := t.probation.Back()
+	if victim == nil {
+		victim = t.protected.Back()
+	}
+	candidate := t.window.Back()
+	if candidate == nil || (t.window.Len() < t.windowCapacity && victim != nil) {
+		return t.remove(victim)
+	}
+	if victim == nil {
+		return t.remove(candidate)
+	}
+
+	candidateItem := candidate.Value.(*tinyLFUItem)
+	victimItem := victim.Value.(*tinyLFUItem)
+	if t.sketch.Estimate(candidateItem.key) <= t.sketch.Estimate(victimItem.key) {
+		return t.remove(candidate)
+	}
+	t.moveToProbation(candidate)
+	return t.remove(victim)
+}
+
+// Delete removes an element from the policy. Its frequency is kept by the sketch until it decays.
+func (t *TinyLFU) Delete(key string) {
+	if ele, ok := t.lookup[key]; ok {
+		t.remove(ele)
+	}
+}
+
+// moveToProbation moves an element of the window or of the protected segment to the front of probation.
+func (t *TinyLFU) moveToProbation(ele *list.Element) {
+	item := ele.Value.(*tinyLFUItem)
+	t.remove(ele)
+	item.segment = probationSegment
+	t.lookup[item.key] = t.probation.PushFront(item)
+}
+
+// remove removes an element from its segment and returns its key, or an empty string for a nil element.
+func (t *TinyLFU) remove(ele *list.Element) string {
+	if ele == nil {
+		return ""
+	}
+	item := ele.Value.(*tinyLFUItem)
+	switch item.segment {
+	case windowSegment:
+		t.window.Remove(ele)
+	case probationSegment:
+		t.probation.Remove(ele)
+	case protectedSegment:
+		t.protected.Remove(ele)
+	}
+	delete(t.lookup, item.key)
+	return item.key
+}
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestTinyLFU_ScanResistance(t *testing.T) {
+	var trace []string
+	// a few popular keys, then a scan of keys accessed once interleaved with the popular ones
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 80; j++ {
+			trace = append(trace, fmt.Sprintf("hot:%d", j))
+		}
+	}
+	for i := 0; i < 1000; i++ {
+		trace = append(trace, fmt.Sprintf("scan:%d", i), fmt.Sprintf("hot:%d", i%80))
+	}
+
+	lru := replay(NewLRU(), 100, trace)
+	tinyLFU := replay(NewTinyLFU(100), 100, trace)
+	assert.Greater(t, tinyLFU, lru)
+	// almost all the popular keys stay in the cache during the scan
+	assert.GreaterOrEqual(t, tinyLFU, 4*80+900)
+}
+
+func TestTinyLFU_Evict(t *testing.T) {
+	p := NewTinyLFU(2)
+	assert.Equal(t, "", p.Evict())
+
+	// the window holds a single key, so the first one enters the main space which is not full yet
+	p.Add("popular")
+	p.Refresh("popular")
+	p.Refresh("popular")
+	p.Add("once")
+	// the candidate leaving the window is not more popular than the victim, so it is rejected
+	assert.Equal(t, "once", p.Evict())
+
+	p.Add("once")
+	p.Refresh("once")
+	p.Refresh("once")
+	p.Refresh("once")
+	// now it is, so it is admitted at the expense of the victim
+	assert.Equal(t, "popular", p.Evict())
+	p.Delete("once")
+	assert.Equal(t, "", p.Evict())
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := NewCountMinSketch(16)
+	assert.Equal(t, 0, s.Estimate("key"))
+	s.Increment("key")
+	// the first access is only remembered by the doorkeeper
+	assert.Equal(t, 1, s.Estimate("key"))
+	for i := 0; i < 10; i++ {
+		s.Increment("key")
+	}
+	assert.Equal(t, 11, s.Estimate("key"))
+	for i := 0; i < 20; i++ {
+		s.Increment("key")
+	}
+	assert.Equal(t, counterMax+1, s.Estimate("key"))
+
+	// the counters are halved once the sample size is reached
+	for i := s.additions; i < s.sampleSize; i++ {
+		s.Increment(fmt.Sprintf("other:%d", i))
+	}
+	assert.LessOrEqual(t, s.Estimate("key"), counterMax/2+1)
+}
+
+func TestNewCountMinSketch_Bounded(t *testing.T) {
+	s := NewCountMinSketch(math.MaxInt64)
+	for _, row := range s.rows {
+		assert.Len(t, row, maxTableSize)
+	}
+	assert.Len(t, NewBloomFilter(math.MaxInt64).bits, maxTableSize/64+1, "the size should not overflow")
+	assert.Len(t, NewBloomFilter(0).bits, 16/64+1)
+
+	assert.Equal(t, maxTableSize, NewARC(math.MaxInt64).capacity)
+	fifo := NewS3FIFO(math.MaxInt64)
+	assert.Equal(t, maxTableSize, fifo.smallCapacity+fifo.ghostCapacity)
+}
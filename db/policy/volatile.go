@@ -0,0 +1,161 @@
+package policy
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Policy is the behavior shared by the eviction policies of this package.
+type Policy interface {
+	Refresh(key string)
+	Evict() string
+	Add(key string)
+	Delete(key string)
+}
+
+// Volatile restricts a policy to the keys having a time to live, the other ones being never evicted.
+// A cache without such keys then behaves like one without eviction.
+type Volatile struct {
+	policy Policy
+	// volatile holds the keys having a time to live, which are the only ones known by the wrapped policy.
+	volatile map[string]struct{}
+}
+
+// NewVolatile wraps a policy so that it only considers the keys having a time to live.
+func NewVolatile(policy Policy) *Volatile {
+	return &Volatile{
+		policy:   policy,
+		volatile: make(map[string]struct{}),
+	}
+}
+
+// SetExpiration tells the policy that the expiration time of a key changed, the zero time meaning it has none.
+func (v *Volatile) SetExpiration(key string, expireAt time.Time) {
+	_, ok := v.volatile[key]
+	switch {
+	case expireAt.IsZero() && ok:
+		delete(v.volatile, key)
+		v.policy.Delete(key)
+	case !expireAt.IsZero() && !ok:
+		v.volatile[key] = struct{}{}
+		v.policy.Add(key)
+	}
+}
+
+func (v *Volatile) Refresh(key string) {
+	if _, ok := v.volatile[key]; ok {
+		v.policy.Refresh(key)
+	}
+}
+
+// Evict evicts a key among the ones having a time to live.
+// It returns an empty string if there is none.
+func (v *Volatile) Evict() string {
+	if len(v.volatile) == 0 {
+		// no key has a time to live, so there is nothing to evict
+		return ""
+	}
+	key := v.policy.Evict()
+	delete(v.volatile, key)
+	return key
+}
+
+// Add does nothing as keys are only considered once they get a time to live.
+func (v *Volatile) Add(string) {}
+
+func (v *Volatile) Delete(key string) {
+	if _, ok := v.volatile[key]; ok {
+		delete(v.volatile, key)
+		v.policy.Delete(key)
+	}
+}
+
+type ttlItem struct {
+	key      string
+	expireAt time.Time
+	index    int
+}
+
+// ttlQueue is a min-heap of keys ordered by expiration time.
+type ttlQueue []*ttlItem
+
+//goland:noinspection GoMixedReceiverTypes
+func (q ttlQueue) Len() int { return len(q) }
+
+//goland:noinspection GoMixedReceiverTypes
+func (q ttlQueue) Less(i, j int) bool { return q[i].expireAt.Before(q[j].expireAt) }
+
+//goland:noinspection GoMixedReceiverTypes
+func (q ttlQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+//goland:noinspection GoMixedReceiverTypes
+func (q *ttlQueue) Push(x any) {
+	item := x.(*ttlItem)
+	item.index = len(*q)
+	*q = append(*q, item)
+}
+
+//goland:noinspection GoMixedReceiverTypes
+func (q *ttlQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
+
+// TTL evicts the key which expires the soonest, among the ones having a time to live.
+type TTL struct {
+	queue  ttlQueue
+	lookup map[string]*ttlItem
+}
+
+func NewTTL() *TTL {
+	return &TTL{
+		lookup: make(map[string]*ttlItem),
+	}
+}
+
+// SetExpiration tells the policy that the expiration time of a key changed, the zero time meaning it has none.
+func (t *TTL) SetExpiration(key string, expireAt time.Time) {
+	item, ok := t.lookup[key]
+	switch {
+	case expireAt.IsZero():
+		t.Delete(key)
+	case ok:
+		item.expireAt = expireAt
+		heap.Fix(&t.queue, item.index)
+	default:
+		item = &ttlItem{key: key, expireAt: expireAt}
+		heap.Push(&t.queue, item)
+		t.lookup[key] = item
+	}
+}
+
+// Refresh does nothing as accesses do not matter to this policy.
+func (t *TTL) Refresh(string) {}
+
+// Evict evicts the key which expires the soonest. It returns an empty string if no key has a time to live.
+func (t *TTL) Evict() string {
+	if len(t.queue) == 0 {
+		return ""
+	}
+	item := heap.Pop(&t.queue).(*ttlItem)
+	delete(t.lookup, item.key)
+	return item.key
+}
+
+// Add does nothing as keys are only considered once they get a time to live.
+func (t *TTL) Add(string) {}
+
+func (t *TTL) Delete(key string) {
+	if item, ok := t.lookup[key]; ok {
+		heap.Remove(&t.queue, item.index)
+		delete(t.lookup, key)
+	}
+}
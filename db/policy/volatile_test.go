@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVolatile_Evict(t *testing.T) {
+	v := NewVolatile(NewLRU())
+	now := time.Now()
+	v.Add("persistent")
+	v.Add("volatile")
+	v.SetExpiration("volatile", now.Add(time.Minute))
+	v.Refresh("persistent")
+	assert.Equal(t, "volatile", v.Evict())
+	// the remaining key has no time to live
+	assert.Equal(t, "", v.Evict())
+
+	v.SetExpiration("persistent", now.Add(time.Minute))
+	v.SetExpiration("persistent", time.Time{})
+	assert.Equal(t, "", v.Evict())
+}
+
+func TestTTL_Evict(t *testing.T) {
+	p := NewTTL()
+	now := time.Now()
+	p.Add("persistent")
+	p.SetExpiration("late", now.Add(time.Hour))
+	p.SetExpiration("soon", now.Add(time.Minute))
+	p.SetExpiration("deleted", now.Add(time.Second))
+	p.Delete("deleted")
+	// expiration times can change
+	p.SetExpiration("late", now.Add(time.Second))
+
+	assert.Equal(t, "late", p.Evict())
+	assert.Equal(t, "soon", p.Evict())
+	assert.Equal(t, "", p.Evict())
+}
+
+func TestRandom_Evict(t *testing.T) {
+	r := NewRandom()
+	keys := map[string]bool{"first": true, "second": true, "third": true}
+	for key := range keys {
+		r.Add(key)
+	}
+	r.Delete("second")
+	delete(keys, "second")
+
+	for i := 0; i < 2; i++ {
+		evicted := r.Evict()
+		assert.True(t, keys[evicted])
+		delete(keys, evicted)
+	}
+	assert.Equal(t, "", r.Evict())
+}
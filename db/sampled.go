@@ -0,0 +1,193 @@
+package db
+
+import (
+	"math/rand"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// lruClockMax is the largest value of the 24 bits access clock stored in the entries, in seconds.
+	lruClockMax = 1<<24 - 1
+	// lfuInitVal is the counter of new keys, so that they are not evicted before having a chance to be accessed.
+	lfuInitVal = 5
+	// evictionPoolSize is the number of eviction candidates remembered between evictions.
+	evictionPoolSize = 16
+)
+
+// Tunables holds the settings of the sampled eviction policies. A server shares them between its caches.
+type Tunables struct {
+	// Samples is the number of keys sampled on each eviction.
+	Samples atomic.Int64
+	// LogFactor slows down the growth of the logarithmic access counters.
+	LogFactor atomic.Int64
+	// DecayTime is the number of minutes after which an access counter is decremented, 0 meaning never.
+	DecayTime atomic.Int64
+}
+
+// NewTunables returns the default settings of Redis: 5 samples, a log factor of 10 and a decay time of 1 minute.
+func NewTunables() *Tunables {
+	t := &Tunables{}
+	t.Samples.Store(5)
+	t.LogFactor.Store(10)
+	t.DecayTime.Store(1)
+	return t
+}
+
+// Sampled approximates LRU or LFU like Redis does, without any per key structure besides 24 bits in the entries.
+// In LRU mode, they hold the time of the last access in seconds. In LFU mode, they hold the time of the last
+// decrement in minutes on 16 bits and a logarithmic access counter on 8 bits, which decays over time.
+// On eviction, a few keys are sampled from the storage and the best candidates are kept in a pool
+// for the next evictions, which gets close to the exact policy with a fraction of its memory.
+// As it samples the storage of its cache, it is only usable once bound to it by NewCache.
+type Sampled struct {
+	cache *Cache
+	lfu   bool
+	// pool holds the best candidates found so far, by increasing score.
+	pool []poolEntry
+}
+
+type poolEntry struct {
+	key   string
+	score uint64
+}
+
+func newSampled(lfu bool) *Sampled {
+	return &Sampled{lfu: lfu, pool: make([]poolEntry, 0, evictionPoolSize)}
+}
+
+// Refresh does nothing: the cache records accesses in the entries themselves.
+func (s *Sampled) Refresh(string) {}
+
+// Add does nothing: the cache initializes the access metadata of the entries it creates.
+func (s *Sampled) Add(string) {}
+
+// Delete forgets a key if it is an eviction candidate.
+func (s *Sampled) Delete(key string) {
+	for i, candidate := range s.pool {
+		if candidate.key == key {
+			s.pool = append(s.pool[:i], s.pool[i+1:]...)
+			return
+		}
+	}
+}
+
+// Evict samples keys to refill the pool and evicts its best candidate still in the storage.
+// It returns an empty string if the storage is empty.
+func (s *Sampled) Evict() string {
+	c := s.cache
+	now := c.clock()
+	samples := c.tunables.Samples.Load()
+	// map iteration starts at a random position, so successive calls sample different keys
+	for key, e := range c.storage {
+		if samples <= 0 {
+			break
+		}
+		samples--
+		s.insert(key, s.score(e, now))
+	}
+	for len(s.pool) > 0 {
+		best := s.pool[len(s.pool)-1]
+		s.pool = s.pool[:len(s.pool)-1]
+		if _, ok := c.storage[best.key]; ok {
+			return best.key
+		}
+	}
+	return ""
+}
+
+// score tells how good an eviction candidate an entry is, the higher the better.
+func (s *Sampled) score(e *Entry, now time.Time) uint64 {
+	if s.lfu {
+		return 255 - uint64(s.cache.lfuDecay(e, now))
+	}
+	return uint64(idleTime(e, now) / time.Second)
+}
+
+// insert adds a candidate to the pool, replacing the worst one if the pool is full.
+func (s *Sampled) insert(key string, score uint64) {
+	s.Delete(key)
+	if len(s.pool) == evictionPoolSize {
+		if score <= s.pool[0].score {
+			return
+		}
+		s.pool = s.pool[1:]
+	}
+	i := sort.Search(len(s.pool), func(i int) bool { return s.pool[i].score >= score })
+	s.pool = append(s.pool, poolEntry{})
+	copy(s.pool[i+1:], s.pool[i:])
+	s.pool[i] = poolEntry{key: key, score: score}
+}
+
+// lruClock returns the access clock of a time, in seconds on 24 bits.
+func lruClock(now time.Time) uint32 {
+	return uint32(now.Unix()) & lruClockMax
+}
+
+// idleTime returns the time elapsed since the last access of an entry, in LRU mode.
+func idleTime(e *Entry, now time.Time) time.Duration {
+	// the clock wraps around every 194 days
+	elapsed := (lruClock(now) - e.access) & lruClockMax
+	return time.Duration(elapsed) * time.Second
+}
+
+// lfuMinutes returns the decrement clock of a time, in minutes on 16 bits.
+func lfuMinutes(now time.Time) uint32 {
+	return uint32(now.Unix()/60) & 0xFFFF
+}
+
+// lfuDecay returns the access counter of an entry once decremented for the decay periods elapsed
+// since its last decrement, in LFU mode.
+func (c *Cache) lfuDecay(e *Entry, now time.Time) uint8 {
+	counter := uint8(e.access & 0xFF)
+	decayTime := c.tunables.DecayTime.Load()
+	if decayTime <= 0 {
+		return counter
+	}
+	elapsed := (lfuMinutes(now) - e.access>>8) & 0xFFFF
+	periods := int64(elapsed) / decayTime
+	if periods >= int64(counter) {
+		return 0
+	}
+	return counter - uint8(periods)
+}
+
+// lfuIncrement increments an access counter with a probability decreasing as it grows,
+// so that 8 bits are enough to count millions of accesses.
+func (c *Cache) lfuIncrement(counter uint8) uint8 {
+	if counter == 255 {
+		return counter
+	}
+	base := float64(max(int(counter)-lfuInitVal, 0))
+	if rand.Float64() < 1/(base*float64(c.tunables.LogFactor.Load())+1) {
+		counter++
+	}
+	return counter
+}
+
+// recordAccess updates the access metadata of an entry. The caller must hold the lock.
+func (c *Cache) recordAccess(e *Entry) {
+	now := c.clock()
+	if c.usesLFU() {
+		e.access = lfuMinutes(now)<<8 | uint32(c.lfuIncrement(c.lfuDecay(e, now)))
+		return
+	}
+	e.access = lruClock(now)
+}
+
+// initAccess sets the access metadata of a new entry. The caller must hold the lock.
+func (c *Cache) initAccess(e *Entry) {
+	now := c.clock()
+	if c.usesLFU() {
+		e.access = lfuMinutes(now)<<8 | lfuInitVal
+		return
+	}
+	e.access = lruClock(now)
+}
+
+// usesLFU tells if the entries hold access counters rather than access times.
+func (c *Cache) usesLFU() bool {
+	s, ok := c.eviction.(*Sampled)
+	return ok && s.lfu
+}
@@ -0,0 +1,89 @@
+package db
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"testing"
+	"time"
+)
+
+// newSampledCache creates a cache using a sampled policy, whose clock is controlled by the test.
+func newSampledCache(t *testing.T, maxItems int64, policy string) (*Cache, *time.Time) {
+	c, err := NewCache(maxItems, policy)
+	if err != nil {
+		t.Fatalf("unable to create cache: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	c.clock = func() time.Time { return now }
+	return c, &now
+}
+
+func TestSampled_LRU(t *testing.T) {
+	c, now := newSampledCache(t, 4, "sampled-lru")
+	// sampling more keys than the cache holds makes the approximation exact
+	c.tunables.Samples.Store(10)
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.Set(fmt.Sprintf("key:%d", i), "value"))
+		*now = now.Add(time.Second)
+	}
+	c.Get("key:0")
+	*now = now.Add(time.Second)
+
+	idle, ok, err := c.IdleTime("key:1")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, idle)
+	_, _, err = c.Frequency("key:1")
+	assert.ErrorIs(t, err, gerror.ErrLFUNotSelected)
+
+	assert.NoError(t, c.Set("new", "value"))
+	assert.False(t, c.Exists("key:1"))
+	assert.True(t, c.Exists("key:0"))
+}
+
+func TestSampled_LFU(t *testing.T) {
+	c, now := newSampledCache(t, 2, "sampled-lfu")
+	c.tunables.Samples.Store(10)
+	// without log factor, every access increments the counter
+	c.tunables.LogFactor.Store(0)
+	assert.NoError(t, c.Set("popular", "value"))
+	assert.NoError(t, c.Set("rare", "value"))
+	assert.NoError(t, c.Set("other", "value"))
+	for i := 0; i < 10; i++ {
+		c.Get("popular")
+		c.Get("other")
+	}
+
+	freq, ok, err := c.Frequency("popular")
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, lfuInitVal+10, freq)
+	_, _, err = c.IdleTime("popular")
+	assert.ErrorIs(t, err, gerror.ErrLFUSelected)
+
+	assert.NoError(t, c.Set("new", "value"))
+	assert.False(t, c.Exists("rare"))
+
+	// counters are decremented once per decay period
+	*now = now.Add(3 * time.Minute)
+	freq, _, _ = c.Frequency("popular")
+	assert.Equal(t, lfuInitVal+10-3, freq)
+}
+
+func TestSampled_EvictionPool(t *testing.T) {
+	c, now := newSampledCache(t, 100, "sampled-lru")
+	s := c.eviction.(*Sampled)
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, c.Set(fmt.Sprintf("key:%d", i), "value"))
+		*now = now.Add(time.Second)
+	}
+	c.tunables.Samples.Store(20)
+	assert.Equal(t, "key:0", s.Evict())
+	// the best remaining candidates were kept
+	assert.Len(t, s.pool, evictionPoolSize-1)
+	// deleted keys are forgotten by the pool
+	c.Delete("key:0", "key:1")
+	assert.Len(t, s.pool, evictionPoolSize-2)
+	assert.Equal(t, "key:2", s.Evict())
+}
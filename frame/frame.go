@@ -21,6 +21,12 @@ var (
 // initialTemporaryBufferSize is the initial size of temporary buffers used in Deserialize methods.
 const initialTemporaryBufferSize = 1024
 
+// Protocol versions a client can speak. RESP3 adds types like Push and Map which RESP2 clients do not understand.
+const (
+	RESP2 = 2
+	RESP3 = 3
+)
+
 type Framer interface {
 	// Serialize returns a slice of bytes' representation of this frame.
 	// It produces a slice of bytes that is ready to be transferred other the network.
@@ -33,6 +39,34 @@ type Framer interface {
 	io.WriterTo
 }
 
+// ForProtocol adapts a frame to a protocol version. RESP3 frames are downgraded for RESP2 clients: maps are
+// flattened to arrays of alternating keys and values, nulls become null bulk strings and booleans integers.
+// Push frames are left alone, NewOutOfBand builds them for the right protocol in the first place.
+func ForProtocol(protocol int, f Framer) Framer {
+	if protocol >= RESP3 {
+		return f
+	}
+	switch f := f.(type) {
+	case *Null:
+		return &NullBulkString{}
+	case *Bool:
+		if f.value {
+			return NewInteger(1)
+		}
+		return NewInteger(0)
+	case *Map:
+		return ForProtocol(protocol, f.Flatten())
+	case *Array:
+		array := NewArray(f.size)
+		for _, element := range f.value {
+			_ = array.Append(ForProtocol(protocol, element))
+		}
+		return array
+	default:
+		return f
+	}
+}
+
 // Decode tries to read a frame from a buffer. It returns an error if no frame
 // can be read from the buffer.
 // In case an error occurs, the bytes read before getting the
@@ -59,13 +93,23 @@ func Decode(rd *bufio.Reader) (Framer, error) {
 	case ':':
 		return DecodeInteger(rd)
 	case '$':
+		if next, err := rd.Peek(2); err == nil && string(next) == "-1" {
+			return DecodeNullBulkString(rd)
+		}
 		return DecodeBulkString(rd)
 	case '#':
 		return DecodeBool(rd)
 	case '_':
 		return DecodeNull(rd)
 	case '*':
+		if next, err := rd.Peek(2); err == nil && string(next) == "-1" {
+			return DecodeNullArray(rd)
+		}
 		return DecodeArray(rd)
+	case '>':
+		return DecodePush(rd)
+	case '%':
+		return DecodeMap(rd)
 	default:
 		return nil, ErrUnknownFrameType
 	}
@@ -189,6 +233,30 @@ func DecodeBulkString(rd *bufio.Reader) (*BulkString, error) {
 	return &bs, nil
 }
 
+// DecodeNullBulkString decodes the RESP2 null from a buffer.
+func DecodeNullBulkString(rd *bufio.Reader) (*NullBulkString, error) {
+	w, err := simpleStringFromBuffer(rd)
+	if err != nil {
+		return nil, err
+	}
+	if w != "-1" {
+		return nil, ErrMalformedFrame
+	}
+	return &NullBulkString{}, nil
+}
+
+// DecodeNullArray decodes the RESP2 null array from a buffer.
+func DecodeNullArray(rd *bufio.Reader) (*NullArray, error) {
+	w, err := simpleStringFromBuffer(rd)
+	if err != nil {
+		return nil, err
+	}
+	if w != "-1" {
+		return nil, ErrMalformedFrame
+	}
+	return &NullArray{}, nil
+}
+
 // DecodeBool decodes a bool from a buffer.
 func DecodeBool(rd *bufio.Reader) (*Bool, error) {
 	w, err := simpleStringFromBuffer(rd)
@@ -236,3 +304,45 @@ func DecodeArray(rd *bufio.Reader) (*Array, error) {
 	}
 	return array, nil
 }
+
+// DecodePush decodes a Push frame from a buffer.
+func DecodePush(rd *bufio.Reader) (*Push, error) {
+	length, err := getInt(rd)
+	if err != nil {
+		return nil, err
+	}
+	push := NewPush(length)
+	for i := 0; i < length; i++ {
+		frame, err := Decode(rd)
+		if err != nil {
+			return nil, err
+		}
+		if err := push.Append(frame); err != nil {
+			return nil, err
+		}
+	}
+	return push, nil
+}
+
+// DecodeMap decodes a Map frame from a buffer.
+func DecodeMap(rd *bufio.Reader) (*Map, error) {
+	length, err := getInt(rd)
+	if err != nil {
+		return nil, err
+	}
+	m := NewMap(length)
+	for i := 0; i < length; i++ {
+		key, err := Decode(rd)
+		if err != nil {
+			return nil, err
+		}
+		value, err := Decode(rd)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Append(key, value); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -1 +1,60 @@
 package frame
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestForProtocol(t *testing.T) {
+	nested := NewArray(3)
+	_ = nested.Append(NewBulkString("value"))
+	_ = nested.Append(&Null{})
+	_ = nested.Append(&Bool{value: true})
+
+	info := NewMap(1)
+	_ = info.Append(NewBulkString("proto"), &Integer{value: 2})
+
+	tests := []struct {
+		name      string
+		give      Framer
+		wantRESP2 string
+	}{
+		{name: "null", give: &Null{}, wantRESP2: "$-1\r\n"},
+		{name: "true", give: &Bool{value: true}, wantRESP2: ":1\r\n"},
+		{name: "false", give: &Bool{value: false}, wantRESP2: ":0\r\n"},
+		{name: "map", give: info, wantRESP2: "*2\r\n$5\r\nproto\r\n:2\r\n"},
+		{name: "nested", give: nested, wantRESP2: "*3\r\n$5\r\nvalue\r\n$-1\r\n:1\r\n"},
+		{name: "bulk string", give: NewBulkString("value"), wantRESP2: "$5\r\nvalue\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ForProtocol(RESP2, tt.give).String(); got != tt.wantRESP2 {
+				t.Errorf("ForProtocol(RESP2) = %q, want %q", got, tt.wantRESP2)
+			}
+			if got := ForProtocol(RESP3, tt.give); got != tt.give {
+				t.Errorf("ForProtocol(RESP3) = %q, want the frame unchanged", got)
+			}
+		})
+	}
+}
+
+func TestDecode_NullBulkString(t *testing.T) {
+	f, err := Decode(bufio.NewReader(strings.NewReader("$-1\r\n")))
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if _, ok := f.(*NullBulkString); !ok {
+		t.Errorf("Decode() = %T, want *NullBulkString", f)
+	}
+}
+
+func TestDecode_NullArray(t *testing.T) {
+	f, err := Decode(bufio.NewReader(strings.NewReader("*-1\r\n")))
+	if err != nil {
+		t.Fatalf("Decode() failed: %v", err)
+	}
+	if _, ok := f.(*NullArray); !ok {
+		t.Errorf("Decode() = %T, want *NullArray", f)
+	}
+}
@@ -0,0 +1,80 @@
+package frame
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Map represents a RESP3 map. Entries keep their insertion order.
+type Map struct {
+	size    int
+	keys    []Framer
+	values  []Framer
+	indexes map[string]int
+}
+
+// NewMap creates a new Map expected to hold size entries, filled via Append.
+func NewMap(size int) *Map {
+	return &Map{
+		size:    size,
+		keys:    make([]Framer, 0, size),
+		values:  make([]Framer, 0, size),
+		indexes: make(map[string]int, size),
+	}
+}
+
+// Append adds a new entry to a Map. It fails when there is not enough capacity to add more.
+func (m *Map) Append(key Framer, value Framer) error {
+	if len(m.keys) >= m.size {
+		return ErrArrayIsFull
+	}
+	m.indexes[key.String()] = len(m.keys)
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+	return nil
+}
+
+// String provides a text representation of a Map frame.
+func (m *Map) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%%%d\r\n", len(m.keys)))
+	for i := range m.keys {
+		sb.WriteString(m.keys[i].String())
+		sb.WriteString(m.values[i].String())
+	}
+	return sb.String()
+}
+
+func (m *Map) Size() int {
+	return m.size
+}
+
+// Get returns the value associated with a key, or nil if there is none.
+func (m *Map) Get(key Framer) Framer {
+	i, ok := m.indexes[key.String()]
+	if !ok {
+		return nil
+	}
+	return m.values[i]
+}
+
+// Flatten returns the entries as an Array of alternating keys and values, which is how RESP2 represents maps.
+func (m *Map) Flatten() *Array {
+	array := NewArray(2 * len(m.keys))
+	for i := range m.keys {
+		_ = array.Append(m.keys[i])
+		_ = array.Append(m.values[i])
+	}
+	return array
+}
+
+func (m *Map) Serialize() []byte {
+	return []byte(m.String())
+}
+
+func (m *Map) WriteTo(w io.Writer) (int64, error) {
+	frameToBytes := m.Serialize()
+	count, err := w.Write(frameToBytes)
+	return int64(count), err
+}
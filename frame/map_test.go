@@ -0,0 +1,62 @@
+package frame
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestMap_String(t *testing.T) {
+	m := NewMap(2)
+	_ = m.Append(NewBulkString("proto"), &Integer{value: 3})
+	_ = m.Append(NewBulkString("mode"), NewBulkString("standalone"))
+
+	want := "%2\r\n$5\r\nproto\r\n:3\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n"
+	if got := m.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	wantFlat := "*4\r\n$5\r\nproto\r\n:3\r\n$4\r\nmode\r\n$10\r\nstandalone\r\n"
+	if got := m.Flatten().String(); got != wantFlat {
+		t.Errorf("Flatten() = %q, want %q", got, wantFlat)
+	}
+	if err := m.Append(NewBulkString("extra"), &Null{}); err != ErrArrayIsFull {
+		t.Errorf("Append() on a full map should fail with %v, got %v", ErrArrayIsFull, err)
+	}
+}
+
+func TestMap_DecodeMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{name: "valid map", give: "%2\r\n$5\r\nproto\r\n:3\r\n$2\r\nid\r\n:7\r\n", key: "id", want: ":7\r\n"},
+		{name: "empty map", give: "%0\r\n", key: "id"},
+		{name: "missing value", give: "%1\r\n$5\r\nproto\r\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Decode(bufio.NewReader(strings.NewReader(tt.give)))
+			if err != nil {
+				if tt.wantErr {
+					return
+				}
+				t.Fatalf("Decode() unexpected gerror = %v", err)
+			} else if tt.wantErr {
+				t.Fatalf("Decode() expected gerror but got none.")
+			}
+			value := f.(*Map).Get(NewBulkString(tt.key))
+			if tt.want == "" {
+				if value != nil {
+					t.Errorf("Get() = %v, want nil", value)
+				}
+				return
+			}
+			if value == nil || value.String() != tt.want {
+				t.Errorf("Get() = %v, want %q", value, tt.want)
+			}
+		})
+	}
+}
@@ -20,3 +20,41 @@ func (n *Null) WriteTo(w io.Writer) (int64, error) {
 	count, err := w.Write(frameToBytes)
 	return int64(count), err
 }
+
+// NullBulkString is how RESP2 represents a null: a bulk string of length -1.
+type NullBulkString struct{}
+
+func (n *NullBulkString) Serialize() []byte {
+	return []byte(n.String())
+}
+
+// String provides a text representation of a NullBulkString frame.
+func (n *NullBulkString) String() string {
+	return "$-1\r\n"
+}
+
+// WriteTo writes a frame to an io.reader.
+func (n *NullBulkString) WriteTo(w io.Writer) (int64, error) {
+	frameToBytes := n.Serialize()
+	count, err := w.Write(frameToBytes)
+	return int64(count), err
+}
+
+// NullArray is how RESP2 represents a null array, replied by EXEC when a watched key was modified.
+type NullArray struct{}
+
+func (n *NullArray) Serialize() []byte {
+	return []byte(n.String())
+}
+
+// String provides a text representation of a NullArray frame.
+func (n *NullArray) String() string {
+	return "*-1\r\n"
+}
+
+// WriteTo writes a frame to an io.reader.
+func (n *NullArray) WriteTo(w io.Writer) (int64, error) {
+	frameToBytes := n.Serialize()
+	count, err := w.Write(frameToBytes)
+	return int64(count), err
+}
@@ -0,0 +1,67 @@
+package frame
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Push represents out-of-band data sent by the server, like pub/sub messages.
+// It is encoded like an Array but with its own type byte, so that RESP3 clients can tell it apart from replies.
+type Push struct {
+	size  int
+	value []Framer
+}
+
+// NewPush creates a new Push frame expected to hold size elements, filled via Append.
+func NewPush(size int) *Push {
+	value := make([]Framer, 0, size)
+	return &Push{size: size, value: value}
+}
+
+// NewOutOfBand builds a frame carrying out-of-band data for a given protocol version.
+// RESP2 has no Push type, such data is sent as an Array there.
+func NewOutOfBand(protocol int, elements ...Framer) Framer {
+	if protocol >= RESP3 {
+		return &Push{size: len(elements), value: elements}
+	}
+	return &Array{size: len(elements), value: elements}
+}
+
+// Append adds a new frame to a Push. It fails when there is not enough capacity to add more.
+func (p *Push) Append(f Framer) error {
+	if len(p.value) >= p.size {
+		return ErrArrayIsFull
+	}
+	p.value = append(p.value, f)
+	return nil
+}
+
+// String provides a text representation of a Push frame.
+func (p *Push) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf(">%d\r\n", len(p.value)))
+	for _, f := range p.value {
+		sb.WriteString(f.String())
+	}
+	return sb.String()
+}
+
+func (p *Push) Size() int {
+	return p.size
+}
+
+// Get return the Frame at position i in the Push. It would panic if the index is out of bounds.
+func (p *Push) Get(i int) Framer {
+	return p.value[i]
+}
+
+func (p *Push) Serialize() []byte {
+	return []byte(p.String())
+}
+
+func (p *Push) WriteTo(w io.Writer) (int64, error) {
+	frameToBytes := p.Serialize()
+	count, err := w.Write(frameToBytes)
+	return int64(count), err
+}
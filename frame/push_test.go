@@ -0,0 +1,89 @@
+package frame
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPush_String(t *testing.T) {
+	message := NewPush(3)
+	_ = message.Append(NewBulkString("message"))
+	_ = message.Append(NewBulkString("news"))
+	_ = message.Append(NewBulkString("hello"))
+
+	tests := []struct {
+		name string
+		give *Push
+		want string
+	}{
+		{name: "empty push", give: NewPush(0), want: ">0\r\n"},
+		{name: "pub/sub message", give: message, want: ">3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$5\r\nhello\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.give.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPush_DecodePush(t *testing.T) {
+	message := NewPush(2)
+	_ = message.Append(NewBulkString("invalidate"))
+	_ = message.Append(&Integer{value: 1})
+
+	tests := []struct {
+		name      string
+		give      *bufio.Reader
+		wantFrame *Push
+		wantErr   bool
+	}{
+		{
+			name:      "mixed frame types",
+			give:      bufio.NewReader(strings.NewReader(">2\r\n$10\r\ninvalidate\r\n:1\r\n")),
+			wantFrame: message,
+		},
+		{
+			name:    "truncated push",
+			give:    bufio.NewReader(strings.NewReader(">2\r\n$10\r\ninvalidate\r\n")),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Decode(tt.give)
+			if err != nil {
+				if tt.wantErr {
+					return
+				}
+				t.Fatalf("Decode() unexpected gerror = %v", err)
+			} else if tt.wantErr {
+				t.Fatalf("Decode() expected gerror but got none.")
+			}
+			if !reflect.DeepEqual(f, tt.wantFrame) {
+				t.Errorf("Decode() got = %v, want %v", f, tt.wantFrame)
+			}
+		})
+	}
+}
+
+func TestNewOutOfBand(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol int
+		want     string
+	}{
+		{name: "RESP2 uses arrays", protocol: RESP2, want: "*1\r\n$4\r\nnews\r\n"},
+		{name: "RESP3 uses push", protocol: RESP3, want: ">1\r\n$4\r\nnews\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewOutOfBand(tt.protocol, NewBulkString("news")).String(); got != tt.want {
+				t.Errorf("NewOutOfBand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -13,4 +13,71 @@ var (
 	ErrInvalidCmdName = errors.New("command not found")
 
 	ErrInvalidCmdArgs = errors.New("cmd line args are not valid")
+	ErrWrongArity     = errors.New("wrong number of arguments")
+
+	ErrInvalidDBIndex  = errors.New("invalid DB index")
+	ErrDBIndexOutRange = errors.New("DB index is out of range")
+	ErrSameObject      = errors.New("source and destination objects are the same")
+	ErrSyntax          = errors.New("syntax error")
+)
+
+var (
+	ErrNestedMulti         = errors.New("MULTI calls can not be nested")
+	ErrExecWithoutMulti    = errors.New("EXEC without MULTI")
+	ErrDiscardWithoutMulti = errors.New("DISCARD without MULTI")
+	ErrWatchInsideMulti    = errors.New("WATCH inside MULTI is not allowed")
+	ErrNotAllowedInMulti   = errors.New("Command not allowed inside a transaction")
+	ErrExecAbort           = errors.New("EXECABORT Transaction discarded because of previous errors.")
+)
+
+var (
+	ErrSubscriberMode  = errors.New("only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context")
+	ErrUnknownSubCmd   = errors.New("unknown subcommand")
+	ErrNoProto         = errors.New("NOPROTO unsupported protocol version")
+	ErrInvalidProtocol = errors.New("Protocol version is not an integer or out of range")
+)
+
+var (
+	ErrUnknownConfig   = errors.New("Unknown option or number of arguments for CONFIG SET -")
+	ErrImmutableConfig = errors.New("can't set immutable config")
+	ErrNotInteger      = errors.New("value is not an integer or out of range")
+	ErrInvalidExpire   = errors.New("invalid expire time")
+	ErrOOM             = errors.New("OOM command not allowed when used memory > 'maxmemory'.")
+	ErrLFUNotSelected  = errors.New("An LFU maxmemory policy is not selected, access frequency not tracked")
+	ErrLFUSelected     = errors.New("An LFU maxmemory policy is selected, idle time not tracked")
+	ErrNotFloat        = errors.New("value is not a valid float")
+	ErrNoSuchKey       = errors.New("no such key")
+	ErrProtectedCmd    = errors.New("command not allowed. If the enable-debug-command option is set to \"local\", " +
+		"you can run it from a local connection, otherwise you need to set this option in the configuration file, " +
+		"and then restart the server.")
+)
+
+var (
+	ErrRedirectNotFound  = errors.New("The client ID you want redirect to does not exist")
+	ErrPrefixNeedsBCast  = errors.New("PREFIX option requires BCAST mode to be enabled")
+	ErrOptInAndOptOut    = errors.New("You can't use OPTIN and OPTOUT at the same time")
+	ErrOptWithBCast      = errors.New("OPTIN and OPTOUT are not compatible with BCAST")
+	ErrCachingMode       = errors.New("CLIENT CACHING can be called only when the client is in tracking mode with OPTIN or OPTOUT mode enabled")
+	ErrInvalidClientName = errors.New("Client names cannot contain spaces, newlines or special characters.")
+	ErrNoSuchClient      = errors.New("No such client")
+	ErrInvalidTimeout    = errors.New("timeout is not an integer or out of range")
+	ErrInvalidClientType = errors.New("Unknown client type")
+	ErrMaxClients        = errors.New("max number of clients reached")
+	ErrOutputBufferLimit = errors.New("output buffer limit reached")
+)
+
+var (
+	ErrNoAuth          = errors.New("NOAUTH Authentication required.")
+	ErrWrongPass       = errors.New("WRONGPASS invalid username-password pair or user is disabled.")
+	ErrNoPermCommand   = errors.New("NOPERM")
+	ErrNoPermKey       = errors.New("NOPERM No permissions to access a key")
+	ErrNoPermChannel   = errors.New("NOPERM No permissions to access a channel")
+	ErrHelloNoAuth     = errors.New("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+	ErrAuthNotEnabled  = errors.New("AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+	ErrDefaultUser     = errors.New("The 'default' user cannot be removed")
+	ErrUnknownCommand  = errors.New("Unknown command")
+	ErrUnknownCategory = errors.New("Unknown command category")
+	ErrInvalidPassHash = errors.New("The password hash must be exactly 64 characters and contain only lowercase hexadecimal characters")
+	ErrNoSuchPassword  = errors.New("No such password")
+	ErrInvalidUsername = errors.New("Usernames can't contain spaces or null characters")
 )
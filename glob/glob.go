@@ -0,0 +1,99 @@
+// Package glob implements the glob-style patterns used by Redis for channel and key patterns.
+// Unlike path.Match, '*' matches any sequence of characters including '/'.
+// Supported syntax:
+//
+//	'*'    any sequence of characters, including an empty one
+//	'?'    exactly one character
+//	[abc]  one character of the set, [^abc] or [!abc] negates it and [a-z] defines a range
+//	\x     the character x, taken literally
+package glob
+
+// Match reports whether s matches the pattern. A malformed pattern never matches.
+func Match(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			// collapse consecutive stars, a trailing one matches everything
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if Match(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			rest, ok := matchClass(pattern[1:], s[0])
+			if !ok {
+				return false
+			}
+			pattern, s = rest, s[1:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || pattern[0] != s[0] {
+				return false
+			}
+			pattern, s = pattern[1:], s[1:]
+		}
+	}
+	return len(s) == 0
+}
+
+// matchClass matches a character against a class, the pattern starting right after the opening bracket.
+// It returns the pattern following the class and whether the character belongs to it.
+func matchClass(pattern string, c byte) (string, bool) {
+	negate := false
+	if len(pattern) > 0 && (pattern[0] == '^' || pattern[0] == '!') {
+		negate = true
+		pattern = pattern[1:]
+	}
+	matched := false
+	for first := true; ; first = false {
+		if len(pattern) == 0 {
+			// unterminated class
+			return "", false
+		}
+		if pattern[0] == ']' && !first {
+			pattern = pattern[1:]
+			break
+		}
+		lo := pattern[0]
+		if lo == '\\' && len(pattern) > 1 {
+			pattern = pattern[1:]
+			lo = pattern[0]
+		}
+		pattern = pattern[1:]
+		hi := lo
+		if len(pattern) > 1 && pattern[0] == '-' && pattern[1] != ']' {
+			hi = pattern[1]
+			if hi == '\\' && len(pattern) > 2 {
+				pattern = pattern[1:]
+				hi = pattern[1]
+			}
+			pattern = pattern[2:]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+	return pattern, matched != negate
+}
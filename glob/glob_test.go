@@ -0,0 +1,39 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		give    string
+		want    bool
+	}{
+		{pattern: "news", give: "news", want: true},
+		{pattern: "news", give: "new", want: false},
+		{pattern: "*", give: "", want: true},
+		{pattern: "news.*", give: "news.tech", want: true},
+		{pattern: "news.*", give: "news", want: false},
+		{pattern: "*/*", give: "a/b/c", want: true},
+		{pattern: "h?llo", give: "hello", want: true},
+		{pattern: "h?llo", give: "hllo", want: false},
+		{pattern: "h[ae]llo", give: "hallo", want: true},
+		{pattern: "h[ae]llo", give: "hillo", want: false},
+		{pattern: "h[^e]llo", give: "hallo", want: true},
+		{pattern: "h[^e]llo", give: "hello", want: false},
+		{pattern: "h[a-c]llo", give: "hbllo", want: true},
+		{pattern: "h[c-a]llo", give: "hbllo", want: true},
+		{pattern: "h[a-c]llo", give: "hdllo", want: false},
+		{pattern: `h\*llo`, give: "h*llo", want: true},
+		{pattern: `h\*llo`, give: "hello", want: false},
+		{pattern: "h[ab", give: "ha", want: false},
+		{pattern: "a*b*c", give: "axxbyyc", want: true},
+		{pattern: "a*b*c", give: "axxbyy", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"/"+tt.give, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.give); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.give, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package monitor
+
+import (
+	"math/bits"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Events whose latency is monitored.
+const (
+	EventCommand     = "command"
+	EventFastCommand = "fast-command"
+	EventExpireCycle = "expire-cycle"
+)
+
+const (
+	// latencyHistoryLen is the number of samples kept for each event, at most one per second.
+	latencyHistoryLen = 160
+	// histogramBuckets is the number of buckets of the command histograms, the last one being 2^(n-1) µs.
+	histogramBuckets = 40
+)
+
+// LatencySample is the highest latency of an event during a second.
+type LatencySample struct {
+	Time    time.Time
+	Latency time.Duration
+}
+
+// EventLatency summarizes the latency of an event, as LATENCY LATEST shows it.
+type EventLatency struct {
+	Event  string
+	Latest LatencySample
+	Max    time.Duration
+}
+
+// eventHistory holds the latest samples of an event, oldest first.
+type eventHistory struct {
+	samples []LatencySample
+	max     time.Duration
+}
+
+// Histogram is the distribution of the run time of a command. Buckets are powers of 2 of microseconds.
+type Histogram struct {
+	Command string
+	Calls   int64
+	counts  [histogramBuckets]int64
+}
+
+// commandHistogram counts the calls of a command by bucket. Its counters are updated without locking.
+type commandHistogram struct {
+	calls  atomic.Int64
+	counts [histogramBuckets]atomic.Int64
+}
+
+// snapshot returns the current counts of the histogram.
+func (h *commandHistogram) snapshot(command string) Histogram {
+	snapshot := Histogram{Command: command, Calls: h.calls.Load()}
+	for i := range h.counts {
+		snapshot.counts[i] = h.counts[i].Load()
+	}
+	return snapshot
+}
+
+// HistogramBucket counts the calls which ran in up to UpperBound.
+type HistogramBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+// Cumulative returns the number of calls which ran in up to the bound of each bucket, from the first bucket
+// holding calls to the last one.
+func (h *Histogram) Cumulative() []HistogramBucket {
+	first, last := -1, -1
+	for i, n := range h.counts {
+		if n > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	buckets := make([]HistogramBucket, 0, last-first+1)
+	total := int64(0)
+	for i := first; i <= last; i++ {
+		total += h.counts[i]
+		buckets = append(buckets, HistogramBucket{UpperBound: time.Duration(1<<i) * time.Microsecond, Count: total})
+	}
+	return buckets
+}
+
+// bucketOf returns the index of the smallest bucket a duration fits in.
+func bucketOf(d time.Duration) int {
+	us := d.Microseconds()
+	if us <= 1 {
+		return 0
+	}
+	return min(bits.Len64(uint64(us-1)), histogramBuckets-1)
+}
+
+// Latency monitors the events taking longer than a threshold, and the distribution of the run time of each
+// command. Commands are measured without locking, the events being locked only to record a sample.
+type Latency struct {
+	threshold atomic.Int64
+	mu        sync.Mutex
+	events    map[string]*eventHistory
+	// commands holds a *commandHistogram by command name.
+	commands sync.Map
+}
+
+// NewLatency creates a monitor recording the events slower than threshold. A threshold of 0 disables the
+// monitoring of events, but not the histograms of the commands.
+func NewLatency(threshold time.Duration) *Latency {
+	l := &Latency{events: make(map[string]*eventHistory)}
+	l.threshold.Store(int64(threshold))
+	return l
+}
+
+// Threshold returns the latency over which events are recorded.
+func (l *Latency) Threshold() time.Duration {
+	return time.Duration(l.threshold.Load())
+}
+
+// SetThreshold changes the latency over which events are recorded.
+func (l *Latency) SetThreshold(threshold time.Duration) {
+	l.threshold.Store(int64(threshold))
+}
+
+// AddSample records the latency of an event if it reaches the threshold. Samples of the same second are merged,
+// keeping the highest latency.
+func (l *Latency) AddSample(event string, latency time.Duration) {
+	threshold := time.Duration(l.threshold.Load())
+	if threshold <= 0 || latency < threshold {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	history, ok := l.events[event]
+	if !ok {
+		history = &eventHistory{}
+		l.events[event] = history
+	}
+	history.max = max(history.max, latency)
+	now := time.Now().Truncate(time.Second)
+	if n := len(history.samples); n > 0 && history.samples[n-1].Time.Equal(now) {
+		history.samples[n-1].Latency = max(history.samples[n-1].Latency, latency)
+		return
+	}
+	history.samples = append(history.samples, LatencySample{Time: now, Latency: latency})
+	if len(history.samples) > latencyHistoryLen {
+		history.samples = history.samples[1:]
+	}
+}
+
+// Latest returns the latest and highest latency of each event, sorted by event.
+func (l *Latency) Latest() []EventLatency {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	latest := make([]EventLatency, 0, len(l.events))
+	for event, history := range l.events {
+		latest = append(latest, EventLatency{
+			Event:  event,
+			Latest: history.samples[len(history.samples)-1],
+			Max:    history.max,
+		})
+	}
+	sort.Slice(latest, func(i, j int) bool { return latest[i].Event < latest[j].Event })
+	return latest
+}
+
+// History returns the samples of an event, oldest first.
+func (l *Latency) History(event string) []LatencySample {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	history, ok := l.events[event]
+	if !ok {
+		return nil
+	}
+	samples := make([]LatencySample, len(history.samples))
+	copy(samples, history.samples)
+	return samples
+}
+
+// Reset forgets the samples of events, or of all of them if none is given. It returns the number of events
+// which had samples.
+func (l *Latency) Reset(events ...string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(events) == 0 {
+		n := len(l.events)
+		l.events = make(map[string]*eventHistory)
+		return n
+	}
+	n := 0
+	for _, event := range events {
+		if _, ok := l.events[event]; ok {
+			delete(l.events, event)
+			n++
+		}
+	}
+	return n
+}
+
+// RecordCommand adds a call of a command which ran for a duration to its histogram.
+func (l *Latency) RecordCommand(command string, d time.Duration) {
+	h, ok := l.commands.Load(command)
+	if !ok {
+		h, _ = l.commands.LoadOrStore(command, new(commandHistogram))
+	}
+	histogram := h.(*commandHistogram)
+	histogram.calls.Add(1)
+	histogram.counts[bucketOf(d)].Add(1)
+}
+
+// Histograms returns the histograms of commands, or of all the commands called if none is given, sorted by
+// command. Commands never called are left out.
+func (l *Latency) Histograms(commands ...string) []Histogram {
+	histograms := make([]Histogram, 0, len(commands))
+	if len(commands) == 0 {
+		l.commands.Range(func(command, h any) bool {
+			histograms = append(histograms, h.(*commandHistogram).snapshot(command.(string)))
+			return true
+		})
+	}
+	for _, command := range commands {
+		if h, ok := l.commands.Load(command); ok {
+			histograms = append(histograms, h.(*commandHistogram).snapshot(command))
+		}
+	}
+	sort.Slice(histograms, func(i, j int) bool { return histograms[i].Command < histograms[j].Command })
+	return histograms
+}
@@ -0,0 +1,86 @@
+package monitor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatency_AddSample(t *testing.T) {
+	l := NewLatency(0)
+	l.AddSample(EventCommand, time.Second)
+	assert.Empty(t, l.Latest(), "a threshold of 0 disables the monitoring of events")
+
+	l.SetThreshold(10 * time.Millisecond)
+	l.AddSample(EventCommand, time.Millisecond)
+	assert.Empty(t, l.Latest(), "samples under the threshold should be ignored")
+
+	l.AddSample(EventCommand, 20*time.Millisecond)
+	l.AddSample(EventCommand, 50*time.Millisecond)
+	l.AddSample(EventCommand, 30*time.Millisecond)
+	l.AddSample(EventExpireCycle, 15*time.Millisecond)
+
+	latest := l.Latest()
+	assert.Len(t, latest, 2)
+	assert.Equal(t, EventCommand, latest[0].Event)
+	assert.Equal(t, 50*time.Millisecond, latest[0].Max)
+	assert.Equal(t, EventExpireCycle, latest[1].Event)
+
+	history := l.History(EventCommand)
+	assert.NotEmpty(t, history)
+	assert.LessOrEqual(t, len(history), 2, "samples of the same second should be merged")
+	assert.Equal(t, 50*time.Millisecond, history[0].Latency)
+	assert.Nil(t, l.History("unknown"))
+}
+
+func TestLatency_Reset(t *testing.T) {
+	l := NewLatency(time.Millisecond)
+	l.AddSample(EventCommand, time.Second)
+	l.AddSample(EventFastCommand, time.Second)
+	l.AddSample(EventExpireCycle, time.Second)
+
+	assert.Equal(t, 1, l.Reset(EventCommand, "unknown"))
+	assert.Len(t, l.Latest(), 2)
+	assert.Equal(t, 2, l.Reset())
+	assert.Empty(t, l.Latest())
+}
+
+func TestLatency_Histograms(t *testing.T) {
+	l := NewLatency(0)
+	l.RecordCommand("get", 500*time.Nanosecond)
+	l.RecordCommand("get", 3*time.Microsecond)
+	l.RecordCommand("get", 4*time.Microsecond)
+	l.RecordCommand("set", time.Millisecond)
+
+	histograms := l.Histograms("set", "get", "del")
+	assert.Len(t, histograms, 2, "commands never called should be left out")
+	assert.Equal(t, "get", histograms[0].Command)
+	assert.Equal(t, int64(3), histograms[0].Calls)
+	assert.Equal(t, []HistogramBucket{
+		{UpperBound: time.Microsecond, Count: 1},
+		{UpperBound: 2 * time.Microsecond, Count: 1},
+		{UpperBound: 4 * time.Microsecond, Count: 3},
+	}, histograms[0].Cumulative())
+	assert.Equal(t, []HistogramBucket{{UpperBound: 1024 * time.Microsecond, Count: 1}}, histograms[1].Cumulative())
+
+	assert.Len(t, l.Histograms(), 2)
+}
+
+func TestLatency_ConcurrentRecordCommand(t *testing.T) {
+	l := NewLatency(time.Hour)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				l.RecordCommand("get", time.Microsecond)
+				l.AddSample(EventCommand, time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(8000), l.Histograms("get")[0].Calls)
+	assert.Empty(t, l.Latest(), "samples under the threshold should not be recorded")
+}
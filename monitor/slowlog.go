@@ -0,0 +1,155 @@
+// Package monitor records the commands which take too long to run, and the latency of the server.
+package monitor
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// slowLogMaxArgs is the number of arguments of a command kept in an entry, the others being summarized.
+	slowLogMaxArgs = 32
+	// slowLogMaxArgLen is the number of bytes of an argument kept in an entry.
+	slowLogMaxArgLen = 128
+)
+
+// SlowLogEntry records a command which ran slower than the threshold of the log.
+type SlowLogEntry struct {
+	ID       int64
+	Time     time.Time
+	Duration time.Duration
+	// Args are the name and arguments of the command, possibly truncated.
+	Args       []string
+	ClientAddr string
+	ClientName string
+}
+
+// SlowLog keeps the most recent commands which ran slower than a threshold, newest first, like SLOWLOG GET
+// shows them. The threshold is read without locking, the log being locked only to record a slow command.
+type SlowLog struct {
+	threshold atomic.Int64
+	mu        sync.Mutex
+	// entries is a ring buffer of up to maxLen entries, the oldest being at start once it is full.
+	entries []SlowLogEntry
+	start   int
+	nextID  int64
+	maxLen  int
+}
+
+// NewSlowLog creates a log of the commands slower than threshold, keeping up to maxLen of them.
+// A negative threshold disables the log, and 0 records every command.
+func NewSlowLog(threshold time.Duration, maxLen int) *SlowLog {
+	l := &SlowLog{maxLen: maxLen}
+	l.threshold.Store(int64(threshold))
+	return l
+}
+
+// Threshold returns the duration over which commands are recorded.
+func (l *SlowLog) Threshold() time.Duration {
+	return time.Duration(l.threshold.Load())
+}
+
+// SetThreshold changes the duration over which commands are recorded.
+func (l *SlowLog) SetThreshold(threshold time.Duration) {
+	l.threshold.Store(int64(threshold))
+}
+
+// MaxLen returns the number of entries the log keeps.
+func (l *SlowLog) MaxLen() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.maxLen
+}
+
+// SetMaxLen changes the number of entries the log keeps, dropping the oldest ones over it.
+func (l *SlowLog) SetMaxLen(maxLen int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxLen = maxLen
+	entries := l.newest(len(l.entries))
+	if len(entries) > maxLen {
+		entries = entries[:max(maxLen, 0)]
+	}
+	slices.Reverse(entries)
+	l.entries, l.start = entries, 0
+}
+
+// IsSlow tells if a command which ran for a duration should be recorded.
+func (l *SlowLog) IsSlow(d time.Duration) bool {
+	threshold := time.Duration(l.threshold.Load())
+	return threshold >= 0 && d >= threshold
+}
+
+// Add records a slow command. Its arguments are truncated so that a large value does not bloat the log.
+func (l *SlowLog) Add(entry SlowLogEntry) {
+	entry.Args = truncateArgs(entry.Args)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry.ID = l.nextID
+	l.nextID++
+	switch {
+	case l.maxLen <= 0:
+	case len(l.entries) < l.maxLen:
+		l.entries = append(l.entries, entry)
+	default:
+		// the log is full, the entry replaces the oldest one
+		l.entries[l.start] = entry
+		l.start = (l.start + 1) % len(l.entries)
+	}
+}
+
+// Entries returns up to count entries, newest first. A negative count returns all of them.
+func (l *SlowLog) Entries(count int) []SlowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if count < 0 || count > len(l.entries) {
+		count = len(l.entries)
+	}
+	return l.newest(count)
+}
+
+// newest returns the count newest entries, newest first. The caller must hold the lock.
+func (l *SlowLog) newest(count int) []SlowLogEntry {
+	entries := make([]SlowLogEntry, count)
+	n := len(l.entries)
+	for i := range entries {
+		entries[i] = l.entries[(l.start+n-1-i)%n]
+	}
+	return entries
+}
+
+// Len returns the number of entries in the log.
+func (l *SlowLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// Reset empties the log.
+func (l *SlowLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries, l.start = nil, 0
+}
+
+// truncateArgs keeps the first arguments of a command and their first bytes, like Redis does.
+func truncateArgs(args []string) []string {
+	kept := args
+	if len(args) > slowLogMaxArgs {
+		kept = args[:slowLogMaxArgs-1]
+	}
+	truncated := make([]string, 0, len(kept)+1)
+	for _, arg := range kept {
+		if len(arg) > slowLogMaxArgLen {
+			arg = fmt.Sprintf("%s... (%d more bytes)", arg[:slowLogMaxArgLen], len(arg)-slowLogMaxArgLen)
+		}
+		truncated = append(truncated, arg)
+	}
+	if len(args) > slowLogMaxArgs {
+		truncated = append(truncated, fmt.Sprintf("... (%d more arguments)", len(args)-len(kept)))
+	}
+	return truncated
+}
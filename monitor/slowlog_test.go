@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowLog_IsSlow(t *testing.T) {
+	l := NewSlowLog(10*time.Millisecond, 10)
+	assert.False(t, l.IsSlow(time.Millisecond))
+	assert.True(t, l.IsSlow(10*time.Millisecond))
+
+	l.SetThreshold(0)
+	assert.True(t, l.IsSlow(0), "a threshold of 0 records every command")
+
+	l.SetThreshold(-1)
+	assert.False(t, l.IsSlow(time.Hour), "a negative threshold disables the log")
+}
+
+func TestSlowLog_Add(t *testing.T) {
+	l := NewSlowLog(0, 3)
+	for _, name := range []string{"get", "set", "del", "incr"} {
+		l.Add(SlowLogEntry{Args: []string{name, "key"}, Duration: time.Millisecond})
+	}
+
+	assert.Equal(t, 3, l.Len(), "the oldest entries should be dropped")
+	entries := l.Entries(-1)
+	assert.Equal(t, int64(3), entries[0].ID)
+	assert.Equal(t, []string{"incr", "key"}, entries[0].Args, "entries should be returned newest first")
+	assert.Equal(t, "set", entries[2].Args[0])
+	assert.Len(t, l.Entries(2), 2)
+
+	l.SetMaxLen(1)
+	assert.Equal(t, 1, l.Len())
+
+	l.Reset()
+	assert.Equal(t, 0, l.Len())
+	l.Add(SlowLogEntry{Args: []string{"get"}})
+	assert.Equal(t, int64(4), l.Entries(1)[0].ID, "IDs should keep increasing after a reset")
+}
+
+func TestSlowLog_RingBuffer(t *testing.T) {
+	l := NewSlowLog(0, 3)
+	ids := func() []int64 {
+		ids := make([]int64, 0)
+		for _, e := range l.Entries(-1) {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	for i := 0; i < 8; i++ {
+		l.Add(SlowLogEntry{Args: []string{"get"}})
+	}
+	assert.Equal(t, []int64{7, 6, 5}, ids())
+
+	l.SetMaxLen(5)
+	l.Add(SlowLogEntry{Args: []string{"get"}})
+	assert.Equal(t, []int64{8, 7, 6, 5}, ids(), "the log should grow after a wrap around")
+	l.Add(SlowLogEntry{Args: []string{"get"}})
+	l.Add(SlowLogEntry{Args: []string{"get"}})
+	assert.Equal(t, []int64{10, 9, 8, 7, 6}, ids())
+
+	l.SetMaxLen(2)
+	assert.Equal(t, []int64{10, 9}, ids(), "the newest entries should be kept")
+	l.SetMaxLen(0)
+	l.Add(SlowLogEntry{Args: []string{"get"}})
+	assert.Equal(t, 0, l.Len())
+}
+
+func TestSlowLog_TruncateArgs(t *testing.T) {
+	args := []string{"rpush", strings.Repeat("a", slowLogMaxArgLen+5)}
+	for i := 0; i < 40; i++ {
+		args = append(args, "v")
+	}
+	truncated := truncateArgs(args)
+
+	assert.Len(t, truncated, slowLogMaxArgs)
+	assert.Equal(t, strings.Repeat("a", slowLogMaxArgLen)+"... (5 more bytes)", truncated[1])
+	assert.Equal(t, "... (11 more arguments)", truncated[slowLogMaxArgs-1])
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/gerror"
+	"slices"
+)
+
+// knownCommands tells the ACL which commands and categories exist.
+type knownCommands struct{}
+
+func (knownCommands) HasCommand(name string) bool {
+	_, ok := command.Lookup(name)
+	return ok
+}
+
+func (knownCommands) HasCategory(category string) bool {
+	return slices.Contains(command.Categories(), category)
+}
+
+// newACL creates the users of the server from the ACL file and the password of the default user, if configured.
+func newACL(config Config) (*acl.ACL, error) {
+	users := acl.New(knownCommands{})
+	if config.ACLFile != "" {
+		if err := users.LoadFile(config.ACLFile); err != nil {
+			return nil, fmt.Errorf("unable to load ACL file %s: %w", config.ACLFile, err)
+		}
+	}
+	if config.RequirePass != "" {
+		if err := users.SetUser(acl.DefaultUser, "resetpass", ">"+config.RequirePass); err != nil {
+			return nil, err
+		}
+	}
+	return users, nil
+}
+
+// authorize is the outermost middleware. It rejects the commands of the clients which are not authenticated,
+// except the ones authenticating them, and the commands, keys and channels the user of the client is not
+// allowed to access. Denials are recorded in the ACL log.
+func (s *Server) authorize(next Handler) Handler {
+	return func(call *Call) error {
+		if err := s.checkPermissions(call); err != nil {
+			return err
+		}
+		return next(call)
+	}
+}
+
+// checkPermissions checks that the client is authenticated as a user allowed to run a command on its keys and
+// channels. The keys are located by the spec of the command, and checked for writing if the command writes.
+func (s *Server) checkPermissions(call *Call) error {
+	if call.Spec == nil || call.Spec.HasFlag(command.FlagNoAuth) {
+		return nil
+	}
+	user := call.Conn.user.Load()
+	if user == nil {
+		return gerror.ErrNoAuth
+	}
+	name, categories := call.Spec.Permissions(call.Args)
+	if !user.CanRun(name, categories) {
+		s.logDenial(call.Conn, user.Name(), acl.ReasonCommand, name)
+		return fmt.Errorf("%w User %s has no permissions to run the '%s' command",
+			gerror.ErrNoPermCommand, user.Name(), name)
+	}
+	write := call.Spec.HasFlag(command.FlagWrite)
+	if err := s.checkKeys(call.Conn, user, call.Spec.Keys(call.Args), write); err != nil {
+		return err
+	}
+	if accessor, ok := call.Command.(command.ChannelsAccessor); ok {
+		channels, patterns := accessor.Channels()
+		for _, channel := range channels {
+			if !user.CanAccessChannel(channel, patterns) {
+				s.logDenial(call.Conn, user.Name(), acl.ReasonChannel, channel)
+				return gerror.ErrNoPermChannel
+			}
+		}
+	}
+	return nil
+}
+
+// checkKeys checks that a user can read keys, or write them if write is set.
+func (s *Server) checkKeys(conn *Connection, user *acl.User, keys []string, write bool) error {
+	for _, key := range keys {
+		if !user.CanAccessKey(key, write) {
+			s.logDenial(conn, user.Name(), acl.ReasonKey, key)
+			return gerror.ErrNoPermKey
+		}
+	}
+	return nil
+}
+
+// logDenial records a permission denied to a user on a connection.
+func (s *Server) logDenial(conn *Connection, username, reason, object string) {
+	context := "toplevel"
+	if conn.tx.active {
+		context = "multi"
+	}
+	s.acl.Log().Add(acl.LogEntry{
+		Reason:     reason,
+		Context:    context,
+		Object:     object,
+		Username:   username,
+		ClientInfo: conn.describe(),
+	})
+}
+
+// delUsers removes users and disconnects the clients authenticated as them. Nothing is removed if one of the
+// users cannot be.
+func (s *Server) delUsers(names ...string) (int, error) {
+	if slices.Contains(names, acl.DefaultUser) {
+		return 0, gerror.ErrDefaultUser
+	}
+	deleted := 0
+	for _, name := range names {
+		user, ok := s.acl.User(name)
+		if !ok {
+			continue
+		}
+		if _, err := s.acl.DelUser(name); err != nil {
+			return deleted, err
+		}
+		deleted++
+		s.clientsMu.RLock()
+		for _, conn := range s.clients {
+			if conn.user.Load() == user {
+				_ = conn.conn.Close()
+			}
+		}
+		s.clientsMu.RUnlock()
+	}
+	return deleted, nil
+}
+
+// Auth authenticates the client as a user. Failed attempts are recorded in the ACL log.
+func (c *Connection) Auth(username, password string) error {
+	user, err := c.server.acl.Authenticate(username, password)
+	if err != nil {
+		c.server.logDenial(c, username, acl.ReasonAuth, "AUTH")
+		return err
+	}
+	c.user.Store(user)
+	return nil
+}
+
+// User returns the name of the user the client is authenticated as, or an empty string if it is not.
+func (c *Connection) User() string {
+	if user := c.user.Load(); user != nil {
+		return user.Name()
+	}
+	return ""
+}
+
+// ACL returns the users of the server.
+func (c *Connection) ACL() *acl.ACL {
+	return c.server.acl
+}
+
+// DelUsers removes users and disconnects the clients authenticated as them.
+func (c *Connection) DelUsers(names ...string) (int, error) {
+	return c.server.delUsers(names...)
+}
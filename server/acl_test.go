@@ -0,0 +1,150 @@
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestACL_RequirePass(t *testing.T) {
+	s := startTestServer(t, WithRequirePass("secret"))
+	client := dialTestClient(t, s.Address())
+
+	client.send("GET", "hello")
+	assert.Equal(t, "-NOAUTH Authentication required.\r\n", client.read())
+	client.send("HELLO", "3")
+	assert.True(t, strings.HasPrefix(client.read(), "-NOAUTH HELLO must be called"))
+	client.send("AUTH", "wrong")
+	assert.True(t, strings.HasPrefix(client.read(), "-WRONGPASS"))
+	client.send("AUTH", "secret")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("ACL", "WHOAMI")
+	assert.Equal(t, "$7\r\ndefault\r\n", client.read())
+
+	other := dialTestClient(t, s.Address())
+	other.send("HELLO", "3", "AUTH", "default", "secret")
+	assert.True(t, strings.HasPrefix(other.read(), "%"))
+
+	client.send("ACL", "LOG", "1")
+	log := client.read()
+	assert.Contains(t, log, "$6\r\nreason\r\n$4\r\nauth\r\n")
+	assert.Contains(t, log, "$8\r\nusername\r\n$7\r\ndefault\r\n")
+}
+
+func TestACL_Permissions(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	admin.send("ACL", "SETUSER", "alice", "on", ">pw", "~cache:*", "&news", "+get", "+subscribe")
+	assert.Equal(t, "+OK\r\n", admin.read())
+
+	alice := dialTestClient(t, s.Address())
+	alice.send("AUTH", "alice", "pw")
+	assert.Equal(t, "+OK\r\n", alice.read())
+
+	alice.send("GET", "cache:1")
+	assert.Equal(t, "$-1\r\n", alice.read())
+	alice.send("GET", "other")
+	assert.Equal(t, "-NOPERM No permissions to access a key\r\n", alice.read())
+	alice.send("SET", "cache:1", "value")
+	assert.Equal(t, "-NOPERM User alice has no permissions to run the 'set' command\r\n", alice.read())
+	alice.send("SUBSCRIBE", "sport")
+	assert.Equal(t, "-NOPERM No permissions to access a channel\r\n", alice.read())
+	alice.send("SUBSCRIBE", "news")
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", alice.read())
+
+	admin.send("ACL", "LOG")
+	log := admin.read()
+	assert.True(t, strings.HasPrefix(log, "*3\r\n"), "one entry per denial expected, got %q", log)
+	assert.Contains(t, log, "$7\r\nchannel\r\n$7\r\ncontext\r\n$8\r\ntoplevel\r\n$6\r\nobject\r\n$5\r\nsport\r\n")
+
+	// deleting a user disconnects its clients
+	admin.send("ACL", "DELUSER", "alice", "bob")
+	assert.Equal(t, ":1\r\n", admin.read())
+	_, err := alice.reader.ReadByte()
+	assert.Error(t, err)
+
+	admin.send("ACL", "DELUSER", "default")
+	assert.True(t, strings.HasPrefix(admin.read(), "-"))
+}
+
+func TestACL_KeysLocatedBySpec(t *testing.T) {
+	s := startTestServer(t, WithEnableDebugCommand(DebugCommandYes))
+	admin := dialTestClient(t, s.Address())
+	admin.send("SET", "secret", "value")
+	admin.read()
+	admin.send("ACL", "SETUSER", "alice", "on", ">pw", "~cache:*", "+object", "+watch", "+memory", "+debug")
+	assert.Equal(t, "+OK\r\n", admin.read())
+
+	alice := dialTestClient(t, s.Address())
+	alice.send("AUTH", "alice", "pw")
+	assert.Equal(t, "+OK\r\n", alice.read())
+	for _, args := range [][]string{
+		{"OBJECT", "ENCODING", "secret"},
+		{"WATCH", "cache:1", "secret"},
+		{"MEMORY", "USAGE", "secret"},
+		{"DEBUG", "OBJECT", "secret"},
+		{"DEBUG", "DIGEST-VALUE", "cache:1", "secret"},
+	} {
+		alice.send(args...)
+		assert.Equal(t, "-NOPERM No permissions to access a key\r\n", alice.read(), args)
+	}
+	alice.send("OBJECT", "ENCODING", "cache:1")
+	assert.Equal(t, "$-1\r\n", alice.read())
+	alice.send("MEMORY", "STATS")
+	assert.True(t, strings.HasPrefix(alice.read(), "*"), "subcommands without keys should not be checked")
+}
+
+func TestACL_ExecChecksPermissionsAgain(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	admin.send("ACL", "SETUSER", "alice", "on", ">pw", "~*", "+get", "+set", "+multi", "+exec")
+	assert.Equal(t, "+OK\r\n", admin.read())
+
+	alice := dialTestClient(t, s.Address())
+	alice.send("AUTH", "alice", "pw")
+	assert.Equal(t, "+OK\r\n", alice.read())
+	alice.send("MULTI")
+	alice.read()
+	alice.send("SET", "key", "value")
+	assert.Equal(t, "+QUEUED\r\n", alice.read())
+	alice.send("GET", "key")
+	assert.Equal(t, "+QUEUED\r\n", alice.read())
+
+	admin.send("ACL", "SETUSER", "alice", "-set")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	alice.send("EXEC")
+	assert.Equal(t, "*2\r\n-NOPERM User alice has no permissions to run the 'set' command\r\n$-1\r\n", alice.read())
+
+	admin.send("ACL", "LOG", "1")
+	assert.Contains(t, admin.read(), "$7\r\ncontext\r\n$5\r\nmulti\r\n")
+}
+
+func TestACL_AdministrativeSubcommands(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	admin.send("ACL", "SETUSER", "alice", "on", ">pw", "+@connection")
+	assert.Equal(t, "+OK\r\n", admin.read())
+
+	alice := dialTestClient(t, s.Address())
+	alice.send("AUTH", "alice", "pw")
+	assert.Equal(t, "+OK\r\n", alice.read())
+
+	alice.send("CLIENT", "SETNAME", "worker")
+	assert.Equal(t, "+OK\r\n", alice.read())
+	for _, args := range [][]string{
+		{"CLIENT", "KILL", "ID", fmt.Sprint(admin.clientID())},
+		{"CLIENT", "PAUSE", "1000"},
+		{"CLIENT", "UNPAUSE"},
+		{"CLIENT", "LIST"},
+	} {
+		alice.send(args...)
+		assert.Equal(t, "-NOPERM User alice has no permissions to run the 'client|"+strings.ToLower(args[1])+"' command\r\n",
+			alice.read())
+	}
+
+	admin.send("ACL", "SETUSER", "alice", "+client")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	alice.send("CLIENT", "LIST")
+	assert.True(t, strings.HasPrefix(alice.read(), "$"), "the rules on a command should apply to its subcommands")
+}
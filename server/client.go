@@ -0,0 +1,302 @@
+package server
+
+import (
+	"cmp"
+	"fmt"
+	"github.com/ynachi/gcache/command"
+	"slices"
+	"sync"
+	"time"
+)
+
+// clientInfo is the state of a client shown by CLIENT LIST. It is updated by the goroutine serving the client
+// around each command and read by the others, which never touch the state of the connection itself.
+type clientInfo struct {
+	mu            sync.Mutex
+	name          string
+	db            int
+	lastCommand   string
+	lastActive    time.Time
+	subscriptions int
+	patterns      int
+	// multi is the number of commands queued in the transaction, -1 outside of one.
+	multi       int
+	queryBuffer int
+}
+
+// beforeCommand records the command the client is about to run.
+func (c *Connection) beforeCommand(cmd command.Command) {
+	c.info.mu.Lock()
+	defer c.info.mu.Unlock()
+	c.info.lastCommand = cmd.Name()
+	c.info.lastActive = time.Now()
+	c.info.queryBuffer = c.reader.Buffered()
+}
+
+// afterCommand records the state of the client changed by the command it ran.
+func (c *Connection) afterCommand() {
+	c.info.mu.Lock()
+	defer c.info.mu.Unlock()
+	c.info.db = c.dbIndex
+	c.info.subscriptions = len(c.channels)
+	c.info.patterns = len(c.patterns)
+	c.info.multi = -1
+	if c.tx.active {
+		c.info.multi = len(c.tx.queue)
+	}
+	c.info.queryBuffer = c.reader.Buffered()
+}
+
+// describe returns the line describing the client in CLIENT LIST and the ACL log.
+func (c *Connection) describe() string {
+	c.info.mu.Lock()
+	info := clientInfo{
+		name:          c.info.name,
+		db:            c.info.db,
+		lastCommand:   c.info.lastCommand,
+		lastActive:    c.info.lastActive,
+		subscriptions: c.info.subscriptions,
+		patterns:      c.info.patterns,
+		multi:         c.info.multi,
+		queryBuffer:   c.info.queryBuffer,
+	}
+	c.info.mu.Unlock()
+
+	flags := ""
+	if c.monitor.Load() {
+		flags += "O"
+	}
+	if info.subscriptions+info.patterns > 0 {
+		flags += "P"
+	}
+	if info.multi >= 0 {
+		flags += "x"
+	}
+	redirect := c.TrackingRedirect()
+	if redirect >= 0 {
+		flags += "t"
+	}
+	if flags == "" {
+		flags = "N"
+	}
+	lastCommand := info.lastCommand
+	if lastCommand == "" {
+		lastCommand = "NULL"
+	}
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%d sub=%d psub=%d multi=%d "+
+		"qbuf=%d omem=%d cmd=%s user=%s redir=%d resp=%d",
+		c.id, c.clientIP, c.conn.LocalAddr(), info.name, int64(time.Since(c.created).Seconds()),
+		int64(time.Since(info.lastActive).Seconds()), flags, info.db, info.subscriptions, info.patterns, info.multi,
+		info.queryBuffer, c.outbox.pendingSize(), lastCommand, c.User(), redirect, c.Protocol())
+}
+
+// clientType returns the class of a client, pubsub for the ones subscribed to a channel or a pattern.
+func (c *Connection) clientType() string {
+	if c.pubsubClient.Load() {
+		return ClassPubSub
+	}
+	return ClassNormal
+}
+
+// limitClass returns the class whose output buffer limits apply to a client. Monitors are bounded like
+// subscribers, as they receive a stream of frames they may not read fast enough.
+func (c *Connection) limitClass() string {
+	if c.monitor.Load() {
+		return ClassPubSub
+	}
+	return c.clientType()
+}
+
+// matches tells if a client is selected by a filter, self being the client running the command.
+func (c *Connection) matches(filter command.ClientFilter, self *Connection) bool {
+	switch {
+	case filter.SkipMe && c == self:
+		return false
+	case len(filter.IDs) > 0 && !slices.Contains(filter.IDs, c.id):
+		return false
+	case filter.Type != "" && filter.Type != c.clientType():
+		return false
+	case filter.Addr != "" && filter.Addr != c.clientIP:
+		return false
+	case filter.LAddr != "" && filter.LAddr != c.conn.LocalAddr().String():
+		return false
+	case filter.User != "" && filter.User != c.User():
+		return false
+	case filter.MaxAge > 0 && time.Since(c.created) < filter.MaxAge:
+		return false
+	}
+	return true
+}
+
+// connections returns the connected clients sorted by ID. They are copied so that the registry is not locked
+// while they are inspected.
+func (s *Server) connections() []*Connection {
+	s.clientsMu.RLock()
+	conns := make([]*Connection, 0, len(s.clients))
+	for _, conn := range s.clients {
+		conns = append(conns, conn)
+	}
+	s.clientsMu.RUnlock()
+	slices.SortFunc(conns, func(a, b *Connection) int { return cmp.Compare(a.id, b.id) })
+	return conns
+}
+
+// ClientName returns the name the client set for itself.
+func (c *Connection) ClientName() string {
+	c.info.mu.Lock()
+	defer c.info.mu.Unlock()
+	return c.info.name
+}
+
+// SetClientName names the client.
+func (c *Connection) SetClientName(name string) {
+	c.info.mu.Lock()
+	defer c.info.mu.Unlock()
+	c.info.name = name
+}
+
+// ClientList describes the clients selected by a filter.
+func (c *Connection) ClientList(filter command.ClientFilter) []string {
+	lines := make([]string, 0)
+	for _, conn := range c.server.connections() {
+		if conn.matches(filter, c) {
+			lines = append(lines, conn.describe())
+		}
+	}
+	return lines
+}
+
+// KillClients disconnects the clients selected by a filter. Closing their network connection makes the goroutines
+// serving them stop, while the client running the command is only disconnected once it got its reply.
+func (c *Connection) KillClients(filter command.ClientFilter) int {
+	killed := 0
+	for _, conn := range c.server.connections() {
+		if !conn.matches(filter, c) {
+			continue
+		}
+		killed++
+		if conn == c {
+			c.closeAfterReply = true
+			continue
+		}
+		if err := conn.conn.Close(); err != nil {
+			c.server.logger.Error("error closing connection", "error", err)
+		}
+	}
+	return killed
+}
+
+// PauseClients suspends the processing of the commands of all the clients, or of the writes only.
+func (c *Connection) PauseClients(timeout time.Duration, all bool) {
+	c.server.pause.pause(timeout, all)
+}
+
+// UnpauseClients resumes the processing of the commands.
+func (c *Connection) UnpauseClients() {
+	c.server.pause.unpause()
+}
+
+// clientPause suspends the processing of commands, all of them or the writes only, until a deadline or until
+// it is lifted.
+type clientPause struct {
+	mu    sync.Mutex
+	until time.Time
+	all   bool
+	// resume is closed when the pause is lifted before its deadline.
+	resume chan struct{}
+}
+
+func newClientPause() *clientPause {
+	return &clientPause{resume: make(chan struct{})}
+}
+
+// pause suspends commands for a duration. A pause in progress is only extended, and never restricted to the writes
+// once it applies to all the commands.
+func (p *clientPause) pause(timeout time.Duration, all bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until := time.Now().Add(timeout)
+	if time.Now().Before(p.until) {
+		p.all = p.all || all
+		if until.After(p.until) {
+			p.until = until
+		}
+		return
+	}
+	p.until, p.all = until, all
+}
+
+// unpause lifts the pause and wakes up the clients waiting for it.
+func (p *clientPause) unpause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.until = time.Time{}
+	close(p.resume)
+	p.resume = make(chan struct{})
+}
+
+// active tells if a command is suspended, returning the deadline of the pause and the channel closed when it is
+// lifted if it is.
+func (p *clientPause) active(write bool) (time.Time, <-chan struct{}, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !time.Now().Before(p.until) || (!p.all && !write) {
+		return time.Time{}, nil, false
+	}
+	return p.until, p.resume, true
+}
+
+// wait blocks until a command is not suspended anymore.
+func (p *clientPause) wait(write bool) {
+	for {
+		until, resume, paused := p.active(write)
+		if !paused {
+			return
+		}
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-resume:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+// waitForPause suspends a command while the clients are paused. CLIENT is never suspended so that clients can be
+// unpaused, and neither are the commands queued in a transaction: EXEC is. It is called before the connection write
+// lock is taken, so that the frames pushed to the client, like pub/sub messages, are still written meanwhile.
+func (s *Server) waitForPause(call *Call) {
+	queued := call.Conn.tx.active && !isTransactionControl(call.Command)
+	if call.Spec != nil && call.Spec.Name != "client" && !queued {
+		s.pause.wait(mayWrite(call))
+	}
+}
+
+// mayWrite tells if a command may modify the data set or be propagated, like PUBLISH. EXEC may if one of the
+// commands it runs does.
+func mayWrite(call *Call) bool {
+	if call.Spec.HasFlag(command.FlagWrite) || call.Spec.Name == "publish" {
+		return true
+	}
+	if call.Spec.Name != "exec" {
+		return false
+	}
+	return slices.ContainsFunc(call.Conn.tx.queue, func(queued *Call) bool {
+		return queued.Spec != nil && (queued.Spec.HasFlag(command.FlagWrite) || queued.Spec.Name == "publish")
+	})
+}
+
+// ClientsMemory returns the memory used by the buffers of the clients, by class.
+func (c *Connection) ClientsMemory() map[string]int64 {
+	memory := map[string]int64{ClassNormal: 0, ClassPubSub: 0}
+	for _, conn := range c.server.connections() {
+		memory[conn.clientType()] += conn.bufferSize()
+	}
+	return memory
+}
+
+// bufferSize returns the number of bytes of the read and write buffers of the connection, and of the frames
+// pending in its outbox.
+func (c *Connection) bufferSize() int64 {
+	return int64(c.reader.Size() + c.writer.Size() + c.outbox.pendingSize())
+}
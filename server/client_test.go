@@ -0,0 +1,147 @@
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clientID returns the ID of a client, read with CLIENT ID.
+func (c *testClient) clientID() int64 {
+	c.send("CLIENT", "ID")
+	var id int64
+	if _, err := fmt.Sscanf(c.read(), ":%d\r\n", &id); err != nil {
+		c.t.Fatalf("unable to read client ID: %v", err)
+	}
+	return id
+}
+
+// assertDisconnected checks that the server closed the connection of a client.
+func (c *testClient) assertDisconnected() {
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := c.reader.ReadByte()
+	assert.Error(c.t, err)
+}
+
+func TestClient_ListAndInfo(t *testing.T) {
+	s := startTestServer(t)
+	worker := dialTestClient(t, s.Address())
+	subscriber := dialTestClient(t, s.Address())
+
+	worker.send("CLIENT", "GETNAME")
+	assert.Equal(t, "$-1\r\n", worker.read())
+	worker.send("CLIENT", "SETNAME", "worker 1")
+	assert.Equal(t, "-Client names cannot contain spaces, newlines or special characters.\r\n", worker.read())
+	worker.send("CLIENT", "SETNAME", "worker")
+	assert.Equal(t, "+OK\r\n", worker.read())
+	worker.send("CLIENT", "GETNAME")
+	assert.Equal(t, "$6\r\nworker\r\n", worker.read())
+	worker.send("SELECT", "2")
+	assert.Equal(t, "+OK\r\n", worker.read())
+	subscriber.send("SUBSCRIBE", "news")
+	subscriber.read()
+
+	id := worker.clientID()
+	worker.send("CLIENT", "INFO")
+	info := worker.read()
+	assert.Contains(t, info, fmt.Sprintf("id=%d addr=%s ", id, worker.conn.LocalAddr()))
+	assert.Contains(t, info, " name=worker ")
+	assert.Contains(t, info, " flags=N db=2 sub=0 psub=0 multi=-1 ")
+	assert.Contains(t, info, " cmd=client user=default redir=-1 resp=2\n")
+
+	worker.send("CLIENT", "LIST")
+	assert.Equal(t, 2, strings.Count(worker.read(), "id="))
+	worker.send("CLIENT", "LIST", "TYPE", "pubsub")
+	list := worker.read()
+	assert.Equal(t, 1, strings.Count(list, "id="))
+	assert.Contains(t, list, " flags=P db=0 sub=1 psub=0 ")
+	worker.send("CLIENT", "LIST", "ID", fmt.Sprint(id), "12345")
+	list = worker.read()
+	assert.Equal(t, 1, strings.Count(list, "id="))
+	assert.Contains(t, list, fmt.Sprintf("id=%d ", id))
+	worker.send("CLIENT", "LIST", "TYPE", "unknown")
+	assert.Equal(t, "-Unknown client type 'unknown'\r\n", worker.read())
+
+	other := dialTestClient(t, s.Address())
+	other.send("HELLO", "3", "SETNAME", "other")
+	other.read()
+	other.send("CLIENT", "GETNAME")
+	assert.Equal(t, "$5\r\nother\r\n", other.read())
+}
+
+func TestClient_Kill(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	byAddr := dialTestClient(t, s.Address())
+	byID := dialTestClient(t, s.Address())
+
+	admin.send("CLIENT", "KILL", byAddr.conn.LocalAddr().String())
+	assert.Equal(t, "+OK\r\n", admin.read())
+	byAddr.assertDisconnected()
+	admin.send("CLIENT", "KILL", byAddr.conn.LocalAddr().String())
+	assert.Equal(t, "-No such client\r\n", admin.read())
+
+	admin.send("CLIENT", "KILL", "ID", fmt.Sprint(byID.clientID()))
+	assert.Equal(t, ":1\r\n", admin.read())
+	byID.assertDisconnected()
+
+	// the client running the command is skipped unless told otherwise, and then gets its reply
+	admin.send("CLIENT", "KILL", "USER", "default")
+	assert.Equal(t, ":0\r\n", admin.read())
+	admin.send("CLIENT", "KILL", "USER", "default", "SKIPME", "no")
+	assert.Equal(t, ":1\r\n", admin.read())
+	admin.assertDisconnected()
+}
+
+func TestClient_Pause(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	client := dialTestClient(t, s.Address())
+
+	admin.send("CLIENT", "PAUSE", "200", "WRITE")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	start := time.Now()
+	client.send("GET", "hello")
+	assert.Equal(t, "$-1\r\n", client.read())
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "reads should not be paused")
+	client.send("SET", "hello", "world")
+	assert.Equal(t, "+ok\r\n", client.read())
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "writes should wait for the end of the pause")
+
+	admin.send("CLIENT", "PAUSE", "10000", "ALL")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	client.send("PING")
+	time.Sleep(50 * time.Millisecond)
+	admin.send("CLIENT", "UNPAUSE")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	assert.Equal(t, "+PONG\r\n", client.read())
+
+	admin.send("CLIENT", "PAUSE", "abc")
+	assert.Equal(t, "-timeout is not an integer or out of range\r\n", admin.read())
+}
+
+func TestClient_PauseKeepsPushing(t *testing.T) {
+	s := startTestServer(t)
+	admin := dialTestClient(t, s.Address())
+	monitor := dialTestClient(t, s.Address())
+	client := dialTestClient(t, s.Address())
+
+	monitor.send("MONITOR")
+	assert.Equal(t, "+OK\r\n", monitor.read())
+	admin.send("CLIENT", "PAUSE", "10000", "WRITE")
+	assert.Equal(t, "+OK\r\n", admin.read())
+
+	monitor.send("SET", "hello", "world")
+	time.Sleep(50 * time.Millisecond)
+	client.send("GET", "hello")
+	assert.Equal(t, "$-1\r\n", client.read())
+	assert.Contains(t, monitor.read(), `"CLIENT" "PAUSE"`)
+	assert.Contains(t, monitor.read(), `"GET" "hello"`, "a paused client should still receive the frames pushed to it")
+
+	admin.send("CLIENT", "UNPAUSE")
+	assert.Equal(t, "+OK\r\n", admin.read())
+	// the reply comes along with the line of CLIENT UNPAUSE, in any order
+	assert.Contains(t, monitor.read()+monitor.read(), "+ok\r\n")
+}
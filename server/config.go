@@ -1,11 +1,514 @@
 package server
 
+import (
+	"errors"
+	"fmt"
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/gerror"
+	"github.com/ynachi/gcache/glob"
+	"io/fs"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
 // Define various configs here and methods to validate them
 
 // Logger config
 
 // Server config
 
+// DefaultDatabases is the number of logical databases a server holds unless configured otherwise.
+const DefaultDatabases = 16
+
+// DefaultUnixSocketPerm are the permissions of the unix socket unless configured otherwise.
+const DefaultUnixSocketPerm = 0700
+
+// DefaultPubSubBufferLimit is the number of bytes of messages which can be pending for a subscriber.
+const DefaultPubSubBufferLimit = 32 * 1024 * 1024
+
+// DefaultMaxClients is the number of clients which can be connected at the same time unless configured otherwise.
+const DefaultMaxClients = 10000
+
+// DefaultTCPKeepAlive is the interval of the TCP keepalive probes unless configured otherwise.
+const DefaultTCPKeepAlive = 300 * time.Second
+
+// DefaultSlowLogSlowerThan is the run time over which commands are logged unless configured otherwise.
+const DefaultSlowLogSlowerThan = 10 * time.Millisecond
+
+// DefaultSlowLogMaxLen is the number of slow commands kept unless configured otherwise.
+const DefaultSlowLogMaxLen = 128
+
+// DefaultTrackingTableMaxKeys is the number of keys tracked for client side caching unless configured otherwise.
+const DefaultTrackingTableMaxKeys = 1000000
+
+// Modes of enable-debug-command, telling which clients can run the protected commands like DEBUG.
+const (
+	DebugCommandNo    = "no"
+	DebugCommandYes   = "yes"
+	DebugCommandLocal = "local"
+)
+
+var (
+	ErrInvalidDatabaseCount = errors.New("the number of databases should be at least 1")
+	ErrInvalidBufferLimit   = errors.New("buffer limits should not be negative")
+	ErrTLSCertRequired      = errors.New("TLS requires a certificate and its private key")
+	ErrTLSCARequired        = errors.New("verifying client certificates requires a CA bundle")
+	ErrTLSUnverifiedUser    = errors.New("mapping client certificates to users requires verifying them")
+	ErrTLSNotEnabled        = errors.New("TLS is not enabled")
+	ErrNoListener           = errors.New("TCP can only be disabled when listening on a unix socket")
+	ErrInvalidMaxClients    = errors.New("the maximum number of clients should be at least 1")
+	ErrInvalidTimeout       = errors.New("timeouts should not be negative")
+	ErrInvalidSlowLogLen    = errors.New("the length of the slow log should not be negative")
+	ErrInvalidThreshold     = errors.New("the latency monitor threshold should not be negative")
+	ErrInvalidDebugMode     = errors.New("enable-debug-command should be one of no, yes or local")
+	ErrInvalidTrackingTable = errors.New("the size of the tracking table should not be negative")
+)
+
+// Config holds the optional settings of a server.
+type Config struct {
+	// Databases is the number of logical databases clients can SELECT.
+	Databases int
+
+	// OutputBufferLimits bounds the data pending for the clients of each class, normal and pubsub, not reading
+	// fast enough. Clients exceeding them are disconnected.
+	OutputBufferLimits map[string]OutputBufferLimit
+
+	// DropSlowSubscribers makes the server drop the messages exceeding the output buffer limits instead of
+	// disconnecting the subscriber.
+	DropSlowSubscribers bool
+
+	// MaxClients is the number of clients which can be connected at the same time.
+	MaxClients int
+
+	// IdleTimeout disconnects the clients not sending any command for this duration, subscribers excepted.
+	// 0 means clients are never disconnected.
+	IdleTimeout time.Duration
+
+	// TCPKeepAlive is the interval of the TCP keepalive probes sent to the clients. 0 disables them.
+	TCPKeepAlive time.Duration
+
+	// NotifyKeyspaceEvents selects the key events published to pub/sub, with the syntax of the Redis setting
+	// of the same name. Empty disables notifications.
+	NotifyKeyspaceEvents string
+
+	// RequirePass is the password of the default user. Empty means the default user needs no password.
+	RequirePass string
+
+	// ACLFile is the path of a file describing the users, loaded upon creation. See acl.ACL.Load for its format.
+	ACLFile string
+
+	// UnixSocket is the path of a unix socket the server listens on, in addition to TCP. Empty means none.
+	UnixSocket string
+
+	// UnixSocketPerm are the permissions of the unix socket.
+	UnixSocketPerm fs.FileMode
+
+	// DisableTCP makes the server listen on the unix socket only.
+	DisableTCP bool
+
+	// TLS makes the server accept TLS connections only. Nil means plain TCP.
+	TLS *TLSConfig
+
+	// SlowLogSlowerThan is the run time over which commands are recorded in the slow log. 0 records every
+	// command and a negative duration none.
+	SlowLogSlowerThan time.Duration
+
+	// SlowLogMaxLen is the number of commands the slow log keeps.
+	SlowLogMaxLen int
+
+	// LatencyMonitorThreshold is the latency over which events are recorded by the latency monitor.
+	// 0 disables it.
+	LatencyMonitorThreshold time.Duration
+
+	// EnableDebugCommand tells which clients can run the protected commands like DEBUG: none with
+	// DebugCommandNo, all of them with DebugCommandYes, and the ones connected through the unix socket or the
+	// loopback interface with DebugCommandLocal.
+	EnableDebugCommand string
+
+	// TrackingTableMaxKeys is the number of keys read by clients in the default mode of CLIENT TRACKING the server
+	// remembers. Past it, keys are invalidated to make room for new ones. 0 means no limit.
+	TrackingTableMaxKeys int
+
+	// Middlewares wrap the processing of every command, the first one being the outermost.
+	Middlewares []Middleware
+}
+
+// Option customizes the configuration of a server upon creation.
+type Option func(*Config)
+
+// WithDatabases sets the number of logical databases.
+func WithDatabases(n int) Option {
+	return func(c *Config) {
+		c.Databases = n
+	}
+}
+
+// WithPubSubBufferLimit sets the number of bytes of messages which can be pending for a subscriber, its hard
+// output buffer limit.
+func WithPubSubBufferLimit(limit int) Option {
+	return func(c *Config) {
+		pubsub := c.OutputBufferLimits[ClassPubSub]
+		pubsub.Hard = limit
+		c.OutputBufferLimits[ClassPubSub] = pubsub
+	}
+}
+
+// WithOutputBufferLimit sets the output buffer limits of a class of clients, normal or pubsub.
+func WithOutputBufferLimit(class string, limit OutputBufferLimit) Option {
+	return func(c *Config) {
+		c.OutputBufferLimits[class] = limit
+	}
+}
+
+// WithMaxClients sets the number of clients which can be connected at the same time.
+func WithMaxClients(n int) Option {
+	return func(c *Config) {
+		c.MaxClients = n
+	}
+}
+
+// WithIdleTimeout disconnects the clients idle for the given duration.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.IdleTimeout = timeout
+	}
+}
+
+// WithTCPKeepAlive sets the interval of the TCP keepalive probes, 0 disabling them.
+func WithTCPKeepAlive(interval time.Duration) Option {
+	return func(c *Config) {
+		c.TCPKeepAlive = interval
+	}
+}
+
+// WithDropSlowSubscribers makes the server drop messages for slow subscribers instead of disconnecting them.
+func WithDropSlowSubscribers() Option {
+	return func(c *Config) {
+		c.DropSlowSubscribers = true
+	}
+}
+
+// WithNotifyKeyspaceEvents selects the key events published to pub/sub, for instance "KEA".
+func WithNotifyKeyspaceEvents(flags string) Option {
+	return func(c *Config) {
+		c.NotifyKeyspaceEvents = flags
+	}
+}
+
+// WithRequirePass sets the password of the default user, which clients have to AUTH with.
+func WithRequirePass(password string) Option {
+	return func(c *Config) {
+		c.RequirePass = password
+	}
+}
+
+// WithACLFile loads the users from an ACL file.
+func WithACLFile(path string) Option {
+	return func(c *Config) {
+		c.ACLFile = path
+	}
+}
+
+// WithTLS makes the server accept TLS connections only, with the given certificates.
+func WithTLS(config TLSConfig) Option {
+	return func(c *Config) {
+		c.TLS = &config
+	}
+}
+
+// WithUnixSocket makes the server listen on a unix socket, in addition to TCP.
+func WithUnixSocket(path string) Option {
+	return func(c *Config) {
+		c.UnixSocket = path
+	}
+}
+
+// WithUnixSocketPerm sets the permissions of the unix socket, 0700 by default.
+func WithUnixSocketPerm(perm fs.FileMode) Option {
+	return func(c *Config) {
+		c.UnixSocketPerm = perm
+	}
+}
+
+// WithoutTCP makes the server listen on its unix socket only.
+func WithoutTCP() Option {
+	return func(c *Config) {
+		c.DisableTCP = true
+	}
+}
+
+// WithSlowLog records the commands running for threshold or longer in the slow log, keeping maxLen of them.
+// A negative threshold disables the slow log.
+func WithSlowLog(threshold time.Duration, maxLen int) Option {
+	return func(c *Config) {
+		c.SlowLogSlowerThan = threshold
+		c.SlowLogMaxLen = maxLen
+	}
+}
+
+// WithLatencyMonitorThreshold makes the latency monitor record the events taking threshold or longer.
+func WithLatencyMonitorThreshold(threshold time.Duration) Option {
+	return func(c *Config) {
+		c.LatencyMonitorThreshold = threshold
+	}
+}
+
+// WithEnableDebugCommand allows the clients selected by mode, one of DebugCommandNo, DebugCommandYes or
+// DebugCommandLocal, to run the protected commands.
+func WithEnableDebugCommand(mode string) Option {
+	return func(c *Config) {
+		c.EnableDebugCommand = mode
+	}
+}
+
+// WithTrackingTableMaxKeys sets the number of keys the server remembers for client side caching, 0 meaning no
+// limit.
+func WithTrackingTableMaxKeys(n int) Option {
+	return func(c *Config) {
+		c.TrackingTableMaxKeys = n
+	}
+}
+
+// defaultConfig returns the configuration used when no option is provided.
+func defaultConfig() Config {
+	return Config{
+		Databases:            DefaultDatabases,
+		OutputBufferLimits:   defaultOutputBufferLimits(),
+		MaxClients:           DefaultMaxClients,
+		TCPKeepAlive:         DefaultTCPKeepAlive,
+		UnixSocketPerm:       DefaultUnixSocketPerm,
+		SlowLogSlowerThan:    DefaultSlowLogSlowerThan,
+		SlowLogMaxLen:        DefaultSlowLogMaxLen,
+		EnableDebugCommand:   DebugCommandNo,
+		TrackingTableMaxKeys: DefaultTrackingTableMaxKeys,
+	}
+}
+
+// validate checks the consistency of a configuration.
+func (c *Config) validate() error {
+	if c.Databases < 1 {
+		return ErrInvalidDatabaseCount
+	}
+	if err := validateOutputBufferLimits(c.OutputBufferLimits); err != nil {
+		return err
+	}
+	if c.MaxClients < 1 {
+		return ErrInvalidMaxClients
+	}
+	if c.IdleTimeout < 0 || c.TCPKeepAlive < 0 {
+		return ErrInvalidTimeout
+	}
+	if c.SlowLogMaxLen < 0 {
+		return ErrInvalidSlowLogLen
+	}
+	if c.LatencyMonitorThreshold < 0 {
+		return ErrInvalidThreshold
+	}
+	if c.TrackingTableMaxKeys < 0 {
+		return ErrInvalidTrackingTable
+	}
+	switch c.EnableDebugCommand {
+	case DebugCommandNo, DebugCommandYes, DebugCommandLocal:
+	default:
+		return ErrInvalidDebugMode
+	}
+	if _, err := parseNotifyKeyspaceEvents(c.NotifyKeyspaceEvents); err != nil {
+		return err
+	}
+	if c.DisableTCP && c.UnixSocket == "" {
+		return ErrNoListener
+	}
+	if c.TLS != nil {
+		return c.TLS.validate()
+	}
+	return nil
+}
+
+// runtimeParam is a setting clients can read with CONFIG GET and, if set is defined, change with CONFIG SET.
+type runtimeParam struct {
+	get func(s *Server) string
+	set func(s *Server, value string) error
+}
+
+var runtimeParams = map[string]runtimeParam{
+	"databases": {
+		get: func(s *Server) string { return strconv.Itoa(s.config.Databases) },
+	},
+	"maxclients": {
+		get: func(s *Server) string { return strconv.FormatInt(s.maxClients.Load(), 10) },
+		set: func(s *Server, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 1 {
+				return gerror.ErrNotInteger
+			}
+			s.maxClients.Store(n)
+			return nil
+		},
+	},
+	"timeout": {
+		get: func(s *Server) string {
+			return strconv.FormatInt(int64(time.Duration(s.idleTimeout.Load()).Seconds()), 10)
+		},
+		set: func(s *Server, value string) error {
+			seconds, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || seconds < 0 {
+				return gerror.ErrNotInteger
+			}
+			s.idleTimeout.Store(int64(time.Duration(seconds) * time.Second))
+			return nil
+		},
+	},
+	"tcp-keepalive": {
+		get: func(s *Server) string { return strconv.FormatInt(int64(s.config.TCPKeepAlive.Seconds()), 10) },
+	},
+	"client-output-buffer-limit": {
+		get: func(s *Server) string { return formatOutputBufferLimits(*s.outputLimits.Load()) },
+		set: func(s *Server, value string) error {
+			limits, err := parseOutputBufferLimits(value, *s.outputLimits.Load())
+			if err != nil {
+				return err
+			}
+			s.setOutputBufferLimits(limits)
+			return nil
+		},
+	},
+	"slowlog-log-slower-than": {
+		get: func(s *Server) string { return strconv.FormatInt(s.slowLog.Threshold().Microseconds(), 10) },
+		set: func(s *Server, value string) error {
+			us, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return gerror.ErrNotInteger
+			}
+			s.slowLog.SetThreshold(time.Duration(us) * time.Microsecond)
+			return nil
+		},
+	},
+	"slowlog-max-len": {
+		get: func(s *Server) string { return strconv.Itoa(s.slowLog.MaxLen()) },
+		set: func(s *Server, value string) error {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return gerror.ErrNotInteger
+			}
+			s.slowLog.SetMaxLen(n)
+			return nil
+		},
+	},
+	"latency-monitor-threshold": {
+		get: func(s *Server) string { return strconv.FormatInt(s.latency.Threshold().Milliseconds(), 10) },
+		set: func(s *Server, value string) error {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || ms < 0 {
+				return gerror.ErrNotInteger
+			}
+			s.latency.SetThreshold(time.Duration(ms) * time.Millisecond)
+			return nil
+		},
+	},
+	"tracking-table-max-keys": {
+		get: func(s *Server) string { return strconv.FormatInt(s.tracking.maxKeys.Load(), 10) },
+		set: func(s *Server, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return gerror.ErrNotInteger
+			}
+			s.tracking.maxKeys.Store(n)
+			return nil
+		},
+	},
+	"enable-debug-command": {
+		get: func(s *Server) string { return s.config.EnableDebugCommand },
+	},
+	"unixsocket": {
+		get: func(s *Server) string { return s.config.UnixSocket },
+	},
+	"unixsocketperm": {
+		get: func(s *Server) string { return strconv.FormatUint(uint64(s.config.UnixSocketPerm), 8) },
+	},
+	"maxmemory-samples": tunable(func(t *db.Tunables) *atomic.Int64 { return &t.Samples }, 1),
+	"lfu-log-factor":    tunable(func(t *db.Tunables) *atomic.Int64 { return &t.LogFactor }, 0),
+	"lfu-decay-time":    tunable(func(t *db.Tunables) *atomic.Int64 { return &t.DecayTime }, 0),
+	"requirepass": {
+		get: func(s *Server) string {
+			password, _ := s.requirePass.Load().(string)
+			return password
+		},
+		set: func(s *Server, value string) error {
+			rules := []string{"nopass"}
+			if value != "" {
+				rules = []string{"resetpass", ">" + value}
+			}
+			if err := s.acl.SetUser(acl.DefaultUser, rules...); err != nil {
+				return err
+			}
+			s.requirePass.Store(value)
+			return nil
+		},
+	},
+	"notify-keyspace-events": {
+		get: func(s *Server) string { return formatNotifyKeyspaceEvents(int(s.notifyFlags.Load())) },
+		set: func(s *Server, value string) error {
+			flags, err := parseNotifyKeyspaceEvents(value)
+			if err != nil {
+				return err
+			}
+			s.notifyFlags.Store(int32(flags))
+			return nil
+		},
+	},
+}
+
+// tunable returns a runtime parameter reading and writing a setting of the sampled eviction policies,
+// which cannot be lower than minimum.
+func tunable(field func(t *db.Tunables) *atomic.Int64, minimum int64) runtimeParam {
+	return runtimeParam{
+		get: func(s *Server) string { return strconv.FormatInt(field(s.tunables).Load(), 10) },
+		set: func(s *Server, value string) error {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < minimum {
+				return gerror.ErrNotInteger
+			}
+			field(s.tunables).Store(n)
+			return nil
+		},
+	}
+}
+
+// configGet returns the names and values of the runtime parameters matching a glob-style pattern,
+// as alternating names and values sorted by name.
+func (s *Server) configGet(pattern string) []string {
+	names := make([]string, 0)
+	for name := range runtimeParams {
+		if glob.Match(pattern, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, 2*len(names))
+	for _, name := range names {
+		pairs = append(pairs, name, runtimeParams[name].get(s))
+	}
+	return pairs
+}
+
+// configSet changes a runtime parameter.
+func (s *Server) configSet(name, value string) error {
+	param, ok := runtimeParams[name]
+	if !ok {
+		return fmt.Errorf("%w '%s'", gerror.ErrUnknownConfig, name)
+	}
+	if param.set == nil {
+		return fmt.Errorf("%w '%s'", gerror.ErrImmutableConfig, name)
+	}
+	if err := param.set(s, value); err != nil {
+		return fmt.Errorf("CONFIG SET failed for '%s' - %w", name, err)
+	}
+	return nil
+}
+
 // Database config
 
 // ...
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/gerror"
+	"testing"
+)
+
+func TestServer_ConfigSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr error
+	}{
+		{name: "maxmemory-samples", value: "10", want: []string{"maxmemory-samples", "10"}},
+		{name: "maxmemory-samples", value: "0", want: []string{"maxmemory-samples", "5"}, wantErr: gerror.ErrNotInteger},
+		{name: "lfu-log-factor", value: "0", want: []string{"lfu-log-factor", "0"}},
+		{name: "lfu-decay-time", value: "abc", want: []string{"lfu-decay-time", "1"}, wantErr: gerror.ErrNotInteger},
+		{name: "databases", value: "4", want: []string{"databases", "16"}, wantErr: gerror.ErrImmutableConfig},
+		{name: "maxclients", value: "100", want: []string{"maxclients", "100"}},
+		{name: "maxclients", value: "0", want: []string{"maxclients", "10000"}, wantErr: gerror.ErrNotInteger},
+		{name: "timeout", value: "30", want: []string{"timeout", "30"}},
+		{name: "client-output-buffer-limit", value: "pubsub 1kb 512 10", want: []string{"client-output-buffer-limit", "normal 0 0 0 pubsub 1024 512 10"}},
+		{name: "client-output-buffer-limit", value: "replica 1kb 512 10", want: []string{"client-output-buffer-limit", "normal 0 0 0 pubsub 33554432 8388608 60"}, wantErr: gerror.ErrInvalidClientType},
+		{name: "client-output-buffer-limit", value: "pubsub 1kb", want: []string{"client-output-buffer-limit", "normal 0 0 0 pubsub 33554432 8388608 60"}, wantErr: gerror.ErrSyntax},
+		{name: "slowlog-log-slower-than", value: "-1", want: []string{"slowlog-log-slower-than", "-1"}},
+		{name: "slowlog-max-len", value: "-1", want: []string{"slowlog-max-len", "128"}, wantErr: gerror.ErrNotInteger},
+		{name: "latency-monitor-threshold", value: "100", want: []string{"latency-monitor-threshold", "100"}},
+		{name: "enable-debug-command", value: "yes", want: []string{"enable-debug-command", "no"}, wantErr: gerror.ErrImmutableConfig},
+		{name: "unknown", value: "4", want: []string{}, wantErr: gerror.ErrUnknownConfig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, 1)
+			assert.ErrorIs(t, s.configSet(tt.name, tt.value), tt.wantErr)
+			assert.Equal(t, tt.want, s.configGet(tt.name))
+		})
+	}
+}
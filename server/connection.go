@@ -2,53 +2,203 @@ package server
 
 import (
 	"bufio"
+	"github.com/ynachi/gcache/acl"
 	"github.com/ynachi/gcache/command"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
 	"net"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Connection is a helper struct that helps propagates embedded the treader and writer of a connection while
 // allowing top propagates information about this connection.
-// Connection needs a reference to the Cache database to operate on it.
+// Connection needs a reference to the server to reach the databases it operates on.
+// It tracks the database selected by the client and implements command.Session.
 type Connection struct {
-	conn     net.Conn
-	reader   *bufio.Reader
-	writer   *bufio.Writer
-	storage  *db.Cache
+	id     int64
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	// replies writes the replies of the commands to the writer, for the protocol spoken by the client.
+	replies  *command.RespWriter
+	server   *Server
+	dbIndex  int
 	clientIP string
+	tx       transaction
+	// user is the user the client is authenticated as, nil until it is. It is read by ACL DELUSER from
+	// other goroutines.
+	user atomic.Pointer[acl.User]
+	// cachingSet is set by CLIENT CACHING for the next command only.
+	cachingSet bool
+	// closeAfterReply is set when the client killed itself, to disconnect it once the reply is sent.
+	closeAfterReply bool
+	created         time.Time
+	// info is the state of the client shown to the others by CLIENT LIST.
+	info clientInfo
+	// request is the frame of the command being processed, kept for the slow log.
+	request *frame.Array
+	// replyOverSoftSince is the time the replies went over the soft output buffer limit, zero while they are under.
+	replyOverSoftSince time.Time
+
+	// protocol is the RESP version spoken by the client. It is read by publishers from other goroutines.
+	protocol atomic.Int32
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	// pubsubClient tells if the client holds subscriptions, for the goroutines pushing to it.
+	pubsubClient atomic.Bool
+	// monitor tells if the client ran MONITOR.
+	monitor atomic.Bool
+
+	// writeMu serializes the replies and the frames pushed from the outbox.
+	writeMu sync.Mutex
+	outbox  *outbox
 }
 
 // MakeConnection creates a connection from a net.Conn object.
-func MakeConnection(c net.Conn, storage *db.Cache) Connection {
-	return Connection{
+// New connections operate on the first database and speak RESP2 until they switch with HELLO.
+func MakeConnection(c net.Conn, server *Server) *Connection {
+	conn := &Connection{
+		id:       server.nextClientID.Add(1),
 		reader:   bufio.NewReader(c),
 		writer:   bufio.NewWriter(c),
 		clientIP: c.RemoteAddr().String(),
 		conn:     c,
-		storage:  storage,
+		server:   server,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		outbox:   newOutbox(),
+		created:  time.Now(),
+	}
+	conn.info.lastActive = conn.created
+	conn.info.multi = -1
+	conn.protocol.Store(frame.RESP2)
+	conn.replies = command.NewRespWriter(conn.writer, conn.Protocol)
+	conn.replies.SetLimit(conn.checkReplyLimit)
+	// clients are authenticated as the default user right away unless it needs a password
+	if user, ok := server.acl.User(acl.DefaultUser); ok && user.Enabled() && user.NoPass() {
+		conn.user.Store(user)
 	}
+	return conn
 }
 
-func (c Connection) Close() error {
+func (c *Connection) Close() error {
+	c.server.unregisterClient(c)
+	c.outbox.close()
+	c.resetTransaction()
+	c.Unsubscribe()
+	c.PUnsubscribe()
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
 	if err := c.writer.Flush(); err != nil {
 		return err
 	}
 	return c.conn.Close()
 }
 
+// DB returns the database currently selected by the connection.
+func (c *Connection) DB() *db.Cache {
+	cache, _ := c.server.database(c.dbIndex)
+	return cache
+}
+
+// Select makes the database at the given index the one used by subsequent commands.
+func (c *Connection) Select(index int) error {
+	if _, err := c.server.database(index); err != nil {
+		return err
+	}
+	c.dbIndex = index
+	return nil
+}
+
+// Database returns the database at the given index.
+func (c *Connection) Database(index int) (*db.Cache, error) {
+	return c.server.database(index)
+}
+
+// DatabaseCount returns the number of logical databases held by the server.
+func (c *Connection) DatabaseCount() int {
+	return len(c.server.dbs)
+}
+
+// Protocol returns the RESP version spoken by the client.
+func (c *Connection) Protocol() int {
+	return int(c.protocol.Load())
+}
+
+// SetProtocol switches the RESP version spoken by the client.
+func (c *Connection) SetProtocol(version int) {
+	c.protocol.Store(int32(version))
+}
+
+// push queues a frame to be written to the client by the outbox goroutine.
+// When the client does not read fast enough, the frame is either dropped or the client disconnected,
+// depending on the server configuration.
+func (c *Connection) push(f frame.Framer) {
+	if c.outbox.push(frame.ForProtocol(c.Protocol(), f).Serialize(), c.server.outputBufferLimit(c.limitClass())) {
+		return
+	}
+	if c.server.config.DropSlowSubscribers {
+		c.server.logger.Warn("output buffer limit reached, dropping message", "client_ip", c.clientIP)
+		return
+	}
+	c.server.logger.Warn("output buffer limit reached, closing connection", "client_ip", c.clientIP)
+	if err := c.conn.Close(); err != nil {
+		c.server.logger.Error("error closing connection", "error", err)
+	}
+}
+
+// writePushes writes the frames queued in the outbox until the connection is closed.
+func (c *Connection) writePushes() {
+	for {
+		select {
+		case <-c.outbox.done:
+			return
+		case <-c.outbox.notify:
+			c.writeMu.Lock()
+			for _, data := range c.outbox.take() {
+				if _, err := c.writer.Write(data); err != nil {
+					c.server.logger.Error("error writing pushed frame to network", "error", err)
+					break
+				}
+			}
+			if err := c.writer.Flush(); err != nil {
+				c.server.logger.Error("failed to flush buffer to writer", "error", err)
+			}
+			c.writeMu.Unlock()
+		}
+	}
+}
+
+// ConfigGet returns the runtime parameters matching a pattern as alternating names and values.
+func (c *Connection) ConfigGet(pattern string) []string {
+	return c.server.configGet(pattern)
+}
+
+// ConfigSet changes a runtime parameter of the server.
+func (c *Connection) ConfigSet(name, value string) error {
+	return c.server.configSet(name, value)
+}
+
+// SwapDB swaps two databases of the server.
+func (c *Connection) SwapDB(i, j int) error {
+	return c.server.swapDB(i, j)
+}
+
 // GetCommand handles a command received by the server over an established connection.
-func (c Connection) GetCommand() (command.Command, error) {
+func (c *Connection) GetCommand() (command.Command, error) {
 	cmdFrame, err := c.readCmdFrame()
 	if err != nil {
 		return nil, err
 	}
+	c.request = cmdFrame
 	return parseCommandFromFrame(cmdFrame)
 }
 
 // readCmdFrame reads an array frame from the connection. RESP commands are all represented as Array of frames.
-func (c Connection) readCmdFrame() (*frame.Array, error) {
+func (c *Connection) readCmdFrame() (*frame.Array, error) {
 	cmdFrame, err := frame.Decode(c.reader)
 	if err != nil {
 		return nil, err
@@ -62,17 +212,5 @@ func (c Connection) readCmdFrame() (*frame.Array, error) {
 
 // parseCommandFromFrame extracts a command from a frame array.
 func parseCommandFromFrame(f *frame.Array) (command.Command, error) {
-	cmdName, err := command.GetCmdName(f)
-	if err != nil {
-		return nil, err
-	}
-	cmd := command.NewCommand(cmdName)
-	if cmd == nil {
-		return nil, gerror.ErrInvalidCmdName
-	}
-	err = cmd.FromFrame(f)
-	if err != nil {
-		return nil, err
-	}
-	return cmd, nil
+	return command.Parse(f)
 }
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/gerror"
+	"github.com/ynachi/gcache/monitor"
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// newTestServer creates a server which is not listening, for tests which only need its state.
+func newTestServer(t *testing.T, databases int) *Server {
+	s := &Server{
+		config:   defaultConfig(),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		pubsub:   newPubSub(),
+		clients:  make(map[int64]*Connection),
+		pause:    newClientPause(),
+		monitors: newMonitors(),
+		slowLog:  monitor.NewSlowLog(DefaultSlowLogSlowerThan, DefaultSlowLogMaxLen),
+		latency:  monitor.NewLatency(0),
+	}
+	s.tracking = newTracker(s, DefaultTrackingTableMaxKeys)
+	s.acl = acl.New(knownCommands{})
+	s.maxClients.Store(DefaultMaxClients)
+	s.setOutputBufferLimits(s.config.OutputBufferLimits)
+	s.tunables = db.NewTunables()
+	for i := 0; i < databases; i++ {
+		cache, err := db.NewCache(10, "LRU")
+		if err != nil {
+			t.Fatalf("unable to create cache: %v", err)
+		}
+		cache.SetTunables(s.tunables)
+		s.dbs = append(s.dbs, cache)
+	}
+	return s
+}
+
+func TestConnection_Select(t *testing.T) {
+	s := newTestServer(t, 2)
+	conn := &Connection{server: s}
+
+	assert.Same(t, s.dbs[0], conn.DB())
+	assert.NoError(t, conn.Select(1))
+	assert.Same(t, s.dbs[1], conn.DB())
+	assert.Equal(t, gerror.ErrDBIndexOutRange, conn.Select(2))
+	assert.Same(t, s.dbs[1], conn.DB())
+}
+
+func TestConnection_SwapDB(t *testing.T) {
+	s := newTestServer(t, 2)
+	s.dbs[0].Set("hello", "world")
+	first := &Connection{server: s}
+	second := &Connection{server: s, dbIndex: 1}
+
+	assert.NoError(t, first.SwapDB(0, 1))
+	_, ok := first.DB().Get("hello")
+	assert.False(t, ok)
+	value, ok := second.DB().Get("hello")
+	assert.True(t, ok)
+	assert.Equal(t, "world", value)
+
+	assert.Equal(t, gerror.ErrDBIndexOutRange, first.SwapDB(0, 2))
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/gerror"
+	"net"
+)
+
+// protectCommands is the middleware rejecting the protected commands, like DEBUG, unless enable-debug-command
+// allows the client to run them.
+func (s *Server) protectCommands(next Handler) Handler {
+	return func(call *Call) error {
+		if call.Spec == nil || !call.Spec.HasFlag(command.FlagProtected) {
+			return next(call)
+		}
+		switch s.config.EnableDebugCommand {
+		case DebugCommandYes:
+			return next(call)
+		case DebugCommandLocal:
+			if call.Conn.isLocal() {
+				return next(call)
+			}
+		}
+		return fmt.Errorf("%s %w", call.Spec.Name, gerror.ErrProtectedCmd)
+	}
+}
+
+// isLocal tells if the client is connected through the unix socket or the loopback interface.
+func (c *Connection) isLocal() bool {
+	addr := c.conn.RemoteAddr()
+	if addr == nil {
+		return false
+	}
+	if addr.Network() == "unix" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// SetActiveExpire enables or disables the background expiration of the keys of all the databases.
+func (c *Connection) SetActiveExpire(enabled bool) {
+	c.server.activeExpireOff.Store(!enabled)
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDebug_Protected(t *testing.T) {
+	s := startTestServer(t)
+	client := dialTestClient(t, s.Address())
+	client.send("DEBUG", "DIGEST")
+	assert.True(t, strings.HasPrefix(client.read(), "-debug command not allowed."), "DEBUG should be disabled by default")
+	client.send("CONFIG", "GET", "enable-debug-command")
+	assert.Equal(t, "*2\r\n$20\r\nenable-debug-command\r\n$2\r\nno\r\n", client.read())
+
+	path := filepath.Join(t.TempDir(), "gcache.sock")
+	s = startTestServer(t, WithEnableDebugCommand(DebugCommandLocal), WithUnixSocket(path))
+	for _, client := range []*testClient{dialTestClient(t, s.Address()), dialUnixClient(t, path)} {
+		client.send("DEBUG", "DIGEST")
+		assert.Equal(t, "$40\r\n"+strings.Repeat("0", 40)+"\r\n", client.read(), "local clients should run DEBUG")
+	}
+
+	_, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LRU", WithEnableDebugCommand("maybe"))
+	assert.ErrorIs(t, err, ErrInvalidDebugMode)
+}
+
+func TestConnection_IsLocal(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+	assert.False(t, (&Connection{conn: local}).isLocal(), "only loopback and unix connections are local")
+}
+
+func TestDebug_SleepAndPopulate(t *testing.T) {
+	s := startTestServer(t, WithEnableDebugCommand(DebugCommandYes), WithSlowLog(50*time.Millisecond, 10))
+	client := dialTestClient(t, s.Address())
+
+	start := time.Now()
+	client.send("DEBUG", "SLEEP", "0.1")
+	assert.Equal(t, "+OK\r\n", client.read())
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	client.send("SLOWLOG", "GET")
+	assert.Contains(t, client.read(), "$5\r\nDEBUG\r\n$5\r\nSLEEP\r\n$3\r\n0.1\r\n")
+	client.send("DEBUG", "SLEEP", "soon")
+	assert.True(t, strings.HasPrefix(client.read(), "-"))
+
+	client.send("DEBUG", "POPULATE", "3", "user", "10")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("GET", "user:2")
+	assert.Equal(t, "$10\r\nvalue:2\x00\x00\x00\r\n", client.read())
+	client.send("GET", "user:3")
+	assert.Equal(t, "$-1\r\n", client.read())
+}
+
+func TestDebug_DigestAndReload(t *testing.T) {
+	s := startTestServer(t, WithEnableDebugCommand(DebugCommandYes))
+	client := dialTestClient(t, s.Address())
+
+	client.send("SET", "key", "value")
+	client.read()
+	client.send("DEBUG", "DIGEST")
+	digest := client.read()
+	assert.NotEqual(t, "$40\r\n"+strings.Repeat("0", 40)+"\r\n", digest)
+	client.send("DEBUG", "DIGEST-VALUE", "key", "missing")
+	values := client.read()
+	assert.True(t, strings.HasPrefix(values, "*2\r\n$40\r\n"), values)
+	assert.Contains(t, values, strings.Repeat("0", 40), "missing keys should have an all zeroes digest")
+
+	client.send("DEBUG", "RELOAD")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("DEBUG", "DIGEST")
+	assert.Equal(t, digest, client.read(), "the data should survive a reload")
+
+	client.send("MOVE", "key", "1")
+	client.read()
+	client.send("DEBUG", "DIGEST")
+	assert.NotEqual(t, digest, client.read(), "the digest should depend on the database holding the keys")
+}
+
+func TestDebug_Object(t *testing.T) {
+	s := startTestServer(t, WithEnableDebugCommand(DebugCommandYes))
+	client := dialTestClient(t, s.Address())
+
+	client.send("SET", "counter", "42")
+	client.read()
+	client.send("SET", "text", "hello")
+	client.read()
+
+	client.send("OBJECT", "ENCODING", "counter")
+	assert.Equal(t, "$3\r\nint\r\n", client.read())
+	client.send("OBJECT", "ENCODING", "text")
+	assert.Equal(t, "$6\r\nembstr\r\n", client.read())
+	client.send("OBJECT", "ENCODING", "missing")
+	assert.Equal(t, "$-1\r\n", client.read())
+	client.send("OBJECT", "REFCOUNT", "text")
+	assert.Equal(t, ":1\r\n", client.read())
+	client.send("OBJECT", "REFCOUNT", "missing")
+	assert.Equal(t, "$-1\r\n", client.read())
+
+	client.send("DEBUG", "OBJECT", "text")
+	assert.Contains(t, client.read(), "refcount:1 encoding:embstr serializedlength:5 lru:")
+	client.send("DEBUG", "OBJECT", "missing")
+	assert.Equal(t, "-no such key\r\n", client.read())
+}
+
+func TestDebug_SetActiveExpire(t *testing.T) {
+	s := startTestServer(t, WithEnableDebugCommand(DebugCommandYes))
+	client := dialTestClient(t, s.Address())
+
+	client.send("DEBUG", "SET-ACTIVE-EXPIRE", "0")
+	assert.Equal(t, "+OK\r\n", client.read())
+	assert.True(t, s.activeExpireOff.Load())
+	client.send("SET", "key", "value", "PX", "10")
+	client.read()
+	time.Sleep(300 * time.Millisecond)
+	cache, _ := s.database(0)
+	assert.Equal(t, int64(1), cache.Size(), "the key should not be expired in the background")
+
+	client.send("DEBUG", "SET-ACTIVE-EXPIRE", "1")
+	client.read()
+	assert.Eventually(t, func() bool { return cache.Size() == 0 }, 2*time.Second, 10*time.Millisecond)
+	client.send("DEBUG", "SET-ACTIVE-EXPIRE", "2")
+	assert.Equal(t, "-syntax error\r\n", client.read())
+}
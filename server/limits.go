@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/gerror"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client classes having their own output buffer limits.
+const (
+	ClassNormal = "normal"
+	ClassPubSub = "pubsub"
+)
+
+// OutputBufferLimit bounds the data pending for a client which does not read fast enough, like the messages
+// published to a subscriber, and the size of the replies written to it between two flushes, like the reply of a
+// large MEMORY BIGKEYS. Zero fields disable the limits.
+type OutputBufferLimit struct {
+	// Hard is the number of bytes over which the client is disconnected right away.
+	Hard int
+	// Soft is the number of bytes the client can stay over for SoftDuration at most.
+	Soft         int
+	SoftDuration time.Duration
+}
+
+// defaultOutputBufferLimits returns the limits of each class of clients, the ones of Redis.
+func defaultOutputBufferLimits() map[string]OutputBufferLimit {
+	return map[string]OutputBufferLimit{
+		ClassNormal: {},
+		ClassPubSub: {Hard: DefaultPubSubBufferLimit, Soft: 8 * 1024 * 1024, SoftDuration: time.Minute},
+	}
+}
+
+// validateOutputBufferLimits checks that limits are set for known classes and are not negative.
+func validateOutputBufferLimits(limits map[string]OutputBufferLimit) error {
+	for class, limit := range limits {
+		if class != ClassNormal && class != ClassPubSub {
+			return fmt.Errorf("%w '%s'", gerror.ErrInvalidClientType, class)
+		}
+		if limit.Hard < 0 || limit.Soft < 0 || limit.SoftDuration < 0 {
+			return ErrInvalidBufferLimit
+		}
+	}
+	return nil
+}
+
+// parseOutputBufferLimits reads the client-output-buffer-limit setting, made of a class followed by its hard limit,
+// soft limit and soft seconds, for one or more classes. Sizes can have a k, kb, m, mb, g or gb unit.
+// Classes not given keep the limits of current.
+func parseOutputBufferLimits(value string, current map[string]OutputBufferLimit) (map[string]OutputBufferLimit, error) {
+	fields := strings.Fields(value)
+	if len(fields) == 0 || len(fields)%4 != 0 {
+		return nil, gerror.ErrSyntax
+	}
+	limits := make(map[string]OutputBufferLimit, len(current))
+	for class, limit := range current {
+		limits[class] = limit
+	}
+	for i := 0; i < len(fields); i += 4 {
+		class := strings.ToLower(fields[i])
+		hard, err := parseBytes(fields[i+1])
+		if err != nil {
+			return nil, err
+		}
+		soft, err := parseBytes(fields[i+2])
+		if err != nil {
+			return nil, err
+		}
+		seconds, err := strconv.ParseInt(fields[i+3], 10, 64)
+		if err != nil {
+			return nil, gerror.ErrNotInteger
+		}
+		limits[class] = OutputBufferLimit{Hard: int(hard), Soft: int(soft), SoftDuration: time.Duration(seconds) * time.Second}
+	}
+	if err := validateOutputBufferLimits(limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// formatOutputBufferLimits writes limits as the client-output-buffer-limit setting, sizes in bytes.
+func formatOutputBufferLimits(limits map[string]OutputBufferLimit) string {
+	classes := make([]string, 0, len(limits))
+	for class := range limits {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	parts := make([]string, 0, len(classes))
+	for _, class := range classes {
+		limit := limits[class]
+		parts = append(parts, fmt.Sprintf("%s %d %d %d", class, limit.Hard, limit.Soft, int64(limit.SoftDuration.Seconds())))
+	}
+	return strings.Join(parts, " ")
+}
+
+// parseBytes reads a number of bytes, with an optional k, kb, m, mb, g or gb unit.
+func parseBytes(value string) (int64, error) {
+	units := []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"kb", 1024}, {"mb", 1024 * 1024}, {"gb", 1024 * 1024 * 1024},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	}
+	value = strings.ToLower(value)
+	multiplier := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(value, unit.suffix) {
+			value, multiplier = strings.TrimSuffix(value, unit.suffix), unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, gerror.ErrNotInteger
+	}
+	return n * multiplier, nil
+}
+
+// outputBufferLimit returns the limit of the class of a client.
+func (s *Server) outputBufferLimit(class string) OutputBufferLimit {
+	return (*s.outputLimits.Load())[class]
+}
+
+// setOutputBufferLimits replaces the limits of the clients.
+func (s *Server) setOutputBufferLimits(limits map[string]OutputBufferLimit) {
+	s.outputLimits.Store(&limits)
+}
+
+// checkReplyLimit checks the bytes of the replies written since the last flush against the output buffer limit
+// of the class of the client. The soft limit is tracked across commands, as long as the replies exceed it.
+func (c *Connection) checkReplyLimit(pending int) error {
+	limit := c.server.outputBufferLimit(c.limitClass())
+	if limit.Hard > 0 && pending > limit.Hard {
+		return gerror.ErrOutputBufferLimit
+	}
+	if limit.Soft == 0 || pending <= limit.Soft {
+		c.replyOverSoftSince = time.Time{}
+		return nil
+	}
+	if c.replyOverSoftSince.IsZero() {
+		c.replyOverSoftSince = time.Now()
+	}
+	if time.Since(c.replyOverSoftSince) >= limit.SoftDuration {
+		return gerror.ErrOutputBufferLimit
+	}
+	return nil
+}
+
+// setIdleDeadline makes reading the next command fail if the client stays idle longer than the timeout.
+// Subscribers and monitors only wait for messages, so they are never considered idle.
+func (c *Connection) setIdleDeadline() {
+	timeout := time.Duration(c.server.idleTimeout.Load())
+	deadline := time.Time{}
+	if timeout > 0 && !c.subscribed() && !c.monitor.Load() {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := c.conn.SetReadDeadline(deadline); err != nil {
+		c.server.logger.Error("unable to set read deadline", "client_ip", c.clientIP, "error", err)
+	}
+}
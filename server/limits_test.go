@@ -0,0 +1,101 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutbox_Limits(t *testing.T) {
+	o := newOutbox()
+	limit := OutputBufferLimit{Hard: 32, Soft: 10, SoftDuration: 50 * time.Millisecond}
+
+	assert.True(t, o.push(make([]byte, 8), limit))
+	assert.False(t, o.push(make([]byte, 30), limit), "the hard limit should never be exceeded")
+	assert.True(t, o.push(make([]byte, 8), limit), "the soft limit can be exceeded for a while")
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, o.push(make([]byte, 8), limit))
+
+	// draining the outbox resets the soft limit
+	o.take()
+	assert.True(t, o.push(make([]byte, 16), limit))
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "100", want: 100},
+		{value: "2k", want: 2000},
+		{value: "2kb", want: 2048},
+		{value: "32MB", want: 32 * 1024 * 1024},
+		{value: "1g", want: 1000 * 1000 * 1000},
+		{value: "-1", wantErr: true},
+		{value: "mb", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseBytes(tt.value)
+			assert.Equal(t, tt.wantErr, err != nil)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestServer_MaxClients(t *testing.T) {
+	s := startTestServer(t, WithMaxClients(1))
+	first := dialTestClient(t, s.Address())
+	first.send("PING")
+	assert.Equal(t, "+PONG\r\n", first.read())
+
+	second := dialTestClient(t, s.Address())
+	assert.Equal(t, "-max number of clients reached\r\n", second.read())
+	second.assertDisconnected()
+	assert.Equal(t, int64(1), s.Stats().ConnectionsRejected)
+	assert.Equal(t, int64(2), s.Stats().ConnectionsReceived)
+
+	// the client slot is released once the first client leaves
+	_ = first.conn.Close()
+	assert.Eventually(t, func() bool {
+		third := dialTestClient(t, s.Address())
+		third.send("PING")
+		return third.read() == "+PONG\r\n"
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestServer_IdleTimeout(t *testing.T) {
+	s := startTestServer(t, WithIdleTimeout(100*time.Millisecond))
+	idle := dialTestClient(t, s.Address())
+	subscriber := dialTestClient(t, s.Address())
+	subscriber.send("SUBSCRIBE", "news")
+	subscriber.read()
+
+	idle.assertDisconnected()
+
+	// subscribers and monitors are not idle while waiting for messages
+	monitor := dialTestClient(t, s.Address())
+	monitor.send("MONITOR")
+	assert.Equal(t, "+OK\r\n", monitor.read())
+	time.Sleep(200 * time.Millisecond)
+	subscriber.send("PING")
+	assert.Equal(t, "+PONG\r\n", subscriber.read())
+	assert.Contains(t, monitor.read(), `"PING"`)
+}
+
+func TestServer_ReplyOutputBufferLimit(t *testing.T) {
+	s := startTestServer(t, WithOutputBufferLimit(ClassNormal, OutputBufferLimit{Hard: 100}))
+	client := dialTestClient(t, s.Address())
+
+	client.send("SET", "small", "value")
+	client.read()
+	client.send("SET", "large", strings.Repeat("v", 200))
+	client.read()
+	client.send("GET", "small")
+	assert.Equal(t, "$5\r\nvalue\r\n", client.read())
+	client.send("GET", "large")
+	client.assertDisconnected()
+}
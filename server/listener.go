@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// openListeners listens on the TCP address, unless disabled, and on the unix socket if one is configured.
+// TLS only applies to TCP: clients of the unix socket are on the same host.
+func openListeners(address string, config Config, creds *tlsCredentials) ([]net.Listener, error) {
+	listeners := make([]net.Listener, 0, 2)
+	if !config.DisableTCP {
+		// a negative interval disables the keepalive probes, 0 would select a default one
+		keepAlive := config.TCPKeepAlive
+		if keepAlive == 0 {
+			keepAlive = -1
+		}
+		listener, err := (&net.ListenConfig{KeepAlive: keepAlive}).Listen(context.Background(), "tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		if creds != nil {
+			listener = tls.NewListener(listener, creds.listenerConfig())
+		}
+		listeners = append(listeners, listener)
+	}
+	if config.UnixSocket != "" {
+		listener, err := listenUnix(config.UnixSocket, config.UnixSocketPerm)
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on a unix socket with the given permissions. A socket left over by a previous process at the
+// same path is replaced, but not another kind of file. The socket is removed when the listener is closed.
+func listenUnix(path string, perm fs.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("unable to listen on %s: the file exists and is not a socket", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Chmod(path, perm); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// The accept loop waits between minAcceptBackoff and maxAcceptBackoff after a failure, doubling each time.
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// listen waits for new connections on a listener until it is closed, which the server does when it stops.
+// Temporary failures, like running out of file descriptors, are retried with an exponential backoff so the
+// server does not spin while the resources are exhausted.
+func (s *Server) listen(ctx context.Context, listener net.Listener, newConns chan<- *Connection) {
+	backoff := time.Duration(0)
+	for {
+		c, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) || ctx.Err() != nil {
+				s.logger.Debug("listener closed", "address", listener.Addr().String())
+				return
+			}
+			s.stats.acceptErrors.Add(1)
+			if isTemporaryAcceptError(err) {
+				backoff = nextAcceptBackoff(backoff)
+				s.logger.Warn("error accepting connection, retrying", "error", err, "backoff", backoff)
+			} else {
+				backoff = maxAcceptBackoff
+				s.logger.Error("unexpected error accepting connection, retrying", "error", err, "backoff", backoff)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		backoff = 0
+		s.stats.connectionsReceived.Add(1)
+		select {
+		case <-ctx.Done():
+			_ = c.Close()
+			return
+		case newConns <- MakeConnection(c, s):
+		}
+	}
+}
+
+// nextAcceptBackoff doubles the time to wait before accepting connections again, within the bounds.
+func nextAcceptBackoff(backoff time.Duration) time.Duration {
+	if backoff == 0 {
+		return minAcceptBackoff
+	}
+	return min(2*backoff, maxAcceptBackoff)
+}
+
+// isTemporaryAcceptError tells if an accept failure is caused by a lack of resources or by a client giving up
+// during the handshake, which should go away by themselves.
+func isTemporaryAcceptError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EMFILE, syscall.ENFILE, syscall.ENOBUFS, syscall.ENOMEM,
+		syscall.ECONNABORTED, syscall.ECONNRESET} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	var nErr net.Error
+	return errors.As(err, &nErr) && nErr.Timeout()
+}
+
+// closeListeners stops accepting connections.
+func (s *Server) closeListeners() {
+	for _, listener := range s.listeners {
+		if err := listener.Close(); err != nil {
+			s.logger.Error("error closing listener", "error", err)
+		}
+	}
+}
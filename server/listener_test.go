@@ -0,0 +1,167 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func dialUnixClient(t *testing.T, path string) *testClient {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("unable to connect to %s: %v", path, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gcache.sock")
+	s := startTestServer(t, WithUnixSocket(path), WithUnixSocketPerm(0770))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, fs.ModeSocket, info.Mode().Type())
+	assert.Equal(t, fs.FileMode(0770), info.Mode().Perm())
+
+	// both listeners share the same databases
+	local := dialUnixClient(t, path)
+	local.send("SET", "hello", "world")
+	assert.Equal(t, "+ok\r\n", local.read())
+	remote := dialTestClient(t, s.Address())
+	remote.send("GET", "hello")
+	assert.Equal(t, "$5\r\nworld\r\n", remote.read())
+	remote.send("CONFIG", "GET", "unixsocketperm")
+	assert.Equal(t, "*2\r\n$14\r\nunixsocketperm\r\n$3\r\n770\r\n", remote.read())
+}
+
+func TestServer_UnixSocketOnly(t *testing.T) {
+	_, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LRU", WithoutTCP())
+	assert.Equal(t, ErrNoListener, err)
+
+	path := filepath.Join(t.TempDir(), "gcache.sock")
+	// a socket left over by a previous process is replaced
+	stale, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	assert.NoError(t, stale.Close())
+
+	s, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LRU", WithUnixSocket(path), WithoutTCP())
+	assert.NoError(t, err)
+	assert.Equal(t, path, s.Address())
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.Start(ctx)
+
+	client := dialUnixClient(t, path)
+	client.send("PING")
+	assert.Equal(t, "+PONG\r\n", client.read())
+
+	// the socket is removed once the server stops
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_UnixSocketNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gcache.sock")
+	assert.NoError(t, os.WriteFile(path, []byte("data"), 0600))
+	_, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LRU", WithUnixSocket(path))
+	assert.Error(t, err)
+	data, _ := os.ReadFile(path)
+	assert.Equal(t, "data", string(data), "other files should not be replaced")
+}
+
+// fakeListener fails to accept connections with the errors it is given, then blocks until it is closed.
+type fakeListener struct {
+	errs   chan error
+	closed chan struct{}
+}
+
+func newFakeListener(errs ...error) *fakeListener {
+	l := &fakeListener{errs: make(chan error, len(errs)), closed: make(chan struct{})}
+	for _, err := range errs {
+		l.errs <- err
+	}
+	return l
+}
+
+func (l *fakeListener) Accept() (net.Conn, error) {
+	select {
+	case err := <-l.errs:
+		return nil, err
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *fakeListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func (l *fakeListener) Addr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
+
+func TestServer_ListenBackoff(t *testing.T) {
+	s := newTestServer(t, 1)
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	listener := newFakeListener(emfile, emfile, emfile)
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		s.listen(context.Background(), listener, make(chan *Connection))
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool { return s.Stats().AcceptErrors == 3 }, 2*time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond, "the loop should wait 5ms then 10ms between failures")
+
+	// closing the listener stops the loop without counting an error
+	assert.NoError(t, listener.Close())
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the accept loop should stop once the listener is closed")
+	}
+	assert.Equal(t, int64(3), s.Stats().AcceptErrors)
+}
+
+func TestServer_ListenStopsOnCancel(t *testing.T) {
+	s := newTestServer(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := newFakeListener(errors.New("unexpected"))
+	done := make(chan struct{})
+	go func() {
+		s.listen(ctx, listener, make(chan *Connection))
+		close(done)
+	}()
+
+	// the loop waits for the maximum backoff after an unexpected error, unless the server stops
+	assert.Eventually(t, func() bool { return s.Stats().AcceptErrors == 1 }, time.Second, time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(maxAcceptBackoff / 2):
+		t.Fatal("the accept loop should stop once the server stops")
+	}
+}
+
+func TestIsTemporaryAcceptError(t *testing.T) {
+	assert.True(t, isTemporaryAcceptError(&net.OpError{Op: "accept", Err: os.NewSyscallError("accept", syscall.ENFILE)}))
+	assert.True(t, isTemporaryAcceptError(syscall.ECONNABORTED))
+	assert.False(t, isTemporaryAcceptError(errors.New("unexpected")))
+	assert.Equal(t, minAcceptBackoff, nextAcceptBackoff(0))
+	assert.Equal(t, 2*minAcceptBackoff, nextAcceptBackoff(minAcceptBackoff))
+	assert.Equal(t, maxAcceptBackoff, nextAcceptBackoff(maxAcceptBackoff))
+}
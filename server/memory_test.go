@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestMemory_Usage(t *testing.T) {
+	s := startTestServer(t)
+	client := dialTestClient(t, s.Address())
+
+	client.send("SET", "small", "v")
+	client.read()
+	client.send("SET", "large", strings.Repeat("v", 1001))
+	client.read()
+
+	client.send("MEMORY", "USAGE", "missing")
+	assert.Equal(t, "$-1\r\n", client.read())
+	var small, large int
+	client.send("MEMORY", "USAGE", "small")
+	_, err := fmt.Sscanf(client.read(), ":%d\r\n", &small)
+	assert.NoError(t, err)
+	client.send("MEMORY", "USAGE", "large", "SAMPLES", "5")
+	_, err = fmt.Sscanf(client.read(), ":%d\r\n", &large)
+	assert.NoError(t, err)
+	assert.Equal(t, small+1000, large, "the size of the value should be counted")
+	client.send("MEMORY", "USAGE", "large", "SAMPLES")
+	assert.True(t, strings.HasPrefix(client.read(), "-"))
+}
+
+func TestMemory_StatsAndDoctor(t *testing.T) {
+	s := startTestServer(t)
+	client := dialTestClient(t, s.Address())
+
+	client.send("MEMORY", "DOCTOR")
+	assert.Contains(t, client.read(), "nothing to diagnose")
+
+	client.send("SELECT", "2")
+	client.read()
+	client.send("SET", "key", "value")
+	client.read()
+	client.send("HELLO", "3")
+	client.read()
+	client.send("MEMORY", "STATS")
+	stats := client.read()
+	assert.True(t, strings.HasPrefix(stats, "%15\r\n"), stats)
+	for _, field := range []string{"total.allocated", "clients.normal", "overhead.hashtable.main",
+		"overhead.eviction-policy", "keys.count\r\n:1\r\n", "dataset.bytes", "db.2\r\n%3\r\n"} {
+		assert.Contains(t, stats, field)
+	}
+	assert.NotContains(t, stats, "db.0")
+
+	client.send("MEMORY", "DOCTOR")
+	assert.Contains(t, client.read(), "The keys use more memory to be indexed", "tiny values should be reported")
+}
+
+func TestMemory_BigAndHotKeys(t *testing.T) {
+	s, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LFU")
+	if err != nil {
+		t.Fatalf("unable to create server: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Start(ctx)
+	client := dialTestClient(t, s.Address())
+
+	for i, key := range []string{"a", "b", "c"} {
+		client.send("SET", key, strings.Repeat("v", (i+1)*100))
+		client.read()
+		for j := 0; j < i; j++ {
+			client.send("GET", key)
+			client.read()
+		}
+	}
+
+	client.send("MEMORY", "BIGKEYS", "COUNT", "2")
+	bigKeys := client.read()
+	assert.True(t, strings.HasPrefix(bigKeys, "*2\r\n$6\r\nstring\r\n*2\r\n*2\r\n$1\r\nc\r\n:"), bigKeys)
+	assert.Contains(t, bigKeys, "$1\r\nb\r\n")
+	client.send("MEMORY", "HOTKEYS")
+	assert.Equal(t, "*3\r\n*2\r\n$1\r\nc\r\n:3\r\n*2\r\n$1\r\nb\r\n:2\r\n*2\r\n$1\r\na\r\n:1\r\n", client.read())
+	client.send("OBJECT", "FREQ", "c")
+	assert.Equal(t, ":3\r\n", client.read(), "OBJECT FREQ should agree with HOTKEYS")
+	client.send("MEMORY", "HOTKEYS", "COUNT", "0")
+	assert.Equal(t, "-value is not an integer or out of range\r\n", client.read())
+
+	lru := startTestServer(t)
+	other := dialTestClient(t, lru.Address())
+	other.send("MEMORY", "HOTKEYS")
+	assert.Equal(t, "-An LFU maxmemory policy is not selected, access frequency not tracked\r\n", other.read())
+	other.send("SET", "key", "value")
+	other.read()
+	other.send("OBJECT", "FREQ", "key")
+	assert.Equal(t, "-An LFU maxmemory policy is not selected, access frequency not tracked\r\n", other.read())
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+)
+
+// Call is a command being processed on behalf of a client.
+type Call struct {
+	Conn    *Connection
+	Command command.Command
+	// Spec is the metadata of the command, as registered in the command package.
+	Spec *command.Spec
+	// Args are the arguments of the command as received, its name first. They locate its keys.
+	Args []string
+}
+
+// callArgs returns the arguments of a request, its name first.
+func callArgs(request *frame.Array) []string {
+	if request == nil {
+		return nil
+	}
+	args := make([]string, 0, request.Size())
+	for i := 0; i < request.Size(); i++ {
+		if arg, ok := request.Get(i).(*frame.BulkString); ok {
+			args = append(args, arg.Value())
+		}
+	}
+	return args
+}
+
+// Handler processes a command. An error is sent back to the client as an error frame, in place of the reply.
+type Handler func(call *Call) error
+
+// Middleware wraps the processing of the commands, for concerns like logging, metrics or access control.
+// It calls next to carry on with the processing, or returns an error without calling it to reject the command.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware registers middlewares around the processing of every command. They run in the order given,
+// the first one being the outermost, after the ones registered by previous options.
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(c *Config) {
+		c.Middlewares = append(c.Middlewares, middlewares...)
+	}
+}
+
+// chain wraps a handler with middlewares, the first one being the outermost.
+func chain(middlewares []Middleware, final Handler) Handler {
+	handler := final
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
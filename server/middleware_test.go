@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/command"
+	"testing"
+)
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(call *Call) error {
+				calls = append(calls, name+" before")
+				err := next(call)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	handler := chain([]Middleware{trace("outer"), trace("inner")}, func(call *Call) error {
+		calls = append(calls, "command")
+		return nil
+	})
+
+	assert.NoError(t, handler(&Call{}))
+	assert.Equal(t, []string{"outer before", "inner before", "command", "inner after", "outer after"}, calls)
+}
+
+func TestServer_DispatchMiddleware(t *testing.T) {
+	errReadOnly := errors.New("READONLY You can't write against a read only replica.")
+	readOnly := func(next Handler) Handler {
+		return func(call *Call) error {
+			if call.Spec.HasFlag(command.FlagWrite) {
+				return errReadOnly
+			}
+			return next(call)
+		}
+	}
+	s := newTestServer(t, 1)
+	s.handler = chain([]Middleware{readOnly}, s.execute)
+	conn, out := newTestConnection(t, s)
+
+	s.dispatch(conn, newTestCommand(t, "SET", "hello", "world"))
+	assert.Equal(t, "-"+errReadOnly.Error()+"\r\n", out.String())
+	assert.False(t, s.dbs[0].Exists("hello"))
+
+	out.Reset()
+	s.dispatch(conn, newTestCommand(t, "GET", "hello"))
+	assert.Equal(t, "$-1\r\n", out.String())
+}
+
+func TestServer_DispatchMiddlewareAbortsTransaction(t *testing.T) {
+	reject := func(next Handler) Handler {
+		return func(call *Call) error {
+			if call.Command.Name() == "set" {
+				return errors.New("rejected")
+			}
+			return next(call)
+		}
+	}
+	s := newTestServer(t, 1)
+	s.handler = chain([]Middleware{reject}, s.execute)
+	conn, _ := newTestConnection(t, s)
+
+	s.dispatch(conn, newTestCommand(t, "MULTI"))
+	s.dispatch(conn, newTestCommand(t, "SET", "hello", "world"))
+	assert.True(t, conn.tx.aborted)
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/monitor"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// redactedCommands are the commands whose arguments may hold passwords, along with the number of arguments the
+// slow log keeps, their name and subcommand most of the time.
+var redactedCommands = map[string]int{"auth": 1, "hello": 2, "acl": 2, "config": 2}
+
+// recordLatency measures the time taken to apply the commands, for the slow log and the latency monitor.
+// It is the innermost middleware, so that the time spent waiting in CLIENT PAUSE is left out. Commands queued
+// in a transaction are not measured, EXEC being measured as a whole instead.
+func (s *Server) recordLatency(next Handler) Handler {
+	return func(call *Call) error {
+		if call.Conn.tx.active && !isTransactionControl(call.Command) {
+			return next(call)
+		}
+		start := time.Now()
+		err := next(call)
+		elapsed := time.Since(start)
+
+		s.latency.RecordCommand(call.Command.Name(), elapsed)
+		event := monitor.EventCommand
+		if call.Spec != nil && call.Spec.HasFlag(command.FlagFast) {
+			event = monitor.EventFastCommand
+		}
+		s.latency.AddSample(event, elapsed)
+		if s.slowLog.IsSlow(elapsed) {
+			s.slowLog.Add(monitor.SlowLogEntry{
+				Time:       start,
+				Duration:   elapsed,
+				Args:       requestArgs(call.Conn.request, call.Command.Name()),
+				ClientAddr: call.Conn.clientIP,
+				ClientName: call.Conn.ClientName(),
+			})
+		}
+		return err
+	}
+}
+
+// requestArgs returns the name and arguments of the command a client sent, as the slow log shows them.
+func requestArgs(request *frame.Array, name string) []string {
+	if request == nil {
+		return []string{name}
+	}
+	kept, redacted := redactedCommands[name]
+	args := make([]string, 0, request.Size())
+	for i := 0; i < request.Size(); i++ {
+		if redacted && i >= kept {
+			args = append(args, "(redacted)")
+			continue
+		}
+		if arg, ok := request.Get(i).(*frame.BulkString); ok {
+			args = append(args, arg.Value())
+		}
+	}
+	return args
+}
+
+// SlowLog returns the log of the slow commands.
+func (c *Connection) SlowLog() *monitor.SlowLog {
+	return c.server.slowLog
+}
+
+// Latency returns the latency monitor of the server.
+func (c *Connection) Latency() *monitor.Latency {
+	return c.server.latency
+}
+
+// monitors holds the clients which ran MONITOR, receiving the commands processed by the others.
+type monitors struct {
+	mu    sync.RWMutex
+	conns map[*Connection]struct{}
+	// count is the number of monitors, read without locking so that commands are not formatted when there are none.
+	count atomic.Int32
+}
+
+func newMonitors() *monitors {
+	return &monitors{conns: make(map[*Connection]struct{})}
+}
+
+func (m *monitors) add(conn *Connection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.conns[conn]; !ok {
+		m.conns[conn] = struct{}{}
+		m.count.Add(1)
+	}
+}
+
+func (m *monitors) remove(conn *Connection) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.conns[conn]; ok {
+		delete(m.conns, conn)
+		m.count.Add(-1)
+	}
+}
+
+// feed queues a command processed on behalf of a client in the outboxes of the monitors, other than the client.
+func (m *monitors) feed(from *Connection, line *frame.SimpleString) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for conn := range m.conns {
+		if conn != from {
+			conn.push(line)
+		}
+	}
+}
+
+// feedMonitors sends the commands to the clients running MONITOR. It comes right before recordLatency, so that
+// monitors only see the commands the other middlewares let through.
+func (s *Server) feedMonitors(next Handler) Handler {
+	return func(call *Call) error {
+		if s.monitors.count.Load() > 0 {
+			s.monitors.feed(call.Conn, monitorLine(time.Now(), call.Conn, requestArgs(call.Conn.request, call.Command.Name())))
+		}
+		return next(call)
+	}
+}
+
+// monitorLine describes a command like Redis does for MONITOR: the time, the database and address of the client,
+// then the quoted arguments.
+func monitorLine(now time.Time, conn *Connection, args []string) *frame.SimpleString {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d.%06d [%d %s]", now.Unix(), now.Nanosecond()/1000, conn.dbIndex, conn.clientIP)
+	for _, arg := range args {
+		sb.WriteByte(' ')
+		sb.WriteString(quoteArg(arg))
+	}
+	// quoting escapes the line breaks, which simple strings cannot hold
+	line, _ := frame.NewSimpleString(sb.String())
+	return line
+}
+
+// quoteArg quotes an argument, escaping the quotes, backslashes and non printable bytes.
+func quoteArg(arg string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(arg); i++ {
+		switch b := arg[i]; b {
+		case '\\', '"':
+			sb.WriteByte('\\')
+			sb.WriteByte(b)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\a':
+			sb.WriteString(`\a`)
+		case '\b':
+			sb.WriteString(`\b`)
+		default:
+			if b < 0x20 || b > 0x7e {
+				fmt.Fprintf(&sb, "\\x%02x", b)
+			} else {
+				sb.WriteByte(b)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// Monitor makes the connection receive the commands processed on behalf of the other clients.
+func (c *Connection) Monitor() {
+	c.monitor.Store(true)
+	c.server.monitors.add(c)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/frame"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowLog_Commands(t *testing.T) {
+	s := startTestServer(t, WithSlowLog(0, 3))
+	client := dialTestClient(t, s.Address())
+
+	client.send("CLIENT", "SETNAME", "worker")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("SET", "key", "value")
+	assert.Equal(t, "+ok\r\n", client.read())
+	client.send("AUTH", "secret")
+	client.read()
+	client.send("SLOWLOG", "LEN")
+	assert.Equal(t, ":3\r\n", client.read())
+
+	client.send("SLOWLOG", "GET", "2")
+	entries := client.read()
+	assert.True(t, strings.HasPrefix(entries, "*2\r\n*6\r\n:3\r\n"), "entries should be returned newest first")
+	assert.Contains(t, entries, "*2\r\n$7\r\nSLOWLOG\r\n$3\r\nLEN\r\n")
+	assert.Contains(t, entries, "*2\r\n$4\r\nAUTH\r\n$10\r\n(redacted)\r\n", "passwords should not be logged")
+	assert.NotContains(t, entries, "secret")
+	assert.Contains(t, entries, frame.NewBulkString(client.conn.LocalAddr().String()).String()+"$6\r\nworker\r\n")
+
+	client.send("SLOWLOG", "GET", "-1")
+	assert.True(t, strings.HasPrefix(client.read(), "*3\r\n"), "the log should keep slowlog-max-len entries")
+
+	client.send("SLOWLOG", "RESET")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("CONFIG", "SET", "slowlog-log-slower-than", "-1")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("GET", "key")
+	client.read()
+	client.send("SLOWLOG", "LEN")
+	assert.Equal(t, ":1\r\n", client.read(), "only the CONFIG SET should be logged once the log is disabled")
+
+	client.send("CONFIG", "GET", "slowlog-*")
+	assert.Equal(t, "*4\r\n$23\r\nslowlog-log-slower-than\r\n$2\r\n-1\r\n$15\r\nslowlog-max-len\r\n$1\r\n3\r\n", client.read())
+	client.send("SLOWLOG", "GET", "x")
+	assert.Equal(t, "-value is not an integer or out of range\r\n", client.read())
+	client.send("SLOWLOG", "UNKNOWN")
+	assert.True(t, strings.HasPrefix(client.read(), "-"))
+}
+
+func TestLatency_Commands(t *testing.T) {
+	s := startTestServer(t, WithLatencyMonitorThreshold(time.Nanosecond))
+	client := dialTestClient(t, s.Address())
+
+	client.send("HELLO", "3")
+	client.read()
+	client.send("SET", "key", "value")
+	assert.Equal(t, "+ok\r\n", client.read())
+	client.send("GET", "key")
+	client.read()
+
+	client.send("LATENCY", "LATEST")
+	latest := client.read()
+	assert.Contains(t, latest, "$7\r\ncommand\r\n")
+	assert.Contains(t, latest, "$12\r\nfast-command\r\n")
+
+	client.send("LATENCY", "HISTORY", "command")
+	assert.True(t, strings.HasPrefix(client.read(), "*1\r\n*2\r\n"))
+
+	client.send("LATENCY", "HISTOGRAM", "SET", "unknown")
+	histogram := client.read()
+	assert.True(t, strings.HasPrefix(histogram, "%1\r\n$3\r\nset\r\n%2\r\n$5\r\ncalls\r\n:1\r\n$14\r\nhistogram_usec\r\n%1\r\n"),
+		histogram)
+
+	client.send("LATENCY", "RESET", "command", "unknown")
+	assert.Equal(t, ":1\r\n", client.read())
+	client.send("LATENCY", "HISTORY", "unknown")
+	assert.Equal(t, "*0\r\n", client.read())
+
+	client.send("CONFIG", "SET", "latency-monitor-threshold", "0")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("LATENCY", "RESET")
+	client.read()
+	client.send("GET", "key")
+	client.read()
+	client.send("LATENCY", "LATEST")
+	assert.Equal(t, "*0\r\n", client.read(), "a threshold of 0 should disable the monitor")
+}
+
+func TestRequestArgs(t *testing.T) {
+	request := func(args ...string) *frame.Array {
+		f := frame.NewArray(len(args))
+		for _, arg := range args {
+			_ = f.Append(frame.NewBulkString(arg))
+		}
+		return f
+	}
+	assert.Equal(t, []string{"SET", "k", "v"}, requestArgs(request("SET", "k", "v"), "set"))
+	assert.Equal(t, []string{"CONFIG", "SET", "(redacted)", "(redacted)"},
+		requestArgs(request("CONFIG", "SET", "requirepass", "secret"), "config"))
+	assert.Equal(t, []string{"HELLO", "3", "(redacted)", "(redacted)", "(redacted)"},
+		requestArgs(request("HELLO", "3", "AUTH", "alice", "secret"), "hello"))
+	assert.Equal(t, []string{"ping"}, requestArgs(nil, "ping"))
+}
+
+func TestMonitor(t *testing.T) {
+	s := startTestServer(t)
+	monitor := dialTestClient(t, s.Address())
+	client := dialTestClient(t, s.Address())
+
+	monitor.send("MONITOR")
+	assert.Equal(t, "+OK\r\n", monitor.read())
+
+	client.send("SELECT", "1")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("SET", "key", "say \"hi\"\t")
+	client.read()
+	client.send("AUTH", "secret")
+	client.read()
+
+	addr := client.conn.LocalAddr().String()
+	line := monitor.read()
+	assert.Regexp(t, `^\+\d+\.\d{6} \[0 `+addr+`\] "SELECT" "1"\r\n$`, line)
+	assert.Contains(t, monitor.read(), `[1 `+addr+`] "SET" "key" "say \"hi\"\t"`)
+	assert.Contains(t, monitor.read(), `] "AUTH" "(redacted)"`, "passwords should not be shown")
+
+	monitor.send("CLIENT", "LIST", "ID", fmt.Sprint(monitor.clientID()))
+	assert.Contains(t, monitor.read(), " flags=O ")
+}
+
+func TestMonitor_InsideMulti(t *testing.T) {
+	s := startTestServer(t)
+	client := dialTestClient(t, s.Address())
+
+	client.send("MULTI")
+	client.read()
+	client.send("MONITOR")
+	assert.Equal(t, "-Command not allowed inside a transaction\r\n", client.read())
+	client.send("EXEC")
+	assert.True(t, strings.HasPrefix(client.read(), "-EXECABORT"))
+}
+
+func TestMonitor_OutputBufferLimit(t *testing.T) {
+	s := newTestServer(t, 1)
+	conn, _ := newTestConnection(t, s)
+	assert.Equal(t, ClassNormal, conn.limitClass())
+	conn.Monitor()
+	assert.Equal(t, ClassPubSub, conn.limitClass(), "monitors should be bounded like subscribers")
+	assert.Equal(t, ClassNormal, conn.clientType())
+}
+
+func TestQuoteArg(t *testing.T) {
+	assert.Equal(t, `"hello"`, quoteArg("hello"))
+	assert.Equal(t, `"say \"hi\"\\"`, quoteArg(`say "hi"\`))
+	assert.Equal(t, `"\r\n\t\x00\xff"`, quoteArg("\r\n\t\x00\xff"))
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"github.com/ynachi/gcache/db"
+	"strings"
+)
+
+// Flags of notify-keyspace-events which are not event classes: they select the channels events are published to.
+const (
+	notifyKeyspace = 1 << (iota + 16)
+	notifyKeyevent
+)
+
+// notifyClasses maps the characters of notify-keyspace-events to the event classes they enable.
+// Classes of data types gcache does not have (l, s, h, z, t, d) are accepted for compatibility and ignored.
+var notifyClasses = map[byte]db.EventClass{
+	'g': db.EventGeneric,
+	'$': db.EventString,
+	'x': db.EventExpired,
+	'e': db.EventEvicted,
+	'm': db.EventKeyMiss,
+	'n': db.EventNewKey,
+	'l': 0,
+	's': 0,
+	'h': 0,
+	'z': 0,
+	't': 0,
+	'd': 0,
+}
+
+// notifyAllClasses is what the 'A' alias stands for. Like in Redis, it does not include key misses and new keys.
+const notifyAllClasses = db.EventGeneric | db.EventString | db.EventExpired | db.EventEvicted
+
+// parseNotifyKeyspaceEvents parses the notify-keyspace-events setting, for instance "KEA" or "Ex".
+func parseNotifyKeyspaceEvents(value string) (int, error) {
+	flags := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case 'K':
+			flags |= notifyKeyspace
+		case 'E':
+			flags |= notifyKeyevent
+		case 'A':
+			flags |= int(notifyAllClasses)
+		default:
+			class, ok := notifyClasses[c]
+			if !ok {
+				return 0, fmt.Errorf("invalid event class character '%c'", c)
+			}
+			flags |= int(class)
+		}
+	}
+	return flags, nil
+}
+
+// formatNotifyKeyspaceEvents gives the textual representation of notify-keyspace-events flags.
+func formatNotifyKeyspaceEvents(flags int) string {
+	sb := strings.Builder{}
+	if flags&int(notifyAllClasses) == int(notifyAllClasses) {
+		sb.WriteByte('A')
+	} else {
+		for _, c := range []byte("g$xe") {
+			if flags&int(notifyClasses[c]) != 0 {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	for _, c := range []byte("mn") {
+		if flags&int(notifyClasses[c]) != 0 {
+			sb.WriteByte(c)
+		}
+	}
+	if flags&notifyKeyspace != 0 {
+		sb.WriteByte('K')
+	}
+	if flags&notifyKeyevent != 0 {
+		sb.WriteByte('E')
+	}
+	return sb.String()
+}
+
+// dbNotifier handles the key events of the database at a given index.
+// Keys expired or evicted by the database are invalidated for client side caching, the ones modified by commands
+// being invalidated after the command is applied, when the client which modified them is known.
+// Events are also published to the pub/sub channels __keyspace@<index>__:<key> and __keyevent@<index>__:<event>
+// when enabled by notify-keyspace-events.
+type dbNotifier struct {
+	server *Server
+	index  int
+}
+
+func (n dbNotifier) Notify(class db.EventClass, event string, key string) {
+	if class&(db.EventExpired|db.EventEvicted) != 0 {
+		n.server.tracking.invalidate(key, 0)
+	}
+	flags := int(n.server.notifyFlags.Load())
+	if flags&int(class) == 0 {
+		return
+	}
+	if flags&notifyKeyspace != 0 {
+		n.server.pubsub.publish(fmt.Sprintf("__keyspace@%d__:%s", n.index, key), event)
+	}
+	if flags&notifyKeyevent != 0 {
+		n.server.pubsub.publish(fmt.Sprintf("__keyevent@%d__:%s", n.index, event), key)
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/db"
+	"testing"
+)
+
+func TestParseNotifyKeyspaceEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		give    string
+		want    int
+		wantStr string
+		wantErr bool
+	}{
+		{name: "Empty", give: "", want: 0, wantStr: ""},
+		{name: "AllClasses", give: "KEA", want: notifyKeyspace | notifyKeyevent | int(notifyAllClasses), wantStr: "AKE"},
+		{name: "ExpiredOnly", give: "Ex", want: notifyKeyevent | int(db.EventExpired), wantStr: "xE"},
+		{name: "MissAndNew", give: "Kmn", want: notifyKeyspace | int(db.EventKeyMiss|db.EventNewKey), wantStr: "mnK"},
+		{name: "IgnoredClasses", give: "Klsh", want: notifyKeyspace, wantStr: "K"},
+		{name: "Invalid", give: "KQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseNotifyKeyspaceEvents(tt.give)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStr, formatNotifyKeyspaceEvents(got))
+		})
+	}
+}
+
+func TestKeyspaceNotifications(t *testing.T) {
+	s := startTestServer(t)
+	subscriber := dialTestClient(t, s.Address())
+	client := dialTestClient(t, s.Address())
+
+	subscriber.send("PSUBSCRIBE", "__key*@1__:*")
+	subscriber.read()
+
+	// notifications are disabled by default
+	client.send("SELECT", "1")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("SET", "hello", "world")
+	client.read()
+
+	client.send("CONFIG", "SET", "notify-keyspace-events", "KE$g")
+	assert.Equal(t, "+OK\r\n", client.read())
+	client.send("CONFIG", "GET", "notify-*")
+	assert.Equal(t, "*2\r\n$22\r\nnotify-keyspace-events\r\n$4\r\ng$KE\r\n", client.read())
+
+	client.send("SET", "hello", "again")
+	client.read()
+	client.send("DEL", "hello")
+	client.read()
+
+	assert.Equal(t, message("*", "pmessage", "__key*@1__:*", "__keyspace@1__:hello", "set"), subscriber.read())
+	assert.Equal(t, message("*", "pmessage", "__key*@1__:*", "__keyevent@1__:set", "hello"), subscriber.read())
+	assert.Equal(t, message("*", "pmessage", "__key*@1__:*", "__keyspace@1__:hello", "del"), subscriber.read())
+	assert.Equal(t, message("*", "pmessage", "__key*@1__:*", "__keyevent@1__:del", "hello"), subscriber.read())
+
+	client.send("CONFIG", "SET", "notify-keyspace-events", "KQ")
+	assert.Contains(t, client.read(), "invalid event class")
+	client.send("CONFIG", "SET", "databases", "4")
+	assert.Contains(t, client.read(), "immutable")
+}
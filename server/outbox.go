@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// outbox queues the frames pushed to a connection by other goroutines, like pub/sub messages.
+// They are written by a goroutine dedicated to the connection, so that a publisher never blocks on a slow reader.
+// The outbox is bounded in bytes, so a client not reading fast enough cannot make the server hold an unbounded
+// amount of memory.
+type outbox struct {
+	mu      sync.Mutex
+	pending [][]byte
+	size    int
+	// overSoftSince is the time the size went over the soft limit, zero while it is under.
+	overSoftSince time.Time
+	// notify is signaled when data is pushed. It has a capacity of 1 so pushes never block.
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+}
+
+func newOutbox() *outbox {
+	return &outbox{
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// push queues serialized data. It returns false, without queueing anything, when the data would exceed the hard
+// limit, or the soft limit for longer than allowed.
+func (o *outbox) push(data []byte, limit OutputBufferLimit) bool {
+	o.mu.Lock()
+	size := o.size + len(data)
+	if limit.Hard > 0 && size > limit.Hard {
+		o.mu.Unlock()
+		return false
+	}
+	if limit.Soft > 0 && size > limit.Soft {
+		if o.overSoftSince.IsZero() {
+			o.overSoftSince = time.Now()
+		}
+		if time.Since(o.overSoftSince) >= limit.SoftDuration {
+			o.mu.Unlock()
+			return false
+		}
+	} else {
+		o.overSoftSince = time.Time{}
+	}
+	o.pending = append(o.pending, data)
+	o.size += len(data)
+	o.mu.Unlock()
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// take returns the pending data and empties the outbox.
+func (o *outbox) take() [][]byte {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pending := o.pending
+	o.pending = nil
+	o.size = 0
+	o.overSoftSince = time.Time{}
+	return pending
+}
+
+// pendingSize returns the number of bytes waiting to be written.
+func (o *outbox) pendingSize() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.size
+}
+
+// close stops the writer goroutine. It is safe to call it more than once.
+func (o *outbox) close() {
+	o.once.Do(func() { close(o.done) })
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/glob"
+	"sort"
+	"sync"
+)
+
+// pubSub is the hub routing published messages to subscribed connections.
+// Connections keep their own list of subscriptions, the hub holds the reverse index.
+type pubSub struct {
+	mu       sync.RWMutex
+	channels map[string]map[*Connection]struct{}
+	patterns map[string]map[*Connection]struct{}
+}
+
+func newPubSub() *pubSub {
+	return &pubSub{
+		channels: make(map[string]map[*Connection]struct{}),
+		patterns: make(map[string]map[*Connection]struct{}),
+	}
+}
+
+// subscribe adds a connection to the subscribers of a channel, or of a pattern when isPattern is set.
+func (p *pubSub) subscribe(conn *Connection, name string, isPattern bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	index := p.index(isPattern)
+	subscribers, ok := index[name]
+	if !ok {
+		subscribers = make(map[*Connection]struct{})
+		index[name] = subscribers
+	}
+	subscribers[conn] = struct{}{}
+}
+
+// unsubscribe removes a connection from the subscribers of a channel, or of a pattern when isPattern is set.
+func (p *pubSub) unsubscribe(conn *Connection, name string, isPattern bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	index := p.index(isPattern)
+	delete(index[name], conn)
+	if len(index[name]) == 0 {
+		delete(index, name)
+	}
+}
+
+// index returns the subscribers index of either channels or patterns. The caller must hold the lock.
+func (p *pubSub) index(isPattern bool) map[string]map[*Connection]struct{} {
+	if isPattern {
+		return p.patterns
+	}
+	return p.channels
+}
+
+// isSubscribed tells if a connection is subscribed to a channel.
+func (p *pubSub) isSubscribed(conn *Connection, channel string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	_, ok := p.channels[channel][conn]
+	return ok
+}
+
+// publish delivers a message to the subscribers of a channel and to the ones of the matching patterns.
+// It returns the number of deliveries. Delivery is asynchronous, messages are queued in the subscribers' outboxes.
+func (p *pubSub) publish(channel, message string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	receivers := 0
+	for conn := range p.channels[channel] {
+		conn.push(frame.NewOutOfBand(
+			conn.Protocol(),
+			frame.NewBulkString("message"),
+			frame.NewBulkString(channel),
+			frame.NewBulkString(message),
+		))
+		receivers++
+	}
+	for pattern, subscribers := range p.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		for conn := range subscribers {
+			conn.push(frame.NewOutOfBand(
+				conn.Protocol(),
+				frame.NewBulkString("pmessage"),
+				frame.NewBulkString(pattern),
+				frame.NewBulkString(channel),
+				frame.NewBulkString(message),
+			))
+			receivers++
+		}
+	}
+	return receivers
+}
+
+// activeChannels returns the channels having at least one subscriber and matching a pattern.
+// An empty pattern matches all the channels.
+func (p *pubSub) activeChannels(pattern string) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	channels := make([]string, 0)
+	for channel := range p.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// numSub returns the number of subscribers of a channel, pattern subscribers excluded.
+func (p *pubSub) numSub(channel string) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.channels[channel])
+}
+
+// numPat returns the number of patterns subscribed to by all the clients.
+func (p *pubSub) numPat() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.patterns)
+}
+
+// isAllowedWhenSubscribed tells if a command can be issued by a RESP2 client holding subscriptions.
+// Such clients only receive messages, so replies to other commands could not be told apart from them.
+func isAllowedWhenSubscribed(cmd command.Command) bool {
+	switch cmd.(type) {
+	case *command.Subscribe, *command.PSubscribe, *command.Unsubscribe, *command.PUnsubscribe, *command.Ping:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribed tells if the connection holds at least one subscription.
+func (c *Connection) subscribed() bool {
+	return c.subscriptionCount() > 0
+}
+
+// subscriptionCount returns the number of channels and patterns the connection is subscribed to.
+func (c *Connection) subscriptionCount() int {
+	return len(c.channels) + len(c.patterns)
+}
+
+// Subscribe subscribes the connection to channels.
+// It returns the number of subscriptions held by the connection after each of them.
+func (c *Connection) Subscribe(channels ...string) []int {
+	return c.subscribe(channels, c.channels, false)
+}
+
+// PSubscribe subscribes the connection to channel patterns.
+// It returns the number of subscriptions held by the connection after each of them.
+func (c *Connection) PSubscribe(patterns ...string) []int {
+	return c.subscribe(patterns, c.patterns, true)
+}
+
+// Unsubscribe unsubscribes the connection from channels, or from all of them if none is given.
+// It returns the channels along with the number of subscriptions left after each of them.
+func (c *Connection) Unsubscribe(channels ...string) ([]string, []int) {
+	return c.unsubscribe(channels, c.channels, false)
+}
+
+// PUnsubscribe unsubscribes the connection from patterns, or from all of them if none is given.
+// It returns the patterns along with the number of subscriptions left after each of them.
+func (c *Connection) PUnsubscribe(patterns ...string) ([]string, []int) {
+	return c.unsubscribe(patterns, c.patterns, true)
+}
+
+func (c *Connection) subscribe(names []string, subscriptions map[string]struct{}, isPattern bool) []int {
+	counts := make([]int, 0, len(names))
+	for _, name := range names {
+		if _, ok := subscriptions[name]; !ok {
+			subscriptions[name] = struct{}{}
+			c.server.pubsub.subscribe(c, name, isPattern)
+		}
+		counts = append(counts, c.subscriptionCount())
+	}
+	c.pubsubClient.Store(c.subscribed())
+	return counts
+}
+
+func (c *Connection) unsubscribe(names []string, subscriptions map[string]struct{}, isPattern bool) ([]string, []int) {
+	if len(names) == 0 {
+		for name := range subscriptions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	counts := make([]int, 0, len(names))
+	for _, name := range names {
+		if _, ok := subscriptions[name]; ok {
+			delete(subscriptions, name)
+			c.server.pubsub.unsubscribe(c, name, isPattern)
+		}
+		counts = append(counts, c.subscriptionCount())
+	}
+	c.pubsubClient.Store(c.subscribed())
+	return names, counts
+}
+
+// Publish sends a message to a channel and returns the number of clients it was delivered to.
+func (c *Connection) Publish(channel, message string) int {
+	return c.server.pubsub.publish(channel, message)
+}
+
+// ActiveChannels returns the channels with subscribers matching a pattern, all of them if the pattern is empty.
+func (c *Connection) ActiveChannels(pattern string) []string {
+	return c.server.pubsub.activeChannels(pattern)
+}
+
+// NumSub returns the number of subscribers of a channel.
+func (c *Connection) NumSub(channel string) int {
+	return c.server.pubsub.numSub(channel)
+}
+
+// NumPat returns the number of patterns subscribed to by all the clients.
+func (c *Connection) NumPat() int {
+	return c.server.pubsub.numPat()
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/frame"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is a raw RESP client used to drive a running server.
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialTestClient(t *testing.T, address string) *testClient {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		t.Fatalf("unable to connect to %s: %v", address, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send writes a command made of bulk strings.
+func (c *testClient) send(args ...string) {
+	f := frame.NewArray(len(args))
+	for _, arg := range args {
+		_ = f.Append(frame.NewBulkString(arg))
+	}
+	if _, err := f.WriteTo(c.conn); err != nil {
+		c.t.Fatalf("unable to send %v: %v", args, err)
+	}
+}
+
+// read returns the text representation of the next frame received.
+func (c *testClient) read() string {
+	_ = c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	f, err := frame.Decode(c.reader)
+	if err != nil {
+		c.t.Fatalf("unable to read frame: %v", err)
+	}
+	return f.String()
+}
+
+// startTestServer starts a server listening on a random local port.
+func startTestServer(t *testing.T, opts ...Option) *Server {
+	s, err := NewServer("127.0.0.1", 0, "ERROR", 100, "LRU", opts...)
+	if err != nil {
+		t.Fatalf("unable to create server: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go s.Start(ctx)
+	return s
+}
+
+// message returns the representation of a message pushed to a subscriber.
+func message(prefix string, parts ...string) string {
+	sb := strings.Builder{}
+	sb.WriteString(fmt.Sprintf("%s%d\r\n", prefix, len(parts)))
+	for _, part := range parts {
+		sb.WriteString(frame.NewBulkString(part).String())
+	}
+	return sb.String()
+}
+
+func TestPubSub_Publish(t *testing.T) {
+	s := startTestServer(t)
+	subscriber := dialTestClient(t, s.Address())
+	patternSubscriber := dialTestClient(t, s.Address())
+	publisher := dialTestClient(t, s.Address())
+
+	subscriber.send("SUBSCRIBE", "news", "sport")
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n", subscriber.read())
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$5\r\nsport\r\n:2\r\n", subscriber.read())
+
+	patternSubscriber.send("HELLO", "3")
+	assert.True(t, strings.HasPrefix(patternSubscriber.read(), "%4\r\n"))
+	patternSubscriber.send("PSUBSCRIBE", "n*")
+	assert.Equal(t, ">3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:1\r\n", patternSubscriber.read())
+
+	publisher.send("PUBLISH", "news", "hello")
+	assert.Equal(t, ":2\r\n", publisher.read())
+	assert.Equal(t, message("*", "message", "news", "hello"), subscriber.read())
+	assert.Equal(t, message(">", "pmessage", "n*", "news", "hello"), patternSubscriber.read())
+
+	publisher.send("PUBSUB", "NUMSUB", "news", "other")
+	assert.Equal(t, "*4\r\n$4\r\nnews\r\n:1\r\n$5\r\nother\r\n:0\r\n", publisher.read())
+	publisher.send("PUBSUB", "CHANNELS")
+	assert.Equal(t, "*2\r\n$4\r\nnews\r\n$5\r\nsport\r\n", publisher.read())
+
+	// RESP2 subscribers can only manage their subscriptions
+	subscriber.send("GET", "hello")
+	assert.True(t, strings.HasPrefix(subscriber.read(), "-Can't execute 'get'"))
+	subscriber.send("UNSUBSCRIBE")
+	assert.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$4\r\nnews\r\n:1\r\n", subscriber.read())
+	assert.Equal(t, "*3\r\n$11\r\nunsubscribe\r\n$5\r\nsport\r\n:0\r\n", subscriber.read())
+	subscriber.send("GET", "hello")
+	assert.Equal(t, "$-1\r\n", subscriber.read())
+}
+
+func TestConnection_PushOverLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []Option
+		wantClosed bool
+	}{
+		{name: "Disconnect", opts: []Option{WithPubSubBufferLimit(64)}, wantClosed: true},
+		{name: "Drop", opts: []Option{WithPubSubBufferLimit(64), WithDropSlowSubscribers()}, wantClosed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, 1)
+			for _, opt := range tt.opts {
+				opt(&s.config)
+			}
+			s.setOutputBufferLimits(s.config.OutputBufferLimits)
+			client, server := net.Pipe()
+			defer func() { _ = client.Close() }()
+			conn := MakeConnection(server, s)
+
+			// nobody drains the outbox, so the limit is quickly reached
+			conn.Subscribe("news")
+			for i := 0; i < 10; i++ {
+				s.pubsub.publish("news", "hello")
+			}
+
+			assert.LessOrEqual(t, conn.outbox.size, 64)
+			// nobody reads the pipe either, so writing to an open one times out
+			_ = server.SetWriteDeadline(time.Now().Add(10 * time.Millisecond))
+			_, err := server.Write([]byte("ping"))
+			assert.Equal(t, tt.wantClosed, errors.Is(err, io.ErrClosedPipe))
+		})
+	}
+}
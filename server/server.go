@@ -1,28 +1,86 @@
 package server
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"github.com/ynachi/gcache/acl"
 	"github.com/ynachi/gcache/command"
 	"github.com/ynachi/gcache/db"
 	"github.com/ynachi/gcache/frame"
 	"github.com/ynachi/gcache/gerror"
+	"github.com/ynachi/gcache/monitor"
 	"io"
 	"log/slog"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Server struct {
-	address  string
-	listener net.Listener
-	logger   *slog.Logger
-	cache    *db.Cache
+	address string
+	// listeners accept the connections over TCP and the unix socket, depending on the configuration.
+	listeners []net.Listener
+	logger    *slog.Logger
+	config    Config
+
+	// dbMu protects the databases slice against SWAPDB. The databases themselves have their own locks.
+	dbMu sync.RWMutex
+	dbs  []*db.Cache
+
+	pubsub   *pubSub
+	tracking *tracker
+	acl      *acl.ACL
+	// tls holds the certificates of the listener, nil if TLS is not enabled.
+	tls *tlsCredentials
+	// requirePass is the password of the default user set by configuration, for CONFIG GET.
+	requirePass atomic.Value
+	// tunables holds the settings of the sampled eviction policies, shared by all the databases.
+	tunables *db.Tunables
+
+	// clients registers the connections being served by their ID.
+	clientsMu    sync.RWMutex
+	clients      map[int64]*Connection
+	nextClientID atomic.Int64
+	// notifyFlags holds the parsed notify-keyspace-events setting, which can be changed at runtime.
+	notifyFlags atomic.Int32
+	// maxClients, idleTimeout and outputLimits hold the limits of the clients, which can be changed at runtime.
+	maxClients   atomic.Int64
+	idleTimeout  atomic.Int64
+	outputLimits atomic.Pointer[map[string]OutputBufferLimit]
+
+	// execMu is held in read mode while a command is applied, and in write mode by EXEC
+	// so that transactions run without interleaving with other clients.
+	execMu sync.RWMutex
+
+	// stats counts the connections and their failures, for monitoring.
+	stats serverStats
+
+	// pause suspends the commands of the clients on CLIENT PAUSE.
+	pause *clientPause
+
+	// monitors receive the commands processed by the other clients.
+	monitors *monitors
+
+	// slowLog and latency record the commands and the events taking too long.
+	slowLog *monitor.SlowLog
+	latency *monitor.Latency
+
+	// activeExpireOff suspends the background expiration of the keys, on DEBUG SET-ACTIVE-EXPIRE 0.
+	activeExpireOff atomic.Bool
+
+	// handler processes the commands through the middlewares of the configuration.
+	handler Handler
 }
 
+// Active expiration samples this number of keys with a time to live in each database, at each interval.
+const (
+	activeExpireSamples  = 20
+	activeExpireInterval = 100 * time.Millisecond
+)
+
 const (
 	LevelDebug = "DEBUG"
 	LevelWarn  = "WARN"
@@ -45,25 +103,74 @@ func getLogLevel(level string) slog.Level {
 
 // NewServer creates a new Server with the provided IP address and port.
 // It starts listening for incoming connections on the specified address and port.
+// Each logical database holds up to maxItems keys and uses its own instance of the eviction policy.
 // Returns a pointer to the created Server or an error if the listener fails to start.
-func NewServer(ip string, port int, logLevel string, maxItems int64, evictionPolicyName string) (*Server, error) {
-	connString := fmt.Sprintf("%s:%d", ip, port)
-	listener, err := net.Listen("tcp", connString)
+func NewServer(ip string, port int, logLevel string, maxItems int64, evictionPolicyName string, opts ...Option) (*Server, error) {
+	config := defaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	users, err := newACL(config)
 	if err != nil {
 		return nil, err
 	}
 
-	cache, err := db.NewCache(maxItems, evictionPolicyName)
+	tunables := db.NewTunables()
+	dbs := make([]*db.Cache, 0, config.Databases)
+	for i := 0; i < config.Databases; i++ {
+		cache, err := db.NewCache(maxItems, evictionPolicyName)
+		if err != nil {
+			return nil, err
+		}
+		cache.SetTunables(tunables)
+		dbs = append(dbs, cache)
+	}
+
+	var creds *tlsCredentials
+	if config.TLS != nil {
+		if creds, err = newTLSCredentials(*config.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	listeners, err := openListeners(fmt.Sprintf("%s:%d", ip, port), config, creds)
 	if err != nil {
 		return nil, err
 	}
 
 	server := &Server{
-		address:  listener.Addr().String(),
-		listener: listener,
-		cache:    cache,
+		address:   listeners[0].Addr().String(),
+		listeners: listeners,
+		config:    config,
+		dbs:       dbs,
+		pubsub:    newPubSub(),
+		clients:   make(map[int64]*Connection),
+		tunables:  tunables,
+		acl:       users,
+		tls:       creds,
+		pause:     newClientPause(),
+		monitors:  newMonitors(),
+		slowLog:   monitor.NewSlowLog(config.SlowLogSlowerThan, config.SlowLogMaxLen),
+		latency:   monitor.NewLatency(config.LatencyMonitorThreshold),
 	}
+	server.tracking = newTracker(server, config.TrackingTableMaxKeys)
+	server.requirePass.Store(config.RequirePass)
+	middlewares := append([]Middleware{server.authorize, server.protectCommands}, config.Middlewares...)
+	server.handler = chain(append(middlewares, server.feedMonitors, server.recordLatency), server.execute)
 	server.setLogger(logLevel)
+	// already validated with the config
+	notifyFlags, _ := parseNotifyKeyspaceEvents(config.NotifyKeyspaceEvents)
+	server.notifyFlags.Store(int32(notifyFlags))
+	server.maxClients.Store(int64(config.MaxClients))
+	server.idleTimeout.Store(int64(config.IdleTimeout))
+	server.setOutputBufferLimits(config.OutputBufferLimits)
+	for i, cache := range dbs {
+		cache.SetNotifier(dbNotifier{server: server, index: i})
+	}
 	return server, nil
 }
 
@@ -78,25 +185,77 @@ func (s *Server) Address() string {
 	return s.address
 }
 
+// database returns the logical database at the given index.
+func (s *Server) database(index int) (*db.Cache, error) {
+	if index < 0 || index >= len(s.dbs) {
+		return nil, gerror.ErrDBIndexOutRange
+	}
+	s.dbMu.RLock()
+	defer s.dbMu.RUnlock()
+	return s.dbs[index], nil
+}
+
+// registerClient makes a connection reachable by its ID. It returns false, without registering it, when the
+// maximum number of clients is reached.
+func (s *Server) registerClient(conn *Connection) bool {
+	s.clientsMu.Lock()
+	defer s.clientsMu.Unlock()
+	if int64(len(s.clients)) >= s.maxClients.Load() {
+		return false
+	}
+	s.clients[conn.id] = conn
+	return true
+}
+
+// unregisterClient forgets a connection and the client side caching state of its client.
+func (s *Server) unregisterClient(conn *Connection) {
+	s.clientsMu.Lock()
+	delete(s.clients, conn.id)
+	s.clientsMu.Unlock()
+	s.tracking.disable(conn.id)
+	s.monitors.remove(conn)
+}
+
+// client returns the connection of the client with the given ID.
+func (s *Server) client(id int64) (*Connection, bool) {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+	conn, ok := s.clients[id]
+	return conn, ok
+}
+
+// swapDB swaps two logical databases.
+func (s *Server) swapDB(i, j int) error {
+	if i < 0 || i >= len(s.dbs) || j < 0 || j >= len(s.dbs) {
+		return gerror.ErrDBIndexOutRange
+	}
+	s.dbMu.Lock()
+	defer s.dbMu.Unlock()
+	s.dbs[i], s.dbs[j] = s.dbs[j], s.dbs[i]
+	// events are published for the index a database is reachable at
+	s.dbs[i].SetNotifier(dbNotifier{server: s, index: i})
+	s.dbs[j].SetNotifier(dbNotifier{server: s, index: j})
+	return nil
+}
+
 // Start starts the server. It listens to new connections and processes them.
 func (s *Server) Start(ctx context.Context) {
-	defer func() {
-		if err := s.listener.Close(); err != nil {
-			s.logger.Error("error closing listener", "error", err)
-		}
-	}()
+	defer s.closeListeners()
 
-	newConns := make(chan Connection)
-	go s.listen(ctx, newConns)
+	newConns := make(chan *Connection)
+	for _, listener := range s.listeners {
+		go s.listen(ctx, listener, newConns)
+	}
+	go s.expireKeys(ctx)
+	if s.tls != nil {
+		go s.reloadTLSOnSignal(ctx)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			// Close all connections
 			s.logger.Debug("gracefully shutdown server")
-			if err := s.listener.Close(); err != nil {
-				s.logger.Error("error closing listener", "error", err)
-			}
 			return
 		case conn, ok := <-newConns:
 			// NoK means newConns channel is closed.
@@ -110,36 +269,56 @@ func (s *Server) Start(ctx context.Context) {
 	}
 }
 
-// listen waits for new connections for the lifetime of the server.
-func (s *Server) listen(ctx context.Context, newConns chan<- Connection) {
+// expireKeys periodically removes the expired keys nobody accesses anymore, for the lifetime of the server.
+// Like Redis, a database is sampled again right away as long as a significant part of the sample was expired.
+func (s *Server) expireKeys(ctx context.Context) {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		default:
-			c, err := s.listener.Accept()
-			if err != nil {
-				s.logger.Error("error accepting connection", "error", err)
-				// TODO: implement exponential backoff later
-				time.Sleep(5 * time.Second)
+		case <-ticker.C:
+			// expiring keys modifies the data set, which clients paused for writes expect to be frozen
+			if _, _, paused := s.pause.active(true); paused || s.activeExpireOff.Load() {
 				continue
 			}
-			newConns <- MakeConnection(c, s.cache)
+			start := time.Now()
+			for i := 0; i < len(s.dbs); i++ {
+				cache, _ := s.database(i)
+				expired := activeExpireSamples
+				for expired > activeExpireSamples/4 {
+					expired = cache.ActiveExpire(activeExpireSamples)
+				}
+			}
+			s.latency.AddSample(monitor.EventExpireCycle, time.Since(start))
 		}
 	}
 }
 
 // attemptCloseConnection tries to close a connection and log an error if it cannot.
-func (s *Server) attemptCloseConnection(conn Connection) {
-	if err := conn.Close(); err != nil {
+func (s *Server) attemptCloseConnection(conn *Connection) {
+	// connections killed by another client are already closed
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 		s.logger.Error("error closing connection", "error", err)
 	}
 }
 
 // handleConnection is the starting point of each connection established with the server.
 // It reads command from the connection, apply them and send the response back to the client.
-func (s *Server) handleConnection(ctx context.Context, conn Connection) {
+func (s *Server) handleConnection(ctx context.Context, conn *Connection) {
 	defer s.attemptCloseConnection(conn)
+	if err := conn.handshake(ctx); err != nil {
+		s.logger.Error("TLS handshake failed", "client_ip", conn.clientIP, "error", err)
+		return
+	}
+	if !s.registerClient(conn) {
+		s.stats.connectionsRejected.Add(1)
+		s.logger.Warn("max number of clients reached", "client_ip", conn.clientIP)
+		s.SendError(gerror.ErrMaxClients.Error(), conn.replies)
+		return
+	}
+	go conn.writePushes()
 	for {
 		select {
 		case <-ctx.Done():
@@ -147,48 +326,118 @@ func (s *Server) handleConnection(ctx context.Context, conn Connection) {
 			return
 		default:
 			// Get command first
+			conn.setIdleDeadline()
 			cmd, err := conn.GetCommand()
-			if err == nil {
-				// process unknown command
-				if _, ok := cmd.(*command.Unknown); ok {
-					s.SendError(gerror.ErrInvalidCmdName.Error(), conn.writer)
-					continue
-				}
-
-				// Apply command
-				s.logger.Debug("command received", "client_ip", conn.clientIP, "cmd", cmd.Name())
-				cmd.Apply(conn.storage, conn.writer)
-			}
-
-			// Exit on IOF. Log network unavailability ones to the client. Send the rest to the client.
-			if s.handleConnectionError(conn, err) {
+			if s.process(conn, cmd, err) || conn.closeAfterReply {
 				return
 			}
 		}
 	}
 }
 
-// SendError responds to a client with an error.
-// The error message should be compatible with RESP Error type (i.e., Simple String).
-func (s *Server) SendError(msg string, w *bufio.Writer) {
-	defer func(conn *bufio.Writer) {
-		if err := conn.Flush(); err != nil {
-			s.logger.Error("failed to flush buffer to writer", "error", err)
+// process applies a command read from a connection, or handles the error which occurred while reading it.
+// It tells if the caller should stop serving the connection.
+// Replies are written under the connection write lock, so they do not interleave with pushed frames.
+func (s *Server) process(conn *Connection, cmd command.Command, err error) (shouldExit bool) {
+	if err == nil {
+		s.waitForPause(&Call{Conn: conn, Command: cmd, Spec: command.SpecOf(cmd)})
+	}
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	if err == nil {
+		// process unknown command
+		if _, ok := cmd.(*command.Unknown); ok {
+			conn.abortTransaction()
+			s.SendError(gerror.ErrInvalidCmdName.Error(), conn.replies)
+			return false
+		}
+
+		// Apply command
+		s.logger.Debug("command received", "client_ip", conn.clientIP, "cmd", cmd.Name())
+		conn.beforeCommand(cmd)
+		s.dispatch(conn, cmd)
+		conn.afterCommand()
+	}
+
+	// Exit on IOF. Log network unavailability ones to the client. Send the rest to the client.
+	return s.handleConnectionError(conn, err)
+}
+
+// dispatch processes a command received on a connection through the middlewares, then flushes the replies.
+// A command they reject fails the transaction it was meant to be queued in.
+func (s *Server) dispatch(conn *Connection, cmd command.Command) {
+	handler := s.handler
+	if handler == nil {
+		handler = s.execute
+	}
+	call := &Call{Conn: conn, Command: cmd, Spec: command.SpecOf(cmd), Args: callArgs(conn.request)}
+	if err := handler(call); err != nil {
+		conn.abortTransaction()
+		conn.replies.WriteError(err)
+	}
+	if err := conn.replies.Flush(); errors.Is(err, gerror.ErrOutputBufferLimit) {
+		s.logger.Warn("output buffer limit reached, closing connection", "client_ip", conn.clientIP)
+		if err := conn.conn.Close(); err != nil {
+			s.logger.Error("error closing connection", "error", err)
+		}
+	} else if err != nil {
+		s.logger.Error("failed to flush buffer to writer", "error", err)
+	}
+}
+
+// execute applies a command, at the end of the middleware chain.
+// While a transaction is open, commands are queued instead, unless they control the transaction. The ones which
+// cannot be queued, like MONITOR, are rejected and fail the transaction.
+func (s *Server) execute(call *Call) error {
+	conn, cmd := call.Conn, call.Command
+	if conn.subscribed() && conn.Protocol() == frame.RESP2 && !isAllowedWhenSubscribed(cmd) {
+		return fmt.Errorf("Can't execute '%s': %w", cmd.Name(), gerror.ErrSubscriberMode)
+	}
+	if conn.tx.active && !isTransactionControl(cmd) {
+		if call.Spec != nil && call.Spec.HasFlag(command.FlagNoMulti) {
+			return gerror.ErrNotAllowedInMulti
 		}
-	}(w)
+		conn.queue(call)
+		queued, _ := frame.NewSimpleString("QUEUED")
+		conn.replies.WriteFrame(queued)
+		return nil
+	}
+	// EXEC takes the lock in write mode by itself
+	if _, ok := cmd.(*command.Exec); ok {
+		s.apply(conn, cmd, conn.replies)
+		return nil
+	}
+	s.execMu.RLock()
+	defer s.execMu.RUnlock()
+	s.apply(conn, cmd, conn.replies)
+	return nil
+}
+
+// apply binds a command to the connection it was issued from if needed, then applies it on the selected database.
+func (s *Server) apply(conn *Connection, cmd command.Command, dest command.ReplyWriter) {
+	if sc, ok := cmd.(command.SessionAware); ok {
+		sc.BindSession(conn)
+	}
+	s.applyTracked(conn, cmd, dest)
+}
+
+// SendError responds to a client with an error and flushes it.
+// The error message should be compatible with RESP Error type (i.e., Simple String).
+func (s *Server) SendError(msg string, w *command.RespWriter) {
 	errFrame, err := frame.NewError(msg)
 	if err != nil {
 		s.logger.Error("error creating error frame", "error", err)
+		return
 	}
-	_, err = errFrame.WriteTo(w)
-	if err != nil {
-		s.logger.Error("error writing error frame to network", "error", err)
+	w.WriteFrame(errFrame)
+	if err = w.Flush(); err != nil {
+		s.logger.Error("failed to flush buffer to writer", "error", err)
 	}
 }
 
 // handleConnectionError handles connection errors and tells if the caller should return.
 // In all other cases, the caller should continue the execution as those are temporary.
-func (s *Server) handleConnectionError(conn Connection, err error) (shouldExit bool) {
+func (s *Server) handleConnectionError(conn *Connection, err error) (shouldExit bool) {
 	if err == nil {
 		return false
 	}
@@ -196,13 +445,19 @@ func (s *Server) handleConnectionError(conn Connection, err error) (shouldExit b
 		s.logger.Debug("client initiated shutdown", "client_ip", conn.clientIP)
 		return true
 	}
+	// the connection was closed by the server, by CLIENT KILL for instance
+	if errors.Is(err, net.ErrClosed) {
+		s.logger.Debug("connection closed", "client_ip", conn.clientIP)
+		return true
+	}
 
 	// Also check for other network errors
 	var nErr net.Error
 	ok := errors.As(err, &nErr)
+	// reads only time out when the client stays idle for too long
 	if ok && nErr.Timeout() {
-		s.logger.Error("network timeout", "client_ip", conn.clientIP, "err", err)
-		return false
+		s.logger.Debug("idle client timed out", "client_ip", conn.clientIP)
+		return true
 	}
 	var opErr *net.OpError
 	if errors.As(err, &opErr) && opErr.Err.Error() == "read: connection reset by peer" {
@@ -211,6 +466,8 @@ func (s *Server) handleConnectionError(conn Connection, err error) (shouldExit b
 	}
 
 	s.logger.Error("error while handling command", "client_ip", conn.clientIP, "err", err)
-	s.SendError(err.Error(), conn.writer)
+	// A command which cannot be parsed would fail the transaction it was meant to be queued in
+	conn.abortTransaction()
+	s.SendError(err.Error(), conn.replies)
 	return false
 }
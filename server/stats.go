@@ -0,0 +1,29 @@
+package server
+
+import "sync/atomic"
+
+// Stats are counters about the activity of the server since it started.
+type Stats struct {
+	// ConnectionsReceived is the number of connections accepted.
+	ConnectionsReceived int64
+	// ConnectionsRejected is the number of connections refused because the maximum number of clients was reached.
+	ConnectionsRejected int64
+	// AcceptErrors is the number of failures to accept a connection.
+	AcceptErrors int64
+}
+
+// serverStats holds the counters of the server, updated concurrently.
+type serverStats struct {
+	connectionsReceived atomic.Int64
+	connectionsRejected atomic.Int64
+	acceptErrors        atomic.Int64
+}
+
+// Stats returns the counters about the activity of the server.
+func (s *Server) Stats() Stats {
+	return Stats{
+		ConnectionsReceived: s.stats.connectionsReceived.Load(),
+		ConnectionsRejected: s.stats.connectionsRejected.Load(),
+		AcceptErrors:        s.stats.acceptErrors.Load(),
+	}
+}
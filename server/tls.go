@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds the time a client has to complete the TLS handshake once connected.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// TLSConfig configures the TLS listener of a server. Files are read upon creation and again on reload.
+type TLSConfig struct {
+	// CertFile and KeyFile are the paths of the PEM encoded certificate of the server and its private key.
+	CertFile string
+	KeyFile  string
+
+	// CAFile is the path of the PEM encoded bundle of CAs verifying client certificates.
+	CAFile string
+
+	// ClientAuth is the policy for client certificates, tls.RequireAndVerifyClientCert for mutual TLS.
+	ClientAuth tls.ClientAuthType
+
+	// CNAsUser authenticates clients as the ACL user named after the common name of their certificate, if it
+	// exists and is enabled. Other clients are authenticated as usual.
+	CNAsUser bool
+}
+
+// validate checks the consistency of a TLS configuration.
+func (c *TLSConfig) validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return ErrTLSCertRequired
+	}
+	verifies := c.ClientAuth == tls.VerifyClientCertIfGiven || c.ClientAuth == tls.RequireAndVerifyClientCert
+	if verifies && c.CAFile == "" {
+		return ErrTLSCARequired
+	}
+	if c.CNAsUser && !verifies {
+		return ErrTLSUnverifiedUser
+	}
+	return nil
+}
+
+// load reads the certificates of a TLS configuration.
+func (c *TLSConfig) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load the certificate of the server: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   c.ClientAuth,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		bundle, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %s", c.CAFile)
+		}
+		config.ClientCAs = pool
+	}
+	return config, nil
+}
+
+// tlsCredentials holds the certificates in use by the listener, replaced as a whole on reload so that
+// handshakes in progress are not affected.
+type tlsCredentials struct {
+	config  TLSConfig
+	current atomic.Pointer[tls.Config]
+}
+
+func newTLSCredentials(config TLSConfig) (*tlsCredentials, error) {
+	creds := &tlsCredentials{config: config}
+	if err := creds.reload(); err != nil {
+		return nil, err
+	}
+	return creds, nil
+}
+
+// reload reads the certificates again. The ones in use are kept if they cannot be read.
+func (t *tlsCredentials) reload() error {
+	config, err := t.config.load()
+	if err != nil {
+		return err
+	}
+	t.current.Store(config)
+	return nil
+}
+
+// listenerConfig returns the configuration of the listener, which picks the current certificates for each client.
+func (t *tlsCredentials) listenerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return t.current.Load(), nil
+		},
+	}
+}
+
+// ReloadTLS reads the certificates of the server again. Clients already connected keep their session.
+func (s *Server) ReloadTLS() error {
+	if s.tls == nil {
+		return ErrTLSNotEnabled
+	}
+	return s.tls.reload()
+}
+
+// reloadTLSOnSignal reloads the certificates each time the process receives SIGHUP, until ctx is done.
+func (s *Server) reloadTLSOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			if err := s.ReloadTLS(); err != nil {
+				s.logger.Error("unable to reload TLS certificates", "error", err)
+				continue
+			}
+			s.logger.Info("TLS certificates reloaded")
+		}
+	}
+}
+
+// handshake completes the TLS handshake of a client, then authenticates it as the user named after the common
+// name of its certificate if the server is configured so. Plain connections are left untouched.
+func (c *Connection) handshake(ctx context.Context) error {
+	tlsConn, ok := c.conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, tlsHandshakeTimeout)
+	defer cancel()
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return err
+	}
+	if !c.server.config.TLS.CNAsUser {
+		return nil
+	}
+	certs := tlsConn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil
+	}
+	if user, ok := c.server.acl.User(certs[0].Subject.CommonName); ok && user.Enabled() {
+		c.user.Store(user)
+	}
+	return nil
+}
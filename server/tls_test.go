@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA signs the certificates of the TLS tests.
+type testCA struct {
+	t    *testing.T
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gcache test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unable to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{t: t, cert: cert, key: key, pool: pool}
+}
+
+// issue returns a certificate signed by the CA for the given common name, valid for the local address.
+func (ca *testCA) issue(commonName string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatalf("unable to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatalf("unable to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeCA writes the certificate of the CA to a file and returns its path.
+func (ca *testCA) writeCA(dir string) string {
+	path := filepath.Join(dir, "ca.pem")
+	writePEM(ca.t, path, "CERTIFICATE", ca.cert.Raw)
+	return path
+}
+
+// writeCertificate writes a certificate and its key to files and returns their paths.
+func (ca *testCA) writeCertificate(dir string, cert tls.Certificate) (certFile, keyFile string) {
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writePEM(ca.t, certFile, "CERTIFICATE", cert.Certificate[0])
+	der, err := x509.MarshalECPrivateKey(cert.PrivateKey.(*ecdsa.PrivateKey))
+	if err != nil {
+		ca.t.Fatalf("unable to marshal key: %v", err)
+	}
+	writePEM(ca.t, keyFile, "EC PRIVATE KEY", der)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("unable to write %s: %v", path, err)
+	}
+}
+
+// dialTLSClient connects to a TLS server, presenting a client certificate if one is given.
+func dialTLSClient(t *testing.T, address string, ca *testCA, certs ...tls.Certificate) *testClient {
+	conn, err := tls.Dial("tcp", address, &tls.Config{RootCAs: ca.pool, Certificates: certs})
+	if err != nil {
+		t.Fatalf("unable to connect to %s: %v", address, err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config TLSConfig
+		want   error
+	}{
+		{name: "NoCert", config: TLSConfig{KeyFile: "key.pem"}, want: ErrTLSCertRequired},
+		{name: "NoCA", config: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientAuth: tls.RequireAndVerifyClientCert}, want: ErrTLSCARequired},
+		{name: "UnverifiedUser", config: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", ClientAuth: tls.RequireAnyClientCert, CNAsUser: true}, want: ErrTLSUnverifiedUser},
+		{name: "Valid", config: TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", CAFile: "ca.pem", ClientAuth: tls.RequireAndVerifyClientCert, CNAsUser: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.validate())
+		})
+	}
+}
+
+func TestTLS_MutualAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeCertificate(dir, ca.issue("gcache", x509.ExtKeyUsageServerAuth))
+	aclFile := filepath.Join(dir, "users.acl")
+	if err := os.WriteFile(aclFile, []byte("user alice on ~cache:* +get +acl\n"), 0600); err != nil {
+		t.Fatalf("unable to write ACL file: %v", err)
+	}
+	s := startTestServer(t, WithRequirePass("secret"), WithACLFile(aclFile), WithTLS(TLSConfig{
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+		CAFile:     ca.writeCA(dir),
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		CNAsUser:   true,
+	}))
+
+	// the client is authenticated as the user named after its certificate
+	alice := dialTLSClient(t, s.Address(), ca, ca.issue("alice", x509.ExtKeyUsageClientAuth))
+	alice.send("ACL", "WHOAMI")
+	assert.Equal(t, "$5\r\nalice\r\n", alice.read())
+	alice.send("GET", "other")
+	assert.Equal(t, "-NOPERM No permissions to access a key\r\n", alice.read())
+
+	// certificates of unknown users are accepted, but the client has to authenticate
+	bob := dialTLSClient(t, s.Address(), ca, ca.issue("bob", x509.ExtKeyUsageClientAuth))
+	bob.send("GET", "cache:1")
+	assert.Equal(t, "-NOAUTH Authentication required.\r\n", bob.read())
+
+	// clients without a certificate signed by the CA are rejected
+	other := newTestCA(t)
+	mallory := dialTLSClient(t, s.Address(), ca, other.issue("alice", x509.ExtKeyUsageClientAuth))
+	mallory.send("ACL", "WHOAMI")
+	_ = mallory.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := mallory.reader.ReadByte()
+	assert.Error(t, err)
+}
+
+func TestTLS_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.writeCertificate(dir, ca.issue("first", x509.ExtKeyUsageServerAuth))
+	s := startTestServer(t, WithTLS(TLSConfig{CertFile: certFile, KeyFile: keyFile}))
+
+	servedCert := func() string {
+		client := dialTLSClient(t, s.Address(), ca)
+		client.send("PING")
+		assert.Equal(t, "+PONG\r\n", client.read())
+		return client.conn.(*tls.Conn).ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	assert.Equal(t, "first", servedCert())
+
+	ca.writeCertificate(dir, ca.issue("second", x509.ExtKeyUsageServerAuth))
+	assert.NoError(t, s.ReloadTLS())
+	assert.Equal(t, "second", servedCert())
+
+	// invalid files do not replace the certificates in use
+	assert.NoError(t, os.WriteFile(certFile, []byte("invalid"), 0600))
+	assert.Error(t, s.ReloadTLS())
+	assert.Equal(t, "second", servedCert())
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"hash/maphash"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// invalidationChannel is the pub/sub channel RESP2 clients subscribe to when they receive invalidation messages
+// on behalf of a tracking client, through REDIRECT.
+const invalidationChannel = "__redis__:invalidate"
+
+// tracker remembers which clients cache which keys, to send them invalidation messages when the keys change.
+// Like in Redis, keys are tracked by name regardless of the database they belong to, and clients by ID.
+type tracker struct {
+	mu      sync.Mutex
+	server  *Server
+	clients map[int64]command.TrackingOptions
+	// keys indexes the clients in default mode by the keys they read. A key is forgotten once invalidated.
+	keys map[string]map[int64]struct{}
+	// readKeys indexes the keys of the table by the clients which read them, to forget the keys of a client
+	// when it stops tracking.
+	readKeys map[int64]map[string]struct{}
+	// maxKeys bounds the number of keys of the table, 0 meaning no limit.
+	maxKeys atomic.Int64
+	// prefixes indexes the clients in BCAST mode by the prefixes they registered.
+	prefixes map[string]map[int64]struct{}
+	// locks serializes the reads remembered in the table with the writes invalidating the same keys.
+	locks keyLocks
+}
+
+// keyLockStripes is the number of locks the keys are spread over.
+const keyLockStripes = 256
+
+// keyLocks guards keys while they are read or written and the table updated accordingly, so that a write cannot
+// be applied and invalidate a key between the moment a tracking client reads it and the moment it is remembered:
+// the client would keep a stale value without ever being told. Keys are spread over a fixed number of locks.
+type keyLocks struct {
+	seed    maphash.Seed
+	stripes [keyLockStripes]sync.RWMutex
+}
+
+// lock takes the locks of the keys, in write mode for the written ones, and returns the function releasing them.
+// The locks are taken in order so that commands locking several keys cannot deadlock.
+func (l *keyLocks) lock(reads []string, writes []string) func() {
+	if len(reads)+len(writes) == 0 {
+		return func() {}
+	}
+	// write tells for each stripe if one of its keys is written
+	write := make(map[int]bool, len(reads)+len(writes))
+	for _, key := range reads {
+		i := int(maphash.String(l.seed, key) % keyLockStripes)
+		if _, ok := write[i]; !ok {
+			write[i] = false
+		}
+	}
+	for _, key := range writes {
+		write[int(maphash.String(l.seed, key)%keyLockStripes)] = true
+	}
+	stripes := make([]int, 0, len(write))
+	for i := range write {
+		stripes = append(stripes, i)
+	}
+	sort.Ints(stripes)
+	for _, i := range stripes {
+		if write[i] {
+			l.stripes[i].Lock()
+		} else {
+			l.stripes[i].RLock()
+		}
+	}
+	return func() {
+		for _, i := range stripes {
+			if write[i] {
+				l.stripes[i].Unlock()
+			} else {
+				l.stripes[i].RUnlock()
+			}
+		}
+	}
+}
+
+func newTracker(server *Server, maxKeys int) *tracker {
+	t := &tracker{
+		server:   server,
+		clients:  make(map[int64]command.TrackingOptions),
+		keys:     make(map[string]map[int64]struct{}),
+		readKeys: make(map[int64]map[string]struct{}),
+		prefixes: make(map[string]map[int64]struct{}),
+		locks:    keyLocks{seed: maphash.MakeSeed()},
+	}
+	t.maxKeys.Store(int64(maxKeys))
+	return t
+}
+
+// enable starts tracking a client, replacing its previous options if any.
+func (t *tracker) enable(id int64, opts command.TrackingOptions) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disableLocked(id)
+	if opts.BCast && len(opts.Prefixes) == 0 {
+		// no prefix means all the keys
+		opts.Prefixes = []string{""}
+	}
+	t.clients[id] = opts
+	for _, prefix := range opts.Prefixes {
+		clients, ok := t.prefixes[prefix]
+		if !ok {
+			clients = make(map[int64]struct{})
+			t.prefixes[prefix] = clients
+		}
+		clients[id] = struct{}{}
+	}
+}
+
+// disable stops tracking a client.
+func (t *tracker) disable(id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disableLocked(id)
+}
+
+// disableLocked stops tracking a client and forgets the keys it read. The caller must hold the lock.
+func (t *tracker) disableLocked(id int64) {
+	opts, ok := t.clients[id]
+	if !ok {
+		return
+	}
+	for key := range t.readKeys[id] {
+		delete(t.keys[key], id)
+		if len(t.keys[key]) == 0 {
+			delete(t.keys, key)
+		}
+	}
+	delete(t.readKeys, id)
+	for _, prefix := range opts.Prefixes {
+		delete(t.prefixes[prefix], id)
+		if len(t.prefixes[prefix]) == 0 {
+			delete(t.prefixes, prefix)
+		}
+	}
+	delete(t.clients, id)
+}
+
+// options returns the tracking options of a client and whether it is tracking.
+func (t *tracker) options(id int64) (command.TrackingOptions, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	opts, ok := t.clients[id]
+	return opts, ok
+}
+
+// remember records that a client in default mode read keys. Once the table holds more than maxKeys keys, random
+// ones are invalidated to make room, as if they were modified.
+func (t *tracker) remember(id int64, keys []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.clients[id]; !ok {
+		return
+	}
+	read, ok := t.readKeys[id]
+	if !ok {
+		read = make(map[string]struct{})
+		t.readKeys[id] = read
+	}
+	for _, key := range keys {
+		clients, ok := t.keys[key]
+		if !ok {
+			clients = make(map[int64]struct{})
+			t.keys[key] = clients
+		}
+		clients[id] = struct{}{}
+		read[key] = struct{}{}
+	}
+	maxKeys := t.maxKeys.Load()
+	// map iteration starts at a random position, so the keys evicted are picked randomly
+	for key := range t.keys {
+		if maxKeys == 0 || int64(len(t.keys)) <= maxKeys {
+			break
+		}
+		t.forget(key, 0)
+	}
+}
+
+// forget sends an invalidation message for a key to the clients in default mode which read it, and removes it from
+// the table. The caller must hold the lock.
+func (t *tracker) forget(key string, origin int64) {
+	for id := range t.keys[key] {
+		t.send(id, origin, frame.NewBulkString(key))
+		delete(t.readKeys[id], key)
+	}
+	delete(t.keys, key)
+}
+
+// invalidate sends an invalidation message for a key to the clients caching it.
+// origin is the ID of the client which modified the key, 0 when the server did, like for expirations.
+func (t *tracker) invalidate(key string, origin int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.clients) == 0 {
+		return
+	}
+	t.forget(key, origin)
+	for prefix, clients := range t.prefixes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		for id := range clients {
+			t.send(id, origin, frame.NewBulkString(key))
+		}
+	}
+}
+
+// invalidateAll tells all the tracking clients to drop their whole cache, after a flush.
+func (t *tracker) invalidateAll(origin int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.keys = make(map[string]map[int64]struct{})
+	t.readKeys = make(map[int64]map[string]struct{})
+	for id := range t.clients {
+		t.send(id, origin, nil)
+	}
+}
+
+// send delivers an invalidation message for a key, a nil key meaning all the keys.
+// The caller must hold the lock.
+func (t *tracker) send(id int64, origin int64, key *frame.BulkString) {
+	opts, ok := t.clients[id]
+	if !ok || (opts.NoLoop && id == origin) {
+		return
+	}
+	var keys frame.Framer = &frame.Null{}
+	if key != nil {
+		array := frame.NewArray(1)
+		_ = array.Append(key)
+		keys = array
+	}
+
+	target := id
+	if opts.Redirect != 0 {
+		target = opts.Redirect
+	}
+	conn, ok := t.server.client(target)
+	if !ok {
+		if source, ok := t.server.client(id); ok && target != id {
+			source.push(frame.NewOutOfBand(source.Protocol(), frame.NewBulkString("tracking-redir-broken"), frame.NewInteger(target)))
+		}
+		return
+	}
+	if conn.Protocol() >= frame.RESP3 {
+		conn.push(frame.NewOutOfBand(frame.RESP3, frame.NewBulkString("invalidate"), keys))
+		return
+	}
+	// RESP2 clients only get invalidation messages through the pub/sub channel
+	if t.server.pubsub.isSubscribed(conn, invalidationChannel) {
+		conn.push(frame.NewOutOfBand(frame.RESP2, frame.NewBulkString("message"), frame.NewBulkString(invalidationChannel), keys))
+	}
+}
+
+// ClientID returns the unique identifier of the client.
+func (c *Connection) ClientID() int64 {
+	return c.id
+}
+
+// Tracking enables or disables client side caching for the connection.
+func (c *Connection) Tracking(on bool, opts command.TrackingOptions) error {
+	if !on {
+		c.server.tracking.disable(c.id)
+		return nil
+	}
+	if opts.Redirect != 0 && opts.Redirect != c.id {
+		if _, ok := c.server.client(opts.Redirect); !ok {
+			return gerror.ErrRedirectNotFound
+		}
+	}
+	if opts.Redirect == c.id {
+		opts.Redirect = 0
+	}
+	c.server.tracking.enable(c.id, opts)
+	return nil
+}
+
+// Caching decides if the keys read by the next command are tracked.
+func (c *Connection) Caching(yes bool) error {
+	opts, ok := c.server.tracking.options(c.id)
+	if !ok || (!opts.OptIn && !opts.OptOut) || (opts.OptIn && !yes) || (opts.OptOut && yes) {
+		return gerror.ErrCachingMode
+	}
+	c.cachingSet = true
+	return nil
+}
+
+// TrackingRedirect returns the ID of the client receiving the invalidation messages of this one.
+func (c *Connection) TrackingRedirect() int64 {
+	opts, ok := c.server.tracking.options(c.id)
+	if !ok {
+		return -1
+	}
+	return opts.Redirect
+}
+
+// trackedReads returns the keys read by a command which are remembered for the client side caching of the
+// connection, none if it is not tracking them.
+func (s *Server) trackedReads(conn *Connection, cmd command.Command) []string {
+	r, ok := cmd.(command.KeysReader)
+	if !ok {
+		return nil
+	}
+	opts, ok := s.tracking.options(conn.id)
+	if !ok || opts.BCast || (opts.OptIn && !conn.cachingSet) || (opts.OptOut && conn.cachingSet) {
+		return nil
+	}
+	return r.ReadKeys()
+}
+
+// applyTracked applies a command on behalf of a connection and updates the client side caching state: the keys
+// it modified are invalidated and the keys it read are remembered. The keys are locked meanwhile, so that the
+// reads are remembered before a concurrent write of the same keys invalidates them.
+func (s *Server) applyTracked(conn *Connection, cmd command.Command, dest command.ReplyWriter) {
+	reads := s.trackedReads(conn, cmd)
+	var writes []string
+	if w, ok := cmd.(command.KeysWriter); ok {
+		writes = w.WriteKeys()
+	}
+	unlock := s.tracking.locks.lock(reads, writes)
+	defer unlock()
+
+	cmd.Apply(conn.DB(), dest)
+	for _, key := range writes {
+		s.tracking.invalidate(key, conn.id)
+	}
+	switch cmd.(type) {
+	case *command.FlushDB, *command.FlushAll:
+		s.tracking.invalidateAll(conn.id)
+	}
+	// CLIENT CACHING only applies to the command following it
+	if _, ok := cmd.(*command.Client); !ok {
+		conn.cachingSet = false
+	}
+	if len(reads) > 0 {
+		s.tracking.remember(conn.id, reads)
+	}
+}
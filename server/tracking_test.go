@@ -0,0 +1,216 @@
+package server
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dialResp3Client connects a client speaking RESP3, able to receive invalidation pushes.
+func dialResp3Client(t *testing.T, address string) *testClient {
+	c := dialTestClient(t, address)
+	c.send("HELLO", "3")
+	assert.True(t, strings.HasPrefix(c.read(), "%4\r\n"))
+	return c
+}
+
+func TestTracking_Default(t *testing.T) {
+	s := startTestServer(t)
+	reader := dialResp3Client(t, s.Address())
+	writer := dialTestClient(t, s.Address())
+
+	reader.send("CLIENT", "TRACKING", "ON")
+	assert.Equal(t, "+OK\r\n", reader.read())
+	reader.send("GET", "hello")
+	assert.Equal(t, "_\r\n", reader.read())
+
+	writer.send("SET", "hello", "world")
+	assert.Equal(t, "+ok\r\n", writer.read())
+	assert.Equal(t, ">2\r\n$10\r\ninvalidate\r\n*1\r\n$5\r\nhello\r\n", reader.read())
+
+	// the key was forgotten once invalidated, so it is not invalidated twice
+	writer.send("SET", "hello", "again")
+	assert.Equal(t, "+ok\r\n", writer.read())
+	reader.send("CLIENT", "GETREDIR")
+	assert.Equal(t, ":0\r\n", reader.read())
+
+	writer.send("FLUSHALL")
+	assert.Equal(t, "+OK\r\n", writer.read())
+	assert.Equal(t, ">2\r\n$10\r\ninvalidate\r\n_\r\n", reader.read())
+}
+
+func TestTracking_BCastNoLoop(t *testing.T) {
+	s := startTestServer(t)
+	reader := dialResp3Client(t, s.Address())
+	writer := dialTestClient(t, s.Address())
+
+	reader.send("CLIENT", "TRACKING", "ON", "BCAST", "PREFIX", "user:", "NOLOOP")
+	assert.Equal(t, "+OK\r\n", reader.read())
+
+	// keys modified by the client itself are not invalidated, nor the ones out of its prefixes
+	reader.send("SET", "user:1", "me")
+	assert.Equal(t, "+ok\r\n", reader.read())
+	writer.send("SET", "item:1", "other")
+	assert.Equal(t, "+ok\r\n", writer.read())
+	writer.send("DEL", "user:1")
+	assert.Equal(t, ":1\r\n", writer.read())
+	assert.Equal(t, ">2\r\n$10\r\ninvalidate\r\n*1\r\n$6\r\nuser:1\r\n", reader.read())
+}
+
+func TestTracking_Redirect(t *testing.T) {
+	s := startTestServer(t)
+	subscriber := dialTestClient(t, s.Address())
+	reader := dialTestClient(t, s.Address())
+	writer := dialTestClient(t, s.Address())
+
+	subscriber.send("CLIENT", "ID")
+	id := strings.TrimSuffix(strings.TrimPrefix(subscriber.read(), ":"), "\r\n")
+	subscriber.send("SUBSCRIBE", invalidationChannel)
+	assert.Equal(t, "*3\r\n$9\r\nsubscribe\r\n$20\r\n__redis__:invalidate\r\n:1\r\n", subscriber.read())
+
+	reader.send("CLIENT", "TRACKING", "ON", "REDIRECT", id, "OPTIN")
+	assert.Equal(t, "+OK\r\n", reader.read())
+	reader.send("CLIENT", "GETREDIR")
+	assert.Equal(t, fmt.Sprintf(":%s\r\n", id), reader.read())
+	// in OPTIN mode, only the keys read right after CLIENT CACHING YES are tracked
+	reader.send("GET", "ignored")
+	reader.read()
+	reader.send("CLIENT", "CACHING", "YES")
+	assert.Equal(t, "+OK\r\n", reader.read())
+	reader.send("GET", "hello")
+	reader.read()
+
+	writer.send("SET", "ignored", "value")
+	assert.Equal(t, "+ok\r\n", writer.read())
+	writer.send("SET", "hello", "world")
+	assert.Equal(t, "+ok\r\n", writer.read())
+	assert.Equal(t, "*3\r\n$7\r\nmessage\r\n$20\r\n__redis__:invalidate\r\n*1\r\n$5\r\nhello\r\n", subscriber.read())
+}
+
+func TestTracking_Errors(t *testing.T) {
+	s := startTestServer(t)
+	c := dialTestClient(t, s.Address())
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "UnknownRedirect", args: []string{"CLIENT", "TRACKING", "ON", "REDIRECT", "12345"}, want: "-"},
+		{name: "PrefixWithoutBCast", args: []string{"CLIENT", "TRACKING", "ON", "PREFIX", "a"}, want: "-"},
+		{name: "OptInAndOptOut", args: []string{"CLIENT", "TRACKING", "ON", "OPTIN", "OPTOUT"}, want: "-"},
+		{name: "CachingWithoutMode", args: []string{"CLIENT", "CACHING", "YES"}, want: "-"},
+		{name: "GetRedirNotTracking", args: []string{"CLIENT", "GETREDIR"}, want: ":-1\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.send(tt.args...)
+			assert.True(t, strings.HasPrefix(c.read(), tt.want))
+		})
+	}
+}
+
+func TestTracking_ForgetKeysOfDisabledClients(t *testing.T) {
+	s := newTestServer(t, 1)
+	s.tracking.enable(1, command.TrackingOptions{})
+	s.tracking.enable(2, command.TrackingOptions{})
+	s.tracking.remember(1, []string{"a", "b"})
+	s.tracking.remember(2, []string{"b"})
+
+	s.tracking.disable(1)
+	assert.Equal(t, map[string]map[int64]struct{}{"b": {2: {}}}, s.tracking.keys)
+	assert.NotContains(t, s.tracking.readKeys, int64(1))
+
+	s.tracking.remember(1, []string{"c"})
+	assert.NotContains(t, s.tracking.keys, "c", "keys read by clients not tracking should not be remembered")
+	s.tracking.disable(2)
+	assert.Empty(t, s.tracking.keys)
+	assert.Empty(t, s.tracking.readKeys)
+}
+
+func TestTracking_TableMaxKeys(t *testing.T) {
+	s := startTestServer(t, WithTrackingTableMaxKeys(2))
+	reader := dialResp3Client(t, s.Address())
+
+	reader.send("CLIENT", "TRACKING", "ON")
+	assert.Equal(t, "+OK\r\n", reader.read())
+	for _, key := range []string{"a", "b"} {
+		reader.send("GET", key)
+		assert.Equal(t, "_\r\n", reader.read())
+	}
+	// the invalidation is pushed while the reply is written, in any order
+	reader.send("GET", "c")
+	replies := reader.read() + reader.read()
+	assert.Contains(t, replies, "_\r\n")
+	assert.Regexp(t, `>2\r\n\$10\r\ninvalidate\r\n\*1\r\n\$1\r\n[abc]\r\n`, replies,
+		"a key should be invalidated to make room")
+	s.tracking.mu.Lock()
+	assert.Len(t, s.tracking.keys, 2)
+	s.tracking.mu.Unlock()
+
+	reader.send("CONFIG", "GET", "tracking-table-max-keys")
+	assert.Equal(t, "%1\r\n$23\r\ntracking-table-max-keys\r\n$1\r\n2\r\n", reader.read())
+	reader.send("CONFIG", "SET", "tracking-table-max-keys", "-1")
+	assert.True(t, strings.HasPrefix(reader.read(), "-"))
+}
+
+// pausedGet is a GET pausing once it read its key, until it is resumed.
+type pausedGet struct {
+	*command.Get
+	read   chan struct{}
+	resume chan struct{}
+}
+
+func (c *pausedGet) Apply(cache *db.Cache, dest command.ReplyWriter) {
+	c.Get.Apply(cache, dest)
+	close(c.read)
+	<-c.resume
+}
+
+func TestTracking_ConcurrentReadAndWrite(t *testing.T) {
+	s := newTestServer(t, 1)
+	reader, _ := newTestConnection(t, s)
+	reader.id = 1
+	reader.protocol.Store(frame.RESP3)
+	writer, _ := newTestConnection(t, s)
+	writer.id = 2
+	s.clients[reader.id] = reader
+	assert.NoError(t, reader.Tracking(true, command.TrackingOptions{}))
+
+	get := &pausedGet{
+		Get:    newTestCommand(t, "GET", "key").(*command.Get),
+		read:   make(chan struct{}),
+		resume: make(chan struct{}),
+	}
+	set := newTestCommand(t, "SET", "key", "value")
+	replies := command.NewRecorder(frame.RESP3)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.apply(reader, get, replies)
+	}()
+	<-get.read
+	written := make(chan struct{})
+	go func() {
+		defer wg.Done()
+		s.apply(writer, set, command.NewRecorder(frame.RESP3))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Error("the write should wait for the read to be remembered")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(get.resume)
+	wg.Wait()
+
+	assert.Equal(t, []frame.Framer{&frame.Null{}}, replies.Frames)
+	assert.Len(t, reader.outbox.take(), 1, "the reader should be told its value was overwritten")
+}
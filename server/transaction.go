@@ -0,0 +1,145 @@
+package server
+
+import (
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/db"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+)
+
+// transaction holds the state of a MULTI block for a connection.
+type transaction struct {
+	active bool
+	// aborted is set when a command could not be queued, EXEC then discards the whole transaction.
+	aborted bool
+	// queue holds the calls of the queued commands, whose permissions are checked again by EXEC.
+	queue   []*Call
+	watched []watchedKey
+}
+
+// watchedKey is a key marked by WATCH along with its version at that time.
+// The database is recorded by index and by reference, so that a SWAPDB also invalidates the watch.
+type watchedKey struct {
+	dbIndex int
+	cache   *db.Cache
+	key     string
+	version uint64
+}
+
+// isTransactionControl tells if a command drives the transaction and must be applied immediately
+// rather than being queued.
+func isTransactionControl(cmd command.Command) bool {
+	switch cmd.(type) {
+	case *command.Multi, *command.Exec, *command.Discard, *command.Watch:
+		return true
+	default:
+		return false
+	}
+}
+
+// Multi opens a transaction.
+func (c *Connection) Multi() error {
+	if c.tx.active {
+		return gerror.ErrNestedMulti
+	}
+	c.tx.active = true
+	return nil
+}
+
+// Exec applies the queued commands while holding the server exclusive lock, so no other client can observe or
+// interleave with the intermediate states. It always ends the transaction and releases the watched keys.
+func (c *Connection) Exec(dest command.ReplyWriter) error {
+	if !c.tx.active {
+		return gerror.ErrExecWithoutMulti
+	}
+	defer c.resetTransaction()
+	if c.tx.aborted {
+		return gerror.ErrExecAbort
+	}
+
+	c.server.execMu.Lock()
+	defer c.server.execMu.Unlock()
+	if c.watchedKeysModified() {
+		dest.WriteNullArray()
+		return nil
+	}
+	// Replies of the queued commands are collected to be sent as the elements of an array.
+	replies := command.NewRecorder(dest.Protocol())
+	for _, call := range c.tx.queue {
+		// the permissions of the user may have changed since the command was queued
+		if err := c.server.checkPermissions(call); err != nil {
+			replies.WriteError(err)
+			continue
+		}
+		c.server.apply(c, call.Command, replies)
+	}
+	resp := frame.NewArray(len(replies.Frames))
+	for _, f := range replies.Frames {
+		_ = resp.Append(f)
+	}
+	dest.WriteFrame(resp)
+	return nil
+}
+
+// Discard drops the queued commands and the watched keys.
+func (c *Connection) Discard() error {
+	if !c.tx.active {
+		return gerror.ErrDiscardWithoutMulti
+	}
+	c.resetTransaction()
+	return nil
+}
+
+// Watch marks keys of the selected database.
+func (c *Connection) Watch(keys ...string) error {
+	if c.tx.active {
+		return gerror.ErrWatchInsideMulti
+	}
+	cache := c.DB()
+	for _, key := range keys {
+		c.tx.watched = append(c.tx.watched, watchedKey{
+			dbIndex: c.dbIndex,
+			cache:   cache,
+			key:     key,
+			version: cache.Watch(key),
+		})
+	}
+	return nil
+}
+
+// Unwatch forgets all the watched keys.
+func (c *Connection) Unwatch() {
+	for _, w := range c.tx.watched {
+		w.cache.Unwatch(w.key)
+	}
+	c.tx.watched = nil
+}
+
+// watchedKeysModified tells if one of the watched keys changed since it was watched.
+func (c *Connection) watchedKeysModified() bool {
+	for _, w := range c.tx.watched {
+		cache, err := c.server.database(w.dbIndex)
+		if err != nil || cache != w.cache || w.cache.Version(w.key) != w.version {
+			return true
+		}
+	}
+	return false
+}
+
+// queue adds a command to the open transaction.
+func (c *Connection) queue(call *Call) {
+	c.tx.queue = append(c.tx.queue, call)
+}
+
+// abortTransaction flags the open transaction, if any, so that EXEC discards it.
+func (c *Connection) abortTransaction() {
+	if c.tx.active {
+		c.tx.aborted = true
+	}
+}
+
+// resetTransaction closes the transaction and releases the watched keys.
+func (c *Connection) resetTransaction() {
+	c.Unwatch()
+	c.tx = transaction{}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/ynachi/gcache/acl"
+	"github.com/ynachi/gcache/command"
+	"github.com/ynachi/gcache/frame"
+	"github.com/ynachi/gcache/gerror"
+	"testing"
+)
+
+// newTestCommand parses a command the way the server does when it receives one.
+func newTestCommand(t *testing.T, args ...string) command.Command {
+	f := frame.NewArray(len(args))
+	for _, arg := range args {
+		_ = f.Append(frame.NewBulkString(arg))
+	}
+	cmd, err := parseCommandFromFrame(f)
+	if err != nil {
+		t.Fatalf("unable to parse command %v: %v", args, err)
+	}
+	return cmd
+}
+
+// newTestConnection returns a connection bound to a server which is not listening, along with the buffer
+// receiving the replies.
+func newTestConnection(t *testing.T, s *Server) (*Connection, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	conn := &Connection{
+		server:   s,
+		writer:   bufio.NewWriter(out),
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+		outbox:   newOutbox(),
+	}
+	conn.protocol.Store(frame.RESP2)
+	conn.replies = command.NewRespWriter(conn.writer, conn.Protocol)
+	if user, ok := s.acl.User(acl.DefaultUser); ok {
+		conn.user.Store(user)
+	}
+	return conn, out
+}
+
+func TestConnection_Exec(t *testing.T) {
+	s := newTestServer(t, 1)
+	conn, out := newTestConnection(t, s)
+
+	s.dispatch(conn, newTestCommand(t, "MULTI"))
+	s.dispatch(conn, newTestCommand(t, "SET", "hello", "world"))
+	s.dispatch(conn, newTestCommand(t, "GET", "hello"))
+	_, ok := s.dbs[0].Get("hello")
+	assert.False(t, ok, "queued commands should not be applied before EXEC")
+
+	s.dispatch(conn, newTestCommand(t, "EXEC"))
+	assert.Equal(t, "+OK\r\n+QUEUED\r\n+QUEUED\r\n*2\r\n+ok\r\n$5\r\nworld\r\n", out.String())
+	assert.False(t, conn.tx.active)
+}
+
+func TestConnection_ExecAbort(t *testing.T) {
+	s := newTestServer(t, 1)
+	conn, out := newTestConnection(t, s)
+
+	assert.NoError(t, conn.Multi())
+	conn.queue(&Call{Conn: conn, Command: newTestCommand(t, "SET", "hello", "world")})
+	conn.abortTransaction()
+
+	assert.Equal(t, gerror.ErrExecAbort, conn.Exec(conn.replies))
+	assert.Empty(t, out.String())
+	assert.False(t, s.dbs[0].Exists("hello"))
+	assert.Equal(t, gerror.ErrExecWithoutMulti, conn.Exec(conn.replies))
+}
+
+func TestConnection_ExecWatched(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *Server)
+		want   string
+	}{
+		{
+			name:   "Untouched",
+			modify: func(s *Server) {},
+			want:   "*1\r\n+ok\r\n",
+		},
+		{
+			name:   "Modified",
+			modify: func(s *Server) { s.dbs[0].Set("watched", "changed") },
+			want:   "*-1\r\n",
+		},
+		{
+			name:   "Deleted",
+			modify: func(s *Server) { s.dbs[0].Delete("watched") },
+			want:   "*-1\r\n",
+		},
+		{
+			name:   "OtherKeyModified",
+			modify: func(s *Server) { s.dbs[0].Set("other", "changed") },
+			want:   "*1\r\n+ok\r\n",
+		},
+		{
+			name:   "Swapped",
+			modify: func(s *Server) { _ = s.swapDB(0, 1) },
+			want:   "*-1\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, 2)
+			s.dbs[0].Set("watched", "initial")
+			conn, out := newTestConnection(t, s)
+
+			assert.NoError(t, conn.Watch("watched"))
+			tt.modify(s)
+			assert.NoError(t, conn.Multi())
+			conn.queue(&Call{Conn: conn, Command: newTestCommand(t, "SET", "hello", "world")})
+			assert.NoError(t, conn.Exec(conn.replies))
+			_ = conn.replies.Flush()
+
+			assert.Equal(t, tt.want, out.String())
+			assert.Empty(t, conn.tx.watched)
+		})
+	}
+}